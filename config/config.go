@@ -1,33 +1,524 @@
+// Package config loads the configuration of the server. Load merges, from the lowest to the
+// highest precedence, the defaults, an optional YAML file, the .env file and the environment,
+// and the command line flags, then validates the result.
 package config
 
 import (
-	"log"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net"
+	"net/url"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/AlexisZankowitch/concept-insight/toolid"
 	"github.com/joho/godotenv"
+	"go.uber.org/fx"
+	"gopkg.in/yaml.v3"
 )
 
+// defaultChannels are the channels searched when SLACK_CHANNELS is not set
+const defaultChannels = "concept-tech,today-i-learned"
+
+// toolChannelsPrefix prefixes the per-tool channel overrides,
+// e.g. SLACK_CHANNELS_FIND_TECHNOLOGY_POSTS for the find-technology-posts tool
+const toolChannelsPrefix = "SLACK_CHANNELS_"
+
+// toolTimeoutPrefix prefixes the per-tool timeout overrides,
+// e.g. TOOL_TIMEOUT_TECHNOLOGY_TRENDS for the technology-trends tool
+const toolTimeoutPrefix = "TOOL_TIMEOUT_"
+
+// toolScopePrefix prefixes the per-tool scope overrides,
+// e.g. OAUTH_SCOPE_SKILLS_MATRIX for the skills-matrix tool
+const toolScopePrefix = "OAUTH_SCOPE_"
+
+// Transports of the MCP server
+const (
+	TransportHTTP  = "http"
+	TransportStdio = "stdio"
+)
+
+// logLevels are the levels a LOG_LEVEL can be
+var logLevels = []string{"debug", "info", "warn", "error"}
+
 type Config struct {
-	SlackToken string
+	SlackToken string `yaml:"slack_token"`
+	// SlackAPIURL is the Slack Web API called, the real one when empty
+	SlackAPIURL string         `yaml:"slack_api_url"`
+	Server      ServerConfig   `yaml:"server"`
+	Channels    ChannelsConfig `yaml:"channels"`
+	Store       StoreConfig    `yaml:"store"`
+	// TechnologiesPath is the YAML technology catalog, the built-in catalog when empty
+	TechnologiesPath string             `yaml:"technologies_path"`
+	ToolTimeouts     ToolTimeoutsConfig `yaml:"tool_timeouts"`
+	Log              LogConfig          `yaml:"log"`
+	Auth             AuthConfig         `yaml:"auth"`
+}
+
+// ServerConfig configures the MCP server and its transport
+type ServerConfig struct {
+	// Name and Version are returned to the clients on initialization
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+	// Transport is http for streamable HTTP, or stdio for clients launching the server
+	Transport string `yaml:"transport"`
+	// Hostname, Port and Path are the endpoint of the http transport
+	Hostname string `yaml:"hostname"`
+	Port     int    `yaml:"port"`
+	Path     string `yaml:"path"`
+	// SessionIdleTimeout expires the sessions of the http transport without request for longer
+	SessionIdleTimeout time.Duration `yaml:"session_idle_timeout"`
+}
+
+// ChannelsConfig lists the Slack channels the tools search, by name
+type ChannelsConfig struct {
+	// Default channels searched by every tool without override
+	Default []string `yaml:"default"`
+	// Allowed channels a caller can ask for, defaults to every configured channel
+	Allowed []string `yaml:"allowed"`
+	// Tools overrides the default channels per tool id
+	Tools map[string][]string `yaml:"tools"`
+}
+
+// StoreConfig configures the local copy of the channels
+type StoreConfig struct {
+	// Path of the store file, the store is disabled when empty
+	Path string `yaml:"path"`
+	// SyncInterval is the delay between two syncs of the channels
+	SyncInterval time.Duration `yaml:"sync_interval"`
+	// SyncLookback is how far before the last synced message edits and replies are fetched again
+	SyncLookback time.Duration `yaml:"sync_lookback"`
+}
+
+// ToolTimeoutsConfig bounds the duration of a tool call, its Slack requests are aborted past it
+type ToolTimeoutsConfig struct {
+	// Default timeout of the tools without override
+	Default time.Duration `yaml:"default"`
+	// Tools overrides the default timeout per tool id
+	Tools map[string]time.Duration `yaml:"tools"`
+}
+
+// LogConfig configures the logs, written to stderr as JSON
+type LogConfig struct {
+	// Level is the minimum level logged: debug, info, warn or error
+	Level string `yaml:"level"`
+	// Redact hides the text of the messages and the tool arguments from the logs
+	Redact bool `yaml:"redact"`
+}
+
+// AuthConfig configures the authentication of the http transport, off when there is no API key
+type AuthConfig struct {
+	// APIKeys are the accepted API keys by key name, as their hex encoded SHA-256
+	APIKeys map[string]string `yaml:"api_keys"`
+	// APIKeyScopes are the scopes granted per key name, the default scope of the tools when a
+	// key has none
+	APIKeyScopes map[string][]string `yaml:"api_key_scopes"`
+	// APIKeysFile adds the keys of the file, a name:sha256 line per key followed by its scopes
+	APIKeysFile string      `yaml:"api_keys_file"`
+	OAuth       OAuthConfig `yaml:"oauth"`
+}
+
+// OAuthConfig configures the access tokens accepted by the http transport, per the MCP
+// authorization spec. OAuth is off when there is no issuer.
+type OAuthConfig struct {
+	// Issuer is the authorization server issuing the tokens
+	Issuer string `yaml:"issuer"`
+	// JWKSURL serves the signing keys of the issuer, discovered from its metadata when empty
+	JWKSURL string `yaml:"jwks_url"`
+	// Resource is the URL of the MCP endpoint, the audience of the tokens,
+	// http://hostname:port/path by default
+	Resource string `yaml:"resource"`
+	// Scopes a token needs to call the tools
+	Scopes ToolScopesConfig `yaml:"scopes"`
+}
+
+// ToolScopesConfig maps the tools to the scope a token needs to call them
+type ToolScopesConfig struct {
+	// Default scope of the tools without override
+	Default string `yaml:"default"`
+	// Tools overrides the default scope per tool id
+	Tools map[string]string `yaml:"tools"`
+}
+
+// Default is the configuration before any file, environment or flag
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Name:               "concept-insight-server",
+			Version:            "0.0.1",
+			Transport:          TransportHTTP,
+			Hostname:           "localhost",
+			Port:               8080,
+			Path:               "/mcp",
+			SessionIdleTimeout: 30 * time.Minute,
+		},
+		Channels: ChannelsConfig{
+			Default: splitList(defaultChannels),
+			Tools:   map[string][]string{},
+		},
+		Store: StoreConfig{
+			SyncInterval: 15 * time.Minute,
+			SyncLookback: 7 * 24 * time.Hour,
+		},
+		ToolTimeouts: ToolTimeoutsConfig{
+			Default: 2 * time.Minute,
+			Tools:   map[string]time.Duration{},
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Redact: true,
+		},
+		Auth: AuthConfig{
+			APIKeys:      map[string]string{},
+			APIKeyScopes: map[string][]string{},
+			OAuth: OAuthConfig{
+				Scopes: ToolScopesConfig{
+					Default: "slack:read",
+					Tools:   map[string]string{},
+				},
+			},
+		},
+	}
+}
+
+// Load loads the configuration, registering its flags on the flag set and parsing args with it.
+// The file is given by -config or CONFIG_FILE, the arguments left after the flags are the
+// flag set Args. Every invalid value is reported in the returned error, not only the first one.
+func Load(flags *flag.FlagSet, args []string) (Config, error) {
+	cfg := Default()
+	configFile := flags.String("config", "", "YAML configuration file, overridden by the environment and the flags")
+	transport := flags.String("transport", cfg.Server.Transport, "transport of the MCP server: http for streamable HTTP, or stdio for clients launching the server")
+	hostname := flags.String("hostname", cfg.Server.Hostname, "hostname of the http transport")
+	port := flags.Int("port", cfg.Server.Port, "port of the http transport")
+	path := flags.String("path", cfg.Server.Path, "path of the http transport")
+	logLevel := flags.String("log-level", cfg.Log.Level, "minimum level logged: "+strings.Join(logLevels, ", "))
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	l := &loader{}
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		l.errs = append(l.errs, fmt.Errorf("invalid .env file: %w", err))
+	}
+
+	if *configFile == "" {
+		*configFile = os.Getenv("CONFIG_FILE")
+	}
+	if *configFile != "" {
+		l.file(*configFile, &cfg)
+	}
+	l.env(&cfg)
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "transport":
+			cfg.Server.Transport = *transport
+		case "hostname":
+			cfg.Server.Hostname = *hostname
+		case "port":
+			cfg.Server.Port = *port
+		case "path":
+			cfg.Server.Path = *path
+		case "log-level":
+			cfg.Log.Level = *logLevel
+		}
+	})
+
+	if cfg.Auth.APIKeysFile != "" {
+		l.apiKeysFile(cfg.Auth.APIKeysFile, &cfg.Auth)
+	}
+
+	if cfg.Auth.OAuth.Issuer != "" && cfg.Auth.OAuth.Resource == "" {
+		cfg.Auth.OAuth.Resource = (&url.URL{
+			Scheme: "http",
+			Host:   net.JoinHostPort(cfg.Server.Hostname, strconv.Itoa(cfg.Server.Port)),
+			Path:   cfg.Server.Path,
+		}).String()
+	}
+
+	if len(cfg.Channels.Allowed) == 0 {
+		cfg.Channels.Allowed = append(cfg.Channels.Allowed, cfg.Channels.Default...)
+		for _, toolChannels := range cfg.Channels.Tools {
+			cfg.Channels.Allowed = append(cfg.Channels.Allowed, toolChannels...)
+		}
+	}
+
+	l.errs = append(l.errs, cfg.Validate())
+	if err := errors.Join(l.errs...); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Module supplies the loaded configuration to the fx app
+func Module(cfg Config) fx.Option {
+	return fx.Supply(cfg)
+}
+
+// Validate returns every invalid value of the configuration, joined
+func (c Config) Validate() error {
+	errs := []error{}
+	if c.SlackToken == "" {
+		errs = append(errs, errors.New("SLACK_TOKEN is required"))
+	}
+	if c.Server.Name == "" || c.Server.Version == "" {
+		errs = append(errs, errors.New("the server name and version are required"))
+	}
+	switch c.Server.Transport {
+	case TransportHTTP:
+		if c.Server.Port <= 0 || c.Server.Port > 65535 {
+			errs = append(errs, fmt.Errorf("invalid port %d, expected 1 to 65535", c.Server.Port))
+		}
+		if !strings.HasPrefix(c.Server.Path, "/") {
+			errs = append(errs, fmt.Errorf("invalid path %q, expected to start with /", c.Server.Path))
+		}
+	case TransportStdio:
+	default:
+		errs = append(errs, fmt.Errorf("unknown transport %q, expected %s or %s", c.Server.Transport, TransportHTTP, TransportStdio))
+	}
+	if len(c.Channels.Default) == 0 {
+		errs = append(errs, errors.New("at least one default channel is required"))
+	}
+	errs = append(errs, unknownTools("channels", slices.Sorted(maps.Keys(c.Channels.Tools)))...)
+	durations := []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"the session idle timeout", c.Server.SessionIdleTimeout},
+		{"the sync interval", c.Store.SyncInterval},
+		{"the sync lookback", c.Store.SyncLookback},
+		{"the default tool timeout", c.ToolTimeouts.Default},
+	}
+	errs = append(errs, unknownTools("timeout", slices.Sorted(maps.Keys(c.ToolTimeouts.Tools)))...)
+	for _, tool := range slices.Sorted(maps.Keys(c.ToolTimeouts.Tools)) {
+		durations = append(durations, struct {
+			name     string
+			duration time.Duration
+		}{"the timeout of " + tool, c.ToolTimeouts.Tools[tool]})
+	}
+	for _, d := range durations {
+		if d.duration <= 0 {
+			errs = append(errs, fmt.Errorf("invalid duration %s for %s, expected it positive", d.duration, d.name))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Auth.APIKeys)) {
+		if hash, err := hex.DecodeString(c.Auth.APIKeys[name]); err != nil || len(hash) != sha256.Size {
+			errs = append(errs, fmt.Errorf("invalid hash for the API key %s, expected a hex encoded SHA-256", name))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Auth.APIKeyScopes)) {
+		if _, ok := c.Auth.APIKeys[name]; !ok {
+			errs = append(errs, fmt.Errorf("scopes given for the unknown API key %s", name))
+		}
+	}
+	errs = append(errs, unknownTools("scope", slices.Sorted(maps.Keys(c.Auth.OAuth.Scopes.Tools)))...)
+	if c.Auth.OAuth.Issuer != "" {
+		for _, u := range []struct {
+			name  string
+			value string
+		}{
+			{"the OAuth issuer", c.Auth.OAuth.Issuer},
+			{"the OAuth JWKS", c.Auth.OAuth.JWKSURL},
+			{"the OAuth resource", c.Auth.OAuth.Resource},
+		} {
+			if parsed, err := url.Parse(u.value); u.value != "" && (err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "") {
+				errs = append(errs, fmt.Errorf("invalid URL %q for %s", u.value, u.name))
+			}
+		}
+	}
+	if !slices.Contains(logLevels, c.Log.Level) {
+		errs = append(errs, fmt.Errorf("unknown log level %q, expected one of %s", c.Log.Level, strings.Join(logLevels, ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+// unknownTools returns an error per override of the setting keyed by an unknown tool id
+func unknownTools(setting string, tools []string) []error {
+	errs := []error{}
+	for _, tool := range tools {
+		if !toolid.Known(tool) {
+			errs = append(errs, fmt.Errorf("unknown tool %q in the %s overrides, expected one of %s", tool, setting, strings.Join(toolid.All(), ", ")))
+		}
+	}
+	return errs
 }
 
-var AppConfig Config
+// loader collects the errors of the values it reads, so they are all reported at once
+type loader struct {
+	errs []error
+}
 
-func init() {
-	err := godotenv.Load()
+// file overrides the configuration with the values of the YAML file, unknown keys are errors
+func (l *loader) file(path string, cfg *Config) {
+	f, err := os.Open(path)
 	if err != nil {
-		log.Println("Warning: .env file not found, using system environment variables")
+		l.errs = append(l.errs, fmt.Errorf("could not read the configuration file: %w", err))
+		return
 	}
+	defer f.Close()
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		l.errs = append(l.errs, fmt.Errorf("invalid configuration file %s: %w", path, err))
+	}
+}
+
+// env overrides the configuration with the environment variables that are set
+func (l *loader) env(cfg *Config) {
+	l.string("SLACK_TOKEN", &cfg.SlackToken)
+	l.string("SLACK_API_URL", &cfg.SlackAPIURL)
+	l.string("MCP_TRANSPORT", &cfg.Server.Transport)
+	l.string("MCP_HOSTNAME", &cfg.Server.Hostname)
+	l.int("MCP_PORT", &cfg.Server.Port)
+	l.string("MCP_PATH", &cfg.Server.Path)
+	l.duration("MCP_SESSION_IDLE_TIMEOUT", &cfg.Server.SessionIdleTimeout)
+	l.list("SLACK_CHANNELS", &cfg.Channels.Default)
+	l.list("SLACK_ALLOWED_CHANNELS", &cfg.Channels.Allowed)
+	if cfg.Channels.Tools == nil {
+		cfg.Channels.Tools = map[string][]string{}
+	}
+	for tool, value := range toolEnv(toolChannelsPrefix) {
+		cfg.Channels.Tools[tool] = splitList(value)
+	}
+	l.string("SLACK_STORE_PATH", &cfg.Store.Path)
+	l.duration("SLACK_SYNC_INTERVAL", &cfg.Store.SyncInterval)
+	l.duration("SLACK_SYNC_LOOKBACK", &cfg.Store.SyncLookback)
+	l.string("TECHNOLOGIES_PATH", &cfg.TechnologiesPath)
+	l.duration("TOOL_TIMEOUT", &cfg.ToolTimeouts.Default)
+	if cfg.ToolTimeouts.Tools == nil {
+		cfg.ToolTimeouts.Tools = map[string]time.Duration{}
+	}
+	for tool, value := range toolEnv(toolTimeoutPrefix) {
+		cfg.ToolTimeouts.Tools[tool] = l.parseDuration("the timeout of "+tool, value)
+	}
+	l.string("LOG_LEVEL", &cfg.Log.Level)
+	l.bool("LOG_REDACT", &cfg.Log.Redact)
+	l.string("API_KEYS_FILE", &cfg.Auth.APIKeysFile)
+	l.string("OAUTH_ISSUER", &cfg.Auth.OAuth.Issuer)
+	l.string("OAUTH_JWKS_URL", &cfg.Auth.OAuth.JWKSURL)
+	l.string("OAUTH_RESOURCE", &cfg.Auth.OAuth.Resource)
+	l.string("OAUTH_SCOPE", &cfg.Auth.OAuth.Scopes.Default)
+	if cfg.Auth.OAuth.Scopes.Tools == nil {
+		cfg.Auth.OAuth.Scopes.Tools = map[string]string{}
+	}
+	for tool, value := range toolEnv(toolScopePrefix) {
+		cfg.Auth.OAuth.Scopes.Tools[tool] = value
+	}
+}
+
+// apiKeysFile adds the keys of the file with their scopes, ignoring blank lines and # comments
+func (l *loader) apiKeysFile(path string, auth *AuthConfig) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("could not read the API keys file: %w", err))
+		return
+	}
+	if auth.APIKeys == nil {
+		auth.APIKeys = map[string]string{}
+	}
+	if auth.APIKeyScopes == nil {
+		auth.APIKeyScopes = map[string][]string{}
+	}
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, rest, ok := strings.Cut(line, ":")
+		fields := strings.Fields(rest)
+		if !ok || strings.TrimSpace(name) == "" || len(fields) == 0 {
+			l.errs = append(l.errs, fmt.Errorf("invalid line %d of the API keys file %s, expected name:sha256 followed by the scopes", i+1, path))
+			continue
+		}
+		name = strings.TrimSpace(name)
+		auth.APIKeys[name] = fields[0]
+		if len(fields) > 1 {
+			auth.APIKeyScopes[name] = fields[1:]
+		}
+	}
+}
+
+// string sets the value of an environment variable, an empty variable being unset
+func (l *loader) string(key string, value *string) {
+	if env, ok := os.LookupEnv(key); ok && env != "" {
+		*value = env
+	}
+}
+
+func (l *loader) list(key string, value *[]string) {
+	if env, ok := os.LookupEnv(key); ok && env != "" {
+		*value = splitList(env)
+	}
+}
+
+func (l *loader) int(key string, value *int) {
+	if env, ok := os.LookupEnv(key); ok && env != "" {
+		i, err := strconv.Atoi(env)
+		if err != nil {
+			l.errs = append(l.errs, fmt.Errorf("invalid integer %q for %s", env, key))
+			return
+		}
+		*value = i
+	}
+}
+
+func (l *loader) bool(key string, value *bool) {
+	if env, ok := os.LookupEnv(key); ok && env != "" {
+		b, err := strconv.ParseBool(env)
+		if err != nil {
+			l.errs = append(l.errs, fmt.Errorf("invalid boolean %q for %s", env, key))
+			return
+		}
+		*value = b
+	}
+}
+
+func (l *loader) duration(key string, value *time.Duration) {
+	if env, ok := os.LookupEnv(key); ok && env != "" {
+		*value = l.parseDuration(key, env)
+	}
+}
+
+func (l *loader) parseDuration(name string, value string) time.Duration {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("invalid duration %q for %s", value, name))
+	}
+	return duration
+}
 
-	AppConfig = Config{
-		SlackToken: getEnvOrFatal("SLACK_TOKEN"),
+// toolEnv returns the environment variables starting with the prefix by tool id,
+// e.g. FIND_TECHNOLOGY_POSTS after the prefix is the find-technology-posts tool
+func toolEnv(prefix string) map[string]string {
+	values := map[string]string{}
+	for _, env := range os.Environ() {
+		key, value, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		tool := strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(key, prefix), "_", "-"))
+		values[tool] = value
 	}
+	return values
 }
 
-func getEnvOrFatal(key string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		log.Fatalf("%s environment variable is required", key)
+// splitList splits a comma separated list, ignoring blanks and the leading # of channel names
+func splitList(value string) []string {
+	values := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimPrefix(strings.TrimSpace(item), "#")
+		if item != "" {
+			values = append(values, item)
+		}
 	}
-	return value
+	return values
 }
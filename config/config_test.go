@@ -0,0 +1,148 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setEnv sets the variables Load reads, unsetting the others of the environment of the test
+func setEnv(t *testing.T, env map[string]string) {
+	for _, key := range []string{"SLACK_TOKEN", "SLACK_API_URL", "SLACK_CHANNELS", "SLACK_ALLOWED_CHANNELS", "SLACK_STORE_PATH",
+		"SLACK_SYNC_INTERVAL", "SLACK_SYNC_LOOKBACK", "TECHNOLOGIES_PATH", "TOOL_TIMEOUT", "LOG_LEVEL", "LOG_REDACT",
+		"MCP_TRANSPORT", "MCP_HOSTNAME", "MCP_PORT", "MCP_PATH", "MCP_SESSION_IDLE_TIMEOUT", "CONFIG_FILE", "API_KEYS_FILE",
+		"OAUTH_ISSUER", "OAUTH_JWKS_URL", "OAUTH_RESOURCE", "OAUTH_SCOPE"} {
+		t.Setenv(key, env[key])
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "concept-insight.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func Test_LoadPrecedence(t *testing.T) {
+	file := writeFile(t, `
+slack_token: file-token
+server:
+  port: 9000
+  path: /file
+channels:
+  default: [concept-tech]
+  tools:
+    find-experts: [today-i-learned]
+tool_timeouts:
+  tools:
+    technology-trends: 10m
+log:
+  level: debug
+`)
+	setEnv(t, map[string]string{"CONFIG_FILE": file, "SLACK_TOKEN": "env-token", "MCP_PORT": "9100", "LOG_REDACT": "false"})
+	t.Setenv("TOOL_TIMEOUT_LATEST_POSTS_BY_USER", "5m")
+
+	flags := flag.NewFlagSet("concept-insight", flag.ContinueOnError)
+	cfg, err := Load(flags, []string{"-port", "9200", "skills-matrix", "-format", "csv"})
+	if err != nil {
+		t.Fatalf("expected a valid configuration, got %v", err)
+	}
+
+	if cfg.SlackToken != "env-token" || cfg.Server.Port != 9200 || cfg.Server.Path != "/file" || cfg.Server.Hostname != "localhost" {
+		t.Fatalf("expected the flags over the environment over the file over the defaults, got %+v", cfg)
+	}
+	if cfg.Log.Level != "debug" || cfg.Log.Redact || cfg.ToolTimeouts.Default != 2*time.Minute || cfg.ToolTimeouts.Tools["technology-trends"] != 10*time.Minute || cfg.ToolTimeouts.Tools["latest-posts-by-user"] != 5*time.Minute {
+		t.Fatalf("expected the file and environment values, got %+v", cfg)
+	}
+	allowed := slices.Sorted(slices.Values(cfg.Channels.Allowed))
+	if !slices.Equal(allowed, []string{"concept-tech", "today-i-learned"}) {
+		t.Fatalf("expected every configured channel allowed, got %v", allowed)
+	}
+	if !slices.Equal(flags.Args(), []string{"skills-matrix", "-format", "csv"}) {
+		t.Fatalf("expected the command left in the args, got %v", flags.Args())
+	}
+}
+
+func Test_LoadReportsEveryError(t *testing.T) {
+	file := writeFile(t, "server:\n  prot: 9000\n")
+	setEnv(t, map[string]string{"MCP_PORT": "eighty", "TOOL_TIMEOUT": "-1s", "MCP_SESSION_IDLE_TIMEOUT": "0s", "LOG_LEVEL": "verbose", "LOG_REDACT": "maybe"})
+	t.Setenv("SLACK_CHANNELS_FIND_EXPERT", "random")
+	t.Setenv("TOOL_TIMEOUT_GET_USER_DETAIL", "1m")
+
+	_, err := Load(flag.NewFlagSet("concept-insight", flag.ContinueOnError), []string{"-config", file, "-transport", "carrier-pigeon"})
+	if err == nil {
+		t.Fatalf("expected the configuration invalid")
+	}
+	for _, expected := range []string{
+		"field prot not found",
+		`invalid integer "eighty" for MCP_PORT`,
+		`invalid boolean "maybe" for LOG_REDACT`,
+		"SLACK_TOKEN is required",
+		`unknown transport "carrier-pigeon"`,
+		"invalid duration -1s for the default tool timeout",
+		"invalid duration 0s for the session idle timeout",
+		`unknown log level "verbose"`,
+		`unknown tool "find-expert" in the channels overrides`,
+		`unknown tool "get-user-detail" in the timeout overrides`,
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in the errors, got:\n%v", expected, err)
+		}
+	}
+}
+
+func Test_LoadAPIKeys(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	file := writeFile(t, "auth:\n  api_keys:\n    n8n: "+hash+"\n  api_key_scopes:\n    n8n: [slack:read, slack:users]\n")
+	keysFile := filepath.Join(t.TempDir(), "api-keys")
+	os.WriteFile(keysFile, []byte("# the key of open webui\nopen-webui: "+strings.Repeat("cd", 32)+" slack:read\nlocal:"+strings.Repeat("ef", 32)+"\n\n"), 0o600)
+	setEnv(t, map[string]string{"SLACK_TOKEN": "token", "CONFIG_FILE": file, "API_KEYS_FILE": keysFile})
+
+	cfg, err := Load(flag.NewFlagSet("concept-insight", flag.ContinueOnError), nil)
+	if err != nil {
+		t.Fatalf("expected a valid configuration, got %v", err)
+	}
+	if len(cfg.Auth.APIKeys) != 3 || cfg.Auth.APIKeys["n8n"] != hash || cfg.Auth.APIKeys["open-webui"] != strings.Repeat("cd", 32) {
+		t.Fatalf("expected the keys of the configuration and of the file, got %v", cfg.Auth.APIKeys)
+	}
+	// the keys without scopes get the default one from the server
+	if !reflect.DeepEqual(cfg.Auth.APIKeyScopes, map[string][]string{"n8n": {"slack:read", "slack:users"}, "open-webui": {"slack:read"}}) {
+		t.Fatalf("expected the scopes of the configuration and of the file, got %v", cfg.Auth.APIKeyScopes)
+	}
+
+	os.WriteFile(keysFile, []byte("open-webui\nlocal:my-secret\n"), 0o600)
+	t.Setenv("CONFIG_FILE", writeFile(t, "auth:\n  api_key_scopes:\n    n8n: [slack:read]\n"))
+	_, err = Load(flag.NewFlagSet("concept-insight", flag.ContinueOnError), nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid line 1 of the API keys file") || !strings.Contains(err.Error(), "invalid hash for the API key local") ||
+		!strings.Contains(err.Error(), "scopes given for the unknown API key n8n") {
+		t.Fatalf("expected the invalid keys reported, got %v", err)
+	}
+}
+
+func Test_LoadOAuth(t *testing.T) {
+	setEnv(t, map[string]string{"SLACK_TOKEN": "token", "OAUTH_ISSUER": "https://issuer.example", "MCP_PORT": "9000"})
+	t.Setenv("OAUTH_SCOPE_SKILLS_MATRIX", "slack:admin")
+	t.Setenv("OAUTH_SCOPE_GET_USER_DETAILS", "slack:users")
+
+	cfg, err := Load(flag.NewFlagSet("concept-insight", flag.ContinueOnError), nil)
+	if err != nil {
+		t.Fatalf("expected a valid configuration, got %v", err)
+	}
+	oauth := cfg.Auth.OAuth
+	if oauth.Resource != "http://localhost:9000/mcp" || oauth.Scopes.Default != "slack:read" || oauth.Scopes.Tools["skills-matrix"] != "slack:admin" || oauth.Scopes.Tools["get-user-details"] != "slack:users" {
+		t.Fatalf("expected the endpoint as resource and the scopes of the tools, got %+v", oauth)
+	}
+
+	t.Setenv("OAUTH_ISSUER", "issuer.example")
+	t.Setenv("OAUTH_SCOPE_SKILL_MATRIX", "slack:admin")
+	_, err = Load(flag.NewFlagSet("concept-insight", flag.ContinueOnError), nil)
+	if err == nil || !strings.Contains(err.Error(), `invalid URL "issuer.example" for the OAuth issuer`) || !strings.Contains(err.Error(), `unknown tool "skill-matrix" in the scope overrides`) {
+		t.Fatalf("expected the issuer and the misspelled tool rejected, got %v", err)
+	}
+}
@@ -94,8 +94,8 @@ type MCPCallToolParams struct {
 }
 
 type MCPCallToolResult struct {
-	Content interface{} `json:"content"`
-	IsError bool        `json:"isError"`
+	Content []MCPContent `json:"content"`
+	IsError bool         `json:"isError"`
 }
 
 type MCPContent struct {
@@ -235,7 +235,6 @@ func (c *OllamaClient) callMCPTool(name string, arguments map[string]interface{}
 		return "", fmt.Errorf("error unmarshaling tool result: %w", err)
 	}
 
-	// Handle different content formats from your MCP server
 	var result strings.Builder
 	
 	// Check if there's an error
@@ -243,47 +242,21 @@ func (c *OllamaClient) callMCPTool(name string, arguments map[string]interface{}
 		return "", fmt.Errorf("MCP tool returned error")
 	}
 	
-	// Handle the content - it could be various formats
-	contentBytes, err := json.Marshal(toolResult.Content)
-	if err != nil {
-		return "", fmt.Errorf("error marshaling content: %w", err)
-	}
-	
-	if c.debug {
-		fmt.Printf("🔍 Tool content: %s\n", string(contentBytes))
-	}
-	
-	// Try to parse as your server's format (array of arrays)
-	var arrayOfArrays [][]map[string]interface{}
-	if err := json.Unmarshal(contentBytes, &arrayOfArrays); err == nil {
-		// Successfully parsed as array of arrays
-		for _, array := range arrayOfArrays {
-			for i, item := range array {
-				if i > 0 {
-					result.WriteString("\n")
-				}
-				// Convert the map to a readable format
-				itemBytes, _ := json.MarshalIndent(item, "", "  ")
-				result.WriteString(string(itemBytes))
-			}
+	// The text content is a readable rendering of the result, made for the model
+	for i, content := range toolResult.Content {
+		if content.Type != "text" {
+			continue
 		}
-		return result.String(), nil
-	}
-	
-	// Try to parse as standard MCP format (array of content objects)
-	var standardContent []MCPContent
-	if err := json.Unmarshal(contentBytes, &standardContent); err == nil {
-		for i, content := range standardContent {
-			if i > 0 {
-				result.WriteString("\n")
-			}
-			result.WriteString(content.Text)
+		if i > 0 {
+			result.WriteString("\n")
 		}
-		return result.String(), nil
+		result.WriteString(content.Text)
 	}
-	
-	// If neither format works, return the raw content as string
-	return string(contentBytes), nil
+
+	if c.debug {
+		fmt.Printf("🔍 Tool content: %s\n", result.String())
+	}
+	return result.String(), nil
 }
 
 func (c *OllamaClient) Chat(model string, messages []Message) (*ChatResponse, error) {
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// APIKeyHeader is the header an API key can be sent in, instead of as a bearer token
+const APIKeyHeader = "X-API-Key"
+
+// APIKeys authenticates the requests with static API keys, sent as bearer tokens or in the
+// X-API-Key header. Only the SHA-256 of the keys is kept.
+type APIKeys struct {
+	keys []apiKey
+}
+
+type apiKey struct {
+	name   string
+	hash   []byte
+	scopes []string
+}
+
+// NewAPIKeys creates the authenticator of the keys, given as their hex encoded SHA-256 by key name.
+// A key is granted its scopes, the default scope when it has none.
+func NewAPIKeys(hashes map[string]string, scopes map[string][]string, defaultScope string) (*APIKeys, error) {
+	for name := range scopes {
+		if _, ok := hashes[name]; !ok {
+			return nil, fmt.Errorf("scopes given for the unknown API key %s", name)
+		}
+	}
+
+	names := make([]string, 0, len(hashes))
+	for name := range hashes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	keys := &APIKeys{}
+	for _, name := range names {
+		hash, err := hex.DecodeString(hashes[name])
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("invalid hash for the API key %s, expected a hex encoded SHA-256", name)
+		}
+		granted := scopes[name]
+		if len(granted) == 0 {
+			granted = []string{defaultScope}
+		}
+		keys.keys = append(keys.keys, apiKey{name: name, hash: hash, scopes: granted})
+	}
+	return keys, nil
+}
+
+// HashAPIKey returns the hex encoded SHA-256 of a key, as configured
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// Authenticate returns the name and the scopes of the key of the request
+func (k *APIKeys) Authenticate(r *http.Request) (Identity, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		key = bearerToken(r)
+	}
+	if key == "" {
+		return Identity{}, ErrNoCredentials
+	}
+
+	hash := sha256.Sum256([]byte(key))
+	var matched *apiKey
+	// every key is compared, so the time taken does not tell which one is closest
+	for i, known := range k.keys {
+		if subtle.ConstantTimeCompare(hash[:], known.hash) == 1 {
+			matched = &k.keys[i]
+		}
+	}
+	if matched == nil {
+		return Identity{}, ErrInvalidCredentials
+	}
+	return Identity{Name: matched.name, Scopes: matched.scopes}, nil
+}
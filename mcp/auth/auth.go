@@ -0,0 +1,121 @@
+// Package auth authenticates the requests to the http transport. The identity of an
+// authenticated request is carried in its context, down to the tool calls it makes.
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrNoCredentials is returned for requests without credentials
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials is returned for requests with credentials that are not accepted
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Identity is who made a request
+type Identity struct {
+	// Name is the name of the API key the request was made with, or the subject of its token
+	Name string
+	// Scopes granted to the API key or the token of the request
+	Scopes []string
+}
+
+// Allows tells if the identity was granted the scope, an empty scope is always allowed
+func (i Identity) Allows(scope string) bool {
+	return scope == "" || slices.Contains(i.Scopes, scope)
+}
+
+// Scopes maps the tools to the scope a token needs to call them
+type Scopes struct {
+	// Default is the scope of the tools without their own
+	Default string
+	// Tools overrides the default scope per tool id, see toolid
+	Tools map[string]string
+}
+
+// For returns the scope needed to call a tool
+func (s Scopes) For(tool string) string {
+	if scope, ok := s.Tools[tool]; ok {
+		return scope
+	}
+	return s.Default
+}
+
+// Authenticator authenticates a request from its credentials
+type Authenticator interface {
+	// Authenticate returns the identity of the request, ErrNoCredentials when it has none
+	Authenticate(r *http.Request) (Identity, error)
+}
+
+// Chain authenticates a request with the first authenticator accepting its credentials
+func Chain(authenticators ...Authenticator) Authenticator {
+	return chain(authenticators)
+}
+
+type chain []Authenticator
+
+func (c chain) Authenticate(r *http.Request) (Identity, error) {
+	err := ErrNoCredentials
+	for _, authenticator := range c {
+		identity, authErr := authenticator.Authenticate(r)
+		if authErr == nil {
+			return identity, nil
+		}
+		// the error of credentials an authenticator knows is more telling than no credentials
+		if errors.Is(err, ErrNoCredentials) {
+			err = authErr
+		}
+	}
+	return Identity{}, err
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a context carrying the identity
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// FromContext returns the identity of the request, false for unauthenticated requests
+func FromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
+
+// Middleware answers 401 to the requests the authenticator rejects, with the challenge in the
+// WWW-Authenticate header, e.g. Bearer realm="concept-insight". The other requests are passed
+// on with their identity in their context.
+func Middleware(authenticator Authenticator, challenge string, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, err := authenticator.Authenticate(r)
+			if err != nil {
+				header := challenge
+				if !errors.Is(err, ErrNoCredentials) {
+					header += `, error="invalid_token"`
+				}
+				logger.Warn("authentication failed", zap.String("remote_addr", r.RemoteAddr), zap.Error(err))
+				w.Header().Set("WWW-Authenticate", header)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+		})
+	}
+}
+
+// bearerToken returns the token of the Authorization header, empty without bearer token
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func Test_APIKeysMiddleware(t *testing.T) {
+	keys, err := NewAPIKeys(map[string]string{"n8n": HashAPIKey("n8n-secret"), "open-webui": HashAPIKey("webui-secret")},
+		map[string][]string{"open-webui": {"slack:read", "slack:users"}}, "slack:read")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := Middleware(keys, `Bearer realm="concept-insight"`, zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, _ := FromContext(r.Context())
+		w.Write([]byte(identity.Name + " " + strings.Join(identity.Scopes, ",")))
+	}))
+
+	for _, test := range []struct {
+		name      string
+		header    string
+		value     string
+		status    int
+		body      string
+		challenge string
+	}{
+		{"without key", "", "", http.StatusUnauthorized, "", `Bearer realm="concept-insight"`},
+		{"unknown key", "Authorization", "Bearer guessed", http.StatusUnauthorized, "", `Bearer realm="concept-insight", error="invalid_token"`},
+		// a key without scopes is granted the default one
+		{"bearer key", "Authorization", "Bearer n8n-secret", http.StatusOK, "n8n slack:read", ""},
+		{"header key", "X-API-Key", "webui-secret", http.StatusOK, "open-webui slack:read,slack:users", ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			if test.header != "" {
+				r.Header.Set(test.header, test.value)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != test.status || w.Header().Get("WWW-Authenticate") != test.challenge {
+				t.Fatalf("expected %d with challenge %q, got %d with %q", test.status, test.challenge, w.Code, w.Header().Get("WWW-Authenticate"))
+			}
+			if test.status == http.StatusOK && w.Body.String() != test.body {
+				t.Fatalf("expected the identity %q, got %q", test.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_NewAPIKeys(t *testing.T) {
+	if _, err := NewAPIKeys(map[string]string{"n8n": "n8n-secret"}, nil, "slack:read"); err == nil {
+		t.Fatalf("expected a key in clear rejected")
+	}
+	if _, err := NewAPIKeys(map[string]string{"n8n": HashAPIKey("n8n-secret")}, map[string][]string{"n8m": {"slack:read"}}, "slack:read"); err == nil {
+		t.Fatalf("expected the scopes of an unknown key rejected")
+	}
+}
@@ -0,0 +1,129 @@
+// Package fakeissuer is an OAuth authorization server for the tests, serving its metadata
+// and signing keys and issuing RS256 JWT access tokens with the client credentials grant.
+package fakeissuer
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+)
+
+// keyID identifies the signing key of the issuer in its key set
+const keyID = "fakeissuer-1"
+
+// Issuer is an authorization server listening on a local port
+type Issuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+}
+
+// New starts an issuer with a new signing key
+func New() *Issuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	issuer := &Issuer{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/oauth-authorization-server", issuer.handleMetadata)
+	mux.HandleFunc("GET /jwks.json", issuer.handleJWKS)
+	mux.HandleFunc("POST /token", issuer.handleToken)
+	issuer.server = httptest.NewServer(mux)
+	return issuer
+}
+
+// URL is the issuer identifier, the iss of its tokens
+func (i *Issuer) URL() string {
+	return i.server.URL
+}
+
+// Close stops the issuer
+func (i *Issuer) Close() {
+	i.server.Close()
+}
+
+// AccessToken returns a token of the subject for the audience, valid for an hour
+func (i *Issuer) AccessToken(subject string, audience string, scopes ...string) string {
+	now := time.Now()
+	return i.Sign(map[string]interface{}{
+		"iss":   i.URL(),
+		"sub":   subject,
+		"aud":   audience,
+		"scope": strings.Join(scopes, " "),
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+}
+
+// Sign returns a token of the claims signed with the key of the issuer
+func (i *Issuer) Sign(claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "at+jwt", "kid": keyID})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, i.key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func (i *Issuer) handleMetadata(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"issuer":                   i.URL(),
+		"jwks_uri":                 i.URL() + "/jwks.json",
+		"token_endpoint":           i.URL() + "/token",
+		"grant_types_supported":    []string{"client_credentials"},
+		"response_types_supported": []string{},
+	})
+}
+
+func (i *Issuer) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	public := i.key.PublicKey
+	writeJSON(w, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}},
+	})
+}
+
+// handleToken issues a token to any client, the client id being the subject
+// and the resource the audience (RFC 8707)
+func (i *Issuer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "client_credentials" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	clientID, _, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostForm.Get("client_id")
+	}
+	scopes := strings.Fields(r.PostForm.Get("scope"))
+	writeJSON(w, map[string]interface{}{
+		"access_token": i.AccessToken(clientID, r.PostForm.Get("resource"), scopes...),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"scope":        strings.Join(scopes, " "),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
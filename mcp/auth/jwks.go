@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwksRefreshInterval is how long the keys are kept before they are fetched again,
+// an unknown key id fetches them sooner but at most once per jwksMinRefreshInterval
+const (
+	jwksRefreshInterval    = time.Hour
+	jwksMinRefreshInterval = time.Minute
+)
+
+// JWKS is the JSON Web Key Set of an issuer, fetched when needed
+type JWKS struct {
+	issuer string
+	url    string
+	client *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// NewJWKS creates the key set of the issuer served at url. Without url, it is the jwks_uri
+// of the authorization server metadata of the issuer.
+func NewJWKS(issuer string, url string) *JWKS {
+	return &JWKS{
+		issuer: issuer,
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Key returns the public key of the key id, an empty key id being the only key of the set
+func (k *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key, ok := k.find(kid)
+	stale := time.Since(k.fetched) > jwksRefreshInterval
+	if (!ok && time.Since(k.fetched) > jwksMinRefreshInterval) || stale {
+		if err := k.fetch(ctx); err != nil {
+			// the keys fetched before stay valid when the issuer is unreachable
+			if !ok {
+				return nil, err
+			}
+			return key, nil
+		}
+		key, ok = k.find(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+func (k *JWKS) find(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(k.keys) == 1 {
+		for _, key := range k.keys {
+			return key, true
+		}
+	}
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+func (k *JWKS) fetch(ctx context.Context) error {
+	if k.url == "" {
+		jwksURL, err := k.discover(ctx)
+		if err != nil {
+			return err
+		}
+		k.url = jwksURL
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := k.get(ctx, k.url, &set); err != nil {
+		return fmt.Errorf("could not fetch the signing keys: %w", err)
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// a key of an unsupported type does not invalidate the others
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	k.keys = keys
+	k.fetched = time.Now()
+	return nil
+}
+
+// discover returns the jwks_uri of the authorization server metadata of the issuer (RFC 8414)
+func (k *JWKS) discover(ctx context.Context) (string, error) {
+	issuer, err := url.Parse(k.issuer)
+	if err != nil {
+		return "", err
+	}
+	metadataURL := *issuer
+	metadataURL.Path = "/.well-known/oauth-authorization-server" + strings.TrimSuffix(issuer.Path, "/")
+
+	var metadata struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := k.get(ctx, metadataURL.String(), &metadata); err != nil {
+		return "", fmt.Errorf("could not discover the signing keys of %s: %w", k.issuer, err)
+	}
+	if metadata.Issuer != k.issuer || metadata.JWKSURI == "" {
+		return "", fmt.Errorf("invalid authorization server metadata for %s", k.issuer)
+	}
+	return metadata.JWKSURI, nil
+}
+
+func (k *JWKS) get(ctx context.Context, url string, body interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	response, err := k.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s answered %s", url, response.Status)
+	}
+	return json.NewDecoder(response.Body).Decode(body)
+}
+
+// jwk is a JSON Web Key (RFC 7517), RSA and P-256 keys are supported
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (j jwk) publicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(j.E)
+		if err != nil || !e.IsInt64() {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if j.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", j.Crv)
+		}
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		if x.BitLen() > 256 || y.BitLen() > 256 {
+			return nil, errors.New("invalid P-256 key")
+		}
+		// an uncompressed point is only accepted by ecdh when it is on the curve
+		point := append([]byte{4}, append(x.FillBytes(make([]byte, 32)), y.FillBytes(make([]byte, 32))...)...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, errors.New("invalid P-256 key")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", j.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
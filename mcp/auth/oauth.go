@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ProtectedResourcePath is where the protected resource metadata is served (RFC 9728)
+const ProtectedResourcePath = "/.well-known/oauth-protected-resource"
+
+// clockSkew is the leeway given to the expiry and not before times of the tokens
+const clockSkew = 30 * time.Second
+
+// JWTs authenticates the requests with the bearer JWT access tokens of an issuer, per the
+// MCP authorization spec: the tokens are signed by a key of the issuer, RS256 or ES256,
+// and their audience is the MCP server.
+type JWTs struct {
+	issuer   string
+	audience string
+	keys     *JWKS
+	now      func() time.Time
+}
+
+// NewJWTs creates the authenticator of the tokens of the issuer for the audience,
+// the URL of the MCP endpoint
+func NewJWTs(issuer string, audience string, keys *JWKS) *JWTs {
+	return &JWTs{issuer: issuer, audience: audience, keys: keys, now: time.Now}
+}
+
+// Claims are the claims of an access token the server checks
+type Claims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+	// Scope is the space separated scopes granted to the token
+	Scope string `json:"scope"`
+}
+
+// audience is a single audience or a list of them
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(a))
+}
+
+// Authenticate returns the subject and the scopes of the token of the request
+func (j *JWTs) Authenticate(r *http.Request) (Identity, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return Identity{}, ErrNoCredentials
+	}
+	claims, err := j.Verify(r.Context(), token)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	scopes := strings.Fields(claims.Scope)
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return Identity{Name: claims.Subject, Scopes: scopes}, nil
+}
+
+// Verify checks the signature, issuer, audience and validity period of a token
+func (j *JWTs) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Claims{}, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, errors.New("malformed signature")
+	}
+	key, err := j.keys.Key(ctx, header.Kid)
+	if err != nil {
+		return Claims{}, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return Claims{}, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, err
+	}
+	now := j.now()
+	switch {
+	case claims.Issuer != j.issuer:
+		return Claims{}, fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	case !slices.Contains(claims.Audience, j.audience):
+		return Claims{}, fmt.Errorf("token not meant for %s", j.audience)
+	case claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(clockSkew)):
+		return Claims{}, errors.New("token expired")
+	case claims.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(claims.NotBefore, 0)):
+		return Claims{}, errors.New("token not valid yet")
+	case claims.Subject == "":
+		return Claims{}, errors.New("token without subject")
+	}
+	return claims, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errors.New("malformed token")
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return errors.New("malformed token")
+	}
+	return nil
+}
+
+// verifySignature checks the signature of the algorithm, the key type has to match it
+// so a token cannot pick a weaker algorithm than the key of the issuer
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+	switch alg {
+	case "RS256":
+		if key, ok := key.(*rsa.PublicKey); ok && rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil {
+			return nil
+		}
+	case "ES256":
+		if key, ok := key.(*ecdsa.PublicKey); ok && len(signature) == 64 {
+			r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+			if ecdsa.Verify(key, digest[:], r, s) {
+				return nil
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	return errors.New("invalid signature")
+}
+
+// ProtectedResource is the OAuth protected resource metadata of the MCP server (RFC 9728),
+// telling the clients which authorization server issues its tokens
+type ProtectedResource struct {
+	Resource               string   `json:"resource"`
+	AuthorizationServers   []string `json:"authorization_servers"`
+	ScopesSupported        []string `json:"scopes_supported,omitempty"`
+	BearerMethodsSupported []string `json:"bearer_methods_supported"`
+	ResourceName           string   `json:"resource_name,omitempty"`
+}
+
+func (p ProtectedResource) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// MetadataURL returns the URL of the protected resource metadata of the resource,
+// given to the clients in the WWW-Authenticate header of the 401 responses
+func (p ProtectedResource) MetadataURL() string {
+	resource, err := url.Parse(p.Resource)
+	if err != nil {
+		return ProtectedResourcePath
+	}
+	return (&url.URL{Scheme: resource.Scheme, Host: resource.Host, Path: ProtectedResourcePath}).String()
+}
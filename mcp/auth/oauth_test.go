@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexisZankowitch/concept-insight/mcp/auth/fakeissuer"
+	"go.uber.org/zap"
+)
+
+const resource = "http://localhost:8080/mcp"
+
+func Test_JWTs(t *testing.T) {
+	issuer := fakeissuer.New()
+	defer issuer.Close()
+	other := fakeissuer.New()
+	defer other.Close()
+	// the keys are discovered from the metadata of the issuer
+	tokens := NewJWTs(issuer.URL(), resource, NewJWKS(issuer.URL(), ""))
+
+	identity, err := tokens.Authenticate(bearerRequest(issuer.AccessToken("alice", resource, "slack:read")))
+	if err != nil || identity.Name != "alice" || !slices.Equal(identity.Scopes, []string{"slack:read"}) {
+		t.Fatalf("expected the subject and scopes of the token, got %+v %v", identity, err)
+	}
+	if !identity.Allows("slack:read") || identity.Allows("slack:admin") {
+		t.Fatalf("expected only the scopes of the token allowed")
+	}
+
+	past := time.Now().Add(-time.Hour).Unix()
+	for name, token := range map[string]string{
+		"another audience": issuer.AccessToken("alice", "http://elsewhere/mcp", "slack:read"),
+		"expired":          issuer.Sign(map[string]interface{}{"iss": issuer.URL(), "sub": "alice", "aud": resource, "exp": past}),
+		"another issuer":   other.AccessToken("alice", resource, "slack:read"),
+		"forged issuer":    other.Sign(map[string]interface{}{"iss": issuer.URL(), "sub": "alice", "aud": resource, "exp": time.Now().Add(time.Hour).Unix()}),
+		"unsigned":         "eyJhbGciOiJub25lIn0.eyJzdWIiOiJhbGljZSJ9.",
+		"malformed":        "not-a-token",
+	} {
+		if _, err := tokens.Authenticate(bearerRequest(token)); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("expected the token with %s rejected, got %v", name, err)
+		}
+	}
+	if _, err := tokens.Authenticate(httptest.NewRequest(http.MethodPost, "/mcp", nil)); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("expected no credentials, got %v", err)
+	}
+}
+
+func Test_OAuthMiddleware(t *testing.T) {
+	issuer := fakeissuer.New()
+	defer issuer.Close()
+	keys, _ := NewAPIKeys(map[string]string{"n8n": HashAPIKey("n8n-secret")}, nil, "slack:read")
+	authenticator := Chain(NewJWTs(issuer.URL(), resource, NewJWKS(issuer.URL(), issuer.URL()+"/jwks.json")), keys)
+	metadata := ProtectedResource{Resource: resource, AuthorizationServers: []string{issuer.URL()}, BearerMethodsSupported: []string{"header"}}
+	challenge := `Bearer resource_metadata="` + metadata.MetadataURL() + `"`
+	handler := Middleware(authenticator, challenge, zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, _ := FromContext(r.Context())
+		w.Write([]byte(identity.Name))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mcp", nil))
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != `Bearer resource_metadata="http://localhost:8080/.well-known/oauth-protected-resource"` {
+		t.Fatalf("expected a challenge pointing to the metadata, got %d %v", w.Code, w.Header())
+	}
+
+	// a client gets a token for the resource from the issuer
+	response, err := http.PostForm(issuer.URL()+"/token", url.Values{"grant_type": {"client_credentials"}, "client_id": {"bob"}, "scope": {"slack:read"}, "resource": {resource}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	json.NewDecoder(response.Body).Decode(&token)
+	for key, name := range map[string]string{token.AccessToken: "bob", "n8n-secret": "n8n"} {
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, bearerRequest(key))
+		if w.Code != http.StatusOK || w.Body.String() != name {
+			t.Fatalf("expected %s authenticated, got %d %s", name, w.Code, w.Body.String())
+		}
+	}
+}
+
+func Test_ProtectedResource(t *testing.T) {
+	metadata := ProtectedResource{Resource: resource, AuthorizationServers: []string{"https://issuer.example"}, ScopesSupported: []string{"slack:read"}, BearerMethodsSupported: []string{"header"}}
+	w := httptest.NewRecorder()
+	metadata.ServeHTTP(w, httptest.NewRequest(http.MethodGet, ProtectedResourcePath, nil))
+
+	if !strings.Contains(w.Body.String(), `"resource":"http://localhost:8080/mcp","authorization_servers":["https://issuer.example"],"scopes_supported":["slack:read"]`) {
+		t.Fatalf("unexpected metadata %s", w.Body.String())
+	}
+}
+
+func Test_JWKSUnreachable(t *testing.T) {
+	keys := NewJWKS("http://127.0.0.1:1", "")
+	if _, err := keys.Key(context.Background(), "any"); err == nil {
+		t.Fatalf("expected an error without issuer")
+	}
+}
+
+func bearerRequest(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
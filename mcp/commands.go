@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AlexisZankowitch/concept-insight/mcp/slack"
+	"github.com/AlexisZankowitch/concept-insight/toolid"
+)
+
+// runCommand runs a command given on the command line instead of the server
+func runCommand(ctx context.Context, slackService *slack.SlackService, channels *slack.ChannelSet, args []string) error {
+	switch args[0] {
+	case "skills-matrix":
+		return runSkillsMatrix(ctx, slackService, channels, args[1:], os.Stdout)
+	}
+	return fmt.Errorf("unknown command %q, expected skills-matrix", args[0])
+}
+
+// runSkillsMatrix exports the skills matrix, e.g.
+// go run . skills-matrix -format csv -since 1y -output skills.csv
+func runSkillsMatrix(ctx context.Context, slackService *slack.SlackService, channels *slack.ChannelSet, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("skills-matrix", flag.ContinueOnError)
+	format := flags.String("format", slack.SkillsFormatMarkdown, "export format: "+strings.Join(slack.SkillsFormats, ", "))
+	technologies := flags.String("technologies", "", "comma separated technologies of the columns, the whole catalog by default")
+	channelNames := flags.String("channels", "", "comma separated channels to search instead of the default ones")
+	after := flags.String("after", "", "only count the posts from this date on, as YYYY-MM-DD or RFC3339")
+	before := flags.String("before", "", "only count the posts before this date, as YYYY-MM-DD or RFC3339")
+	since := flags.String("since", "", "only count the posts of the last period, e.g. 90d, 12w, 6m or 1y")
+	output := flags.String("output", "", "file to write the matrix to, stdout by default")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if !slices.Contains(slack.SkillsFormats, *format) {
+		return fmt.Errorf("unknown format %q, expected one of %s", *format, strings.Join(slack.SkillsFormats, ", "))
+	}
+	window, err := slack.ParseTimeRange(*after, *before, *since, time.Now())
+	if err != nil {
+		return err
+	}
+	searched, err := channels.Select(toolid.SkillsMatrix, splitFlag(*channelNames))
+	if err != nil {
+		return err
+	}
+	searchedNames := []string{}
+	for _, channel := range searched {
+		searchedNames = append(searchedNames, channel.Name)
+	}
+	columns := splitFlag(*technologies)
+	if len(columns) == 0 {
+		for _, tech := range slackService.Taxonomy().Technologies() {
+			columns = append(columns, tech.Name)
+		}
+	}
+
+	matrix, err := slackService.SkillsMatrix(ctx, columns, searchedNames, window)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return matrix.Write(stdout, *format)
+	}
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := matrix.Write(file, *format); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// splitFlag splits a comma separated flag, nil when empty
+func splitFlag(value string) []string {
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
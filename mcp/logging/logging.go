@@ -0,0 +1,106 @@
+// Package logging builds the zap logger of the server and carries the logger of a request,
+// tagged with its correlation id, in the context of the request.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Config configures the logger
+type Config struct {
+	// Level is the minimum level logged: debug, info, warn or error
+	Level string
+	// Redact hides the fields made with Content, such as message texts and tool arguments
+	Redact bool
+}
+
+// New creates a JSON logger writing to stderr, stdout being left to the stdio transport and the commands
+func New(config Config) (*zap.Logger, error) {
+	level, err := zapcore.ParseLevel(config.Level)
+	if err != nil {
+		return nil, err
+	}
+	cfg := zap.NewProductionConfig()
+	cfg.Level = zap.NewAtomicLevelAt(level)
+	cfg.Sampling = nil
+	logger, err := cfg.Build()
+	if err != nil {
+		return nil, err
+	}
+	if config.Redact {
+		logger = logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return redactingCore{core}
+		}))
+	}
+	return logger, nil
+}
+
+type loggerKey struct{}
+
+// WithCorrelationID tags the logger with a new correlation id, returning a context carrying it
+func WithCorrelationID(ctx context.Context, logger *zap.Logger) (context.Context, *zap.Logger) {
+	logger = logger.With(zap.String("correlation_id", newCorrelationID()))
+	return context.WithValue(ctx, loggerKey{}, logger), logger
+}
+
+// FromContext returns the logger of the request, or the fallback outside of a request
+func FromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	return fallback
+}
+
+func newCorrelationID() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// content is a field value redacted by a redacting logger
+type content string
+
+func (c content) String() string {
+	return string(c)
+}
+
+// Content is a field holding what people wrote, such as a message text, only logged when redaction is off
+func Content(key string, value string) zap.Field {
+	return zap.Stringer(key, content(value))
+}
+
+// redactingCore replaces the value of the Content fields
+type redactingCore struct {
+	zapcore.Core
+}
+
+func (c redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return redactingCore{c.Core.With(redact(fields))}
+}
+
+func (c redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, redact(fields))
+}
+
+func redact(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		if _, ok := field.Interface.(content); ok && field.Type == zapcore.StringerType {
+			field = zap.String(field.Key, "[redacted]")
+		}
+		redacted[i] = field
+	}
+	return redacted
+}
@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func Test_Redaction(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(redactingCore{core}).With(Content("query", "golang"))
+
+	logger.Info("searched", Content("text", "our secret roadmap"), zap.String("tool", "find-experts"))
+	fields := logs.All()[0].ContextMap()
+	if fields["text"] != "[redacted]" || fields["query"] != "[redacted]" || fields["tool"] != "find-experts" {
+		t.Fatalf("expected the content redacted, got %+v", fields)
+	}
+
+	core, logs = observer.New(zapcore.DebugLevel)
+	zap.New(core).Info("searched", Content("text", "our secret roadmap"))
+	if text := logs.All()[0].ContextMap()["text"]; text != "our secret roadmap" {
+		t.Fatalf("expected the content without redaction, got %v", text)
+	}
+}
+
+func Test_CorrelationID(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	fallback := zap.New(core)
+
+	if FromContext(context.Background(), fallback) != fallback {
+		t.Fatalf("expected the fallback outside of a request")
+	}
+	first, _ := WithCorrelationID(context.Background(), fallback)
+	second, _ := WithCorrelationID(context.Background(), fallback)
+	FromContext(first, fallback).Info("first")
+	FromContext(second, fallback).Info("second")
+
+	ids := []interface{}{logs.All()[0].ContextMap()["correlation_id"], logs.All()[1].ContextMap()["correlation_id"]}
+	if ids[0] == nil || len(ids[0].(string)) != 16 || ids[0] == ids[1] {
+		t.Fatalf("expected a correlation id per request, got %v", ids)
+	}
+}
+
+func Test_New(t *testing.T) {
+	if _, err := New(Config{Level: "verbose"}); err == nil {
+		t.Fatalf("expected an unknown level error")
+	}
+	logger, err := New(Config{Level: "debug", Redact: true})
+	if err != nil || !logger.Core().Enabled(zapcore.DebugLevel) {
+		t.Fatalf("expected a debug logger, got %v", err)
+	}
+}
@@ -1,15 +1,30 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"maps"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
 
+	"github.com/AlexisZankowitch/concept-insight/config"
+	"github.com/AlexisZankowitch/concept-insight/mcp/auth"
+	"github.com/AlexisZankowitch/concept-insight/mcp/logging"
+	"github.com/AlexisZankowitch/concept-insight/mcp/mcpresource"
+	"github.com/AlexisZankowitch/concept-insight/mcp/mcptool"
 	"github.com/AlexisZankowitch/concept-insight/mcp/slack"
 	"github.com/AlexisZankowitch/concept-insight/utils"
+	slackapi "github.com/slack-go/slack"
 	"github.com/strowk/foxy-contexts/pkg/app"
 	"github.com/strowk/foxy-contexts/pkg/fxctx"
 	"github.com/strowk/foxy-contexts/pkg/mcp"
-	"github.com/strowk/foxy-contexts/pkg/streamable_http"
+	"github.com/strowk/foxy-contexts/pkg/server"
 	"go.uber.org/fx"
 	"go.uber.org/fx/fxevent"
 	"go.uber.org/zap"
@@ -24,54 +39,199 @@ func (m *MySessionData) String() string {
 }
 
 func main() {
-	slackService := slack.NewSlackService()
+	// defaults < config file < .env and environment < flags, the arguments left are a command
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	cfg, err := config.Load(flags, os.Args[1:])
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	// the logs go to stderr, the message texts are redacted unless LOG_REDACT=false
+	logger, err := logging.New(logging.Config{
+		Level:  cfg.Log.Level,
+		Redact: cfg.Log.Redact,
+	})
+	if err != nil {
+		log.Fatalf("Logger error: %v", err)
+	}
+	defer logger.Sync()
 
+	slackOptions := []slackapi.Option{}
+	if cfg.SlackAPIURL != "" {
+		slackOptions = append(slackOptions, slackapi.OptionAPIURL(cfg.SlackAPIURL))
+	}
+	slackService := slack.NewSlackService(cfg.SlackToken, slackOptions...)
+	slackService.UseLogger(logger)
+	channels, err := slackService.ResolveChannels(context.Background(), slack.ChannelConfig{
+		Default: cfg.Channels.Default,
+		Allowed: cfg.Channels.Allowed,
+		Tools:   cfg.Channels.Tools,
+	})
+	if err != nil {
+		logger.Fatal("invalid channel configuration", zap.Error(err))
+	}
+
+	taxonomy, err := slack.LoadTaxonomy(cfg.TechnologiesPath)
+	if err != nil {
+		logger.Fatal("invalid technologies", zap.Error(err))
+	}
+	slackService.UseTaxonomy(taxonomy)
+
+	// keep a local copy of the channels, the tools answer from it once synced
+	var store *slack.Store
+	if cfg.Store.Path != "" {
+		store, err = slack.OpenStore(cfg.Store.Path)
+		if err != nil {
+			logger.Fatal("could not open the store", zap.Error(err))
+		}
+		defer store.Close()
+		if err := slackService.UseStore(store); err != nil {
+			logger.Fatal("could not read the store", zap.Error(err))
+		}
+	}
+
+	// commands such as skills-matrix run instead of the server
+	if args := flags.Args(); len(args) > 0 {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		ctx, _ = logging.WithCorrelationID(ctx, logger.With(zap.String("command", args[0])))
+		if err := runCommand(ctx, slackService, channels, args); err != nil {
+			logger.Fatal("command failed", zap.String("command", args[0]), zap.Error(err))
+		}
+		return
+	}
+
+	if store != nil {
+		syncCtx, stopSync := context.WithCancel(context.Background())
+		defer stopSync()
+		syncer := slack.NewSyncer(slackService, store, channels.All(), cfg.Store.SyncInterval, cfg.Store.SyncLookback)
+		go syncer.Run(syncCtx)
+	}
+
+	transport, err := newTransport(cfg, logger)
+	if err != nil {
+		logger.Fatal("invalid transport", zap.Error(err))
+	}
 
 	server := app.
 	NewBuilder().
 	// adding the tool to the app
-	WithTool(func() fxctx.Tool { return slack.NewFindTechnologyPost(slackService) }).
+	WithTool(func() fxctx.Tool { return slack.NewFindTechnologyPost(slackService, channels) }).
 	WithTool(func() fxctx.Tool { return slack.NewGetConceptUserDetails(slackService) }).
-	WithTool(func() fxctx.Tool { return slack.NewGetLastestPostsByUserId(slackService) }).
+	WithTool(func() fxctx.Tool { return slack.NewGetLastestPostsByUserId(slackService, channels) }).
+	WithTool(func() fxctx.Tool { return slack.NewGetThread(slackService, channels) }).
+	WithTool(func() fxctx.Tool { return slack.NewFindExperts(slackService, channels) }).
+	WithTool(func() fxctx.Tool { return slack.NewListTechnologies(slackService) }).
+	WithTool(func() fxctx.Tool { return slack.NewTechnologyTrends(slackService, channels) }).
+	WithTool(func() fxctx.Tool { return slack.NewSkillsMatrix(slackService, channels) }).
+	WithTool(func() fxctx.Tool { return slack.NewGetUserExpertiseProfile(slackService, channels) }).
 	WithServerCapabilities(&mcp.ServerCapabilities{
 		Tools: &mcp.ServerCapabilitiesTools{
 			ListChanged: utils.Ptr(false),
 		},
+		Resources: &mcp.ServerCapabilitiesResources{
+			ListChanged: utils.Ptr(false),
+			Subscribe:   utils.Ptr(false),
+		},
 	}).
 	// setting up server
-	WithName("concept-insight-server").
-	WithVersion("0.0.1").
-	WithTransport(
-		streamable_http.NewTransport(
-			streamable_http.Endpoint{
-				Hostname: "localhost",
-				Port:     8080,
-				Path:     "/mcp",
-			}),
-		).
+	WithName(cfg.Server.Name).
+	WithVersion(cfg.Server.Version).
+	WithTransport(transport).
 		// Configuring fx logging to only show errors
 		WithFxOptions(
-			fx.Provide(func() *zap.Logger {
-				cfg := zap.NewDevelopmentConfig()
-				cfg.Level.SetLevel(zap.ErrorLevel)
-				logger, _ := cfg.Build()
-				return logger
+			// serving the output schemas and structured content of the tools
+			mcptool.ProvideToolMux(mcptool.Timeouts{
+				Default: cfg.ToolTimeouts.Default,
+				Tools:   cfg.ToolTimeouts.Tools,
+			}),
+			// the users, channels and messages as resources under URI templates
+			mcpresource.ProvideResourceMux(),
+			fx.Provide(
+				mcpresource.AsTemplate(func() mcpresource.Template { return slack.NewUserResource(slackService, channels) }),
+				mcpresource.AsTemplate(func() mcpresource.Template { return slack.NewChannelResource(slackService, channels) }),
+				mcpresource.AsTemplate(func() mcpresource.Template { return slack.NewMessageResource(slackService, channels) }),
+			),
+			// the tools log with the logger of the service
+			fx.Supply(logger),
+			config.Module(cfg),
+			// the scopes the access tokens need to call the tools
+			fx.Supply(auth.Scopes{
+				Default: cfg.Auth.OAuth.Scopes.Default,
+				Tools:   cfg.Auth.OAuth.Scopes.Tools,
 			}),
 			fx.Option(fx.WithLogger(
 				func(logger *zap.Logger) fxevent.Logger {
-					return &fxevent.ZapLogger{Logger: logger}
+					return &fxevent.ZapLogger{Logger: logger.WithOptions(zap.IncreaseLevel(zap.ErrorLevel))}
 				},
 			)),
 		)
 
-	err := server.Run()
+	err = server.Run()
 	if err != nil {
 		if err == http.ErrServerClosed {
-			log.Println("Server closed")
+			logger.Info("server closed")
+		} else {
+			logger.Fatal("server error", zap.Error(err))
+		}
+	}
+}
+
+// newTransport creates the transport of the MCP server. The stdio transport writes the
+// JSON-RPC messages to stdout, so nothing else must be written there. The http transport
+// requires an API key or an access token when keys or an issuer are configured.
+func newTransport(cfg config.Config, logger *zap.Logger) (server.Transport, error) {
+	switch cfg.Server.Transport {
+	case config.TransportHTTP:
+		addr := net.JoinHostPort(cfg.Server.Hostname, strconv.Itoa(cfg.Server.Port))
+		authenticators := []auth.Authenticator{}
+		challenge := `Bearer realm="concept-insight"`
+		routes := map[string]http.Handler{}
+
+		// access tokens per the MCP authorization spec, the clients find the issuer in the metadata
+		if oauth := cfg.Auth.OAuth; oauth.Issuer != "" {
+			scopes := []string{}
+			for _, scope := range append([]string{oauth.Scopes.Default}, slices.Sorted(maps.Values(oauth.Scopes.Tools))...) {
+				if scope != "" && !slices.Contains(scopes, scope) {
+					scopes = append(scopes, scope)
+				}
+			}
+			metadata := auth.ProtectedResource{
+				Resource:               oauth.Resource,
+				AuthorizationServers:   []string{oauth.Issuer},
+				ScopesSupported:        scopes,
+				BearerMethodsSupported: []string{"header"},
+				ResourceName:           cfg.Server.Name,
+			}
+			authenticators = append(authenticators, auth.NewJWTs(oauth.Issuer, oauth.Resource, auth.NewJWKS(oauth.Issuer, oauth.JWKSURL)))
+			challenge += fmt.Sprintf(`, resource_metadata="%s"`, metadata.MetadataURL())
+			routes["GET "+auth.ProtectedResourcePath] = metadata
+			routes["GET "+auth.ProtectedResourcePath+cfg.Server.Path] = metadata
+		}
+		if len(cfg.Auth.APIKeys) > 0 {
+			keys, err := auth.NewAPIKeys(cfg.Auth.APIKeys, cfg.Auth.APIKeyScopes, cfg.Auth.OAuth.Scopes.Default)
+			if err != nil {
+				return nil, err
+			}
+			authenticators = append(authenticators, keys)
+		}
+
+		middleware := []func(http.Handler) http.Handler{}
+		if len(authenticators) > 0 {
+			middleware = append(middleware, auth.Middleware(auth.Chain(authenticators...), challenge, logger))
 		} else {
-			log.Fatalf("Server error: %v", err)
+			logger.Warn("the http transport is not authenticated, configure API keys or an OAuth issuer to require them")
+		}
+		transport := mcptool.NewHTTPTransport(addr, cfg.Server.Path, middleware...)
+		transport.SetSessionIdleTimeout(cfg.Server.SessionIdleTimeout)
+		for pattern, handler := range routes {
+			transport.Handle(pattern, handler)
 		}
+		return transport, nil
+	case config.TransportStdio:
+		return mcptool.NewStdioTransport(os.Stdin, os.Stdout), nil
 	}
+	return nil, fmt.Errorf("unknown transport %q, expected http or stdio", cfg.Server.Transport)
 }
 
 // --8<-- [end:server]
@@ -0,0 +1,283 @@
+// Package mcpresource adds URI templates to the foxy-contexts resources: templates are
+// listed by resources/templates/list and resources/read reads any URI matching them.
+package mcpresource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AlexisZankowitch/concept-insight/mcp/auth"
+	"github.com/AlexisZankowitch/concept-insight/mcp/logging"
+	"github.com/strowk/foxy-contexts/pkg/fxctx"
+	"github.com/strowk/foxy-contexts/pkg/jsonrpc2"
+	"github.com/strowk/foxy-contexts/pkg/mcp"
+	"github.com/strowk/foxy-contexts/pkg/server"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// ResourceNotFound is the error code of the reads of unknown URIs, per the MCP spec
+const ResourceNotFound = -32002
+
+// ErrNotFound is returned, possibly wrapped, by the templates reading a URI naming nothing
+var ErrNotFound = errors.New("resource not found")
+
+// Template is a resource template, reading the resources whose URI matches it
+type Template interface {
+	GetResourceTemplate() mcp.ResourceTemplate
+	// List returns the resources of the template worth listing, possibly none
+	List(ctx context.Context) ([]mcp.Resource, error)
+	// Read reads the resource of the URI, vars are the values of the variables of the template
+	Read(ctx context.Context, uri string, vars map[string]string) (*mcp.ReadResourceResult, error)
+}
+
+type template struct {
+	mcpTemplate mcp.ResourceTemplate
+	list        func(ctx context.Context) ([]mcp.Resource, error)
+	read        func(ctx context.Context, uri string, vars map[string]string) (*mcp.ReadResourceResult, error)
+}
+
+// NewTemplate creates a template reading its resources with read. list may be nil
+// when the resources of the template are not listed.
+func NewTemplate(
+	mcpTemplate mcp.ResourceTemplate,
+	list func(ctx context.Context) ([]mcp.Resource, error),
+	read func(ctx context.Context, uri string, vars map[string]string) (*mcp.ReadResourceResult, error)) Template {
+	return &template{
+		mcpTemplate: mcpTemplate,
+		list:        list,
+		read:        read,
+	}
+}
+
+func (t *template) GetResourceTemplate() mcp.ResourceTemplate {
+	return t.mcpTemplate
+}
+
+func (t *template) List(ctx context.Context) ([]mcp.Resource, error) {
+	if t.list == nil {
+		return []mcp.Resource{}, nil
+	}
+	return t.list(ctx)
+}
+
+func (t *template) Read(ctx context.Context, uri string, vars map[string]string) (*mcp.ReadResourceResult, error) {
+	return t.read(ctx, uri, vars)
+}
+
+// AsTemplate annotates a constructor of a Template, for fx.Provide
+func AsTemplate(f any) any {
+	return fx.Annotate(f, fx.As(new(Template)), fx.ResultTags(`group:"resource_templates"`))
+}
+
+// matcher matches the URIs of a template
+type matcher struct {
+	template Template
+	pattern  *regexp.Regexp
+	vars     []string
+}
+
+// variablePattern matches the simple {name} expressions of RFC 6570
+var variablePattern = regexp.MustCompile(`\{([A-Za-z0-9_]+)\}`)
+
+// newMatcher compiles the URI template, each variable matching one path segment
+func newMatcher(template Template) (matcher, error) {
+	uriTemplate := template.GetResourceTemplate().UriTemplate
+	m := matcher{template: template}
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	last := 0
+	literal := func(text string) error {
+		// the other expressions of RFC 6570, such as {+path} or {?query}, are not supported
+		if strings.ContainsAny(text, "{}") {
+			return fmt.Errorf("unsupported URI template %q, only {name} variables are supported", uriTemplate)
+		}
+		pattern.WriteString(regexp.QuoteMeta(text))
+		return nil
+	}
+	for _, loc := range variablePattern.FindAllStringSubmatchIndex(uriTemplate, -1) {
+		if err := literal(uriTemplate[last:loc[0]]); err != nil {
+			return matcher{}, err
+		}
+		pattern.WriteString("([^/?#]+)")
+		m.vars = append(m.vars, uriTemplate[loc[2]:loc[3]])
+		last = loc[1]
+	}
+	if err := literal(uriTemplate[last:]); err != nil {
+		return matcher{}, err
+	}
+	pattern.WriteString("$")
+	m.pattern = regexp.MustCompile(pattern.String())
+	return m, nil
+}
+
+// match returns the values of the variables in the URI, false when it does not match
+func (m matcher) match(uri string) (map[string]string, bool) {
+	values := m.pattern.FindStringSubmatch(uri)
+	if values == nil {
+		return nil, false
+	}
+	vars := map[string]string{}
+	for i, name := range m.vars {
+		value, err := url.PathUnescape(values[i+1])
+		if err != nil {
+			return nil, false
+		}
+		vars[name] = value
+	}
+	return vars, true
+}
+
+// ResourceMux serves resources/list, resources/read and resources/templates/list, reading the
+// URIs matching a template with the template and the others with the foxy-contexts resource mux.
+// Unknown URIs are answered with the resource not found error.
+type ResourceMux struct {
+	fxctx.ResourceMux
+	matchers []matcher
+	// scope is the scope the tokens need to read the resources
+	scope  string
+	logger *zap.Logger
+}
+
+// NewResourceMux creates a mux serving the templates next to the resources of the inner mux
+func NewResourceMux(inner fxctx.ResourceMux, templates []Template) (*ResourceMux, error) {
+	m := &ResourceMux{ResourceMux: inner, logger: zap.NewNop()}
+	for _, template := range templates {
+		matcher, err := newMatcher(template)
+		if err != nil {
+			return nil, err
+		}
+		m.matchers = append(m.matchers, matcher)
+	}
+	// the templates are provided in no particular order, they are listed by name
+	sort.Slice(m.matchers, func(i, j int) bool {
+		return m.matchers[i].template.GetResourceTemplate().Name < m.matchers[j].template.GetResourceTemplate().Name
+	})
+	return m, nil
+}
+
+// ProvideResourceMux replaces the foxy-contexts resource mux by a ResourceMux with the
+// templates of the app, logging the reads with the logger of the app. The tokens need
+// the default scope of the tools to read the resources.
+func ProvideResourceMux() fx.Option {
+	return fx.Decorate(fx.Annotate(
+		func(inner fxctx.ResourceMux, templates []Template, logger *zap.Logger, scopes auth.Scopes) (fxctx.ResourceMux, error) {
+			m, err := NewResourceMux(inner, templates)
+			if err != nil {
+				return nil, err
+			}
+			m.scope = scopes.Default
+			if logger != nil {
+				m.logger = logger
+			}
+			return m, nil
+		},
+		fx.ParamTags(``, `group:"resource_templates"`, `optional:"true"`, `optional:"true"`),
+	))
+}
+
+// Templates returns the resource templates, by name
+func (m *ResourceMux) Templates() []mcp.ResourceTemplate {
+	templates := []mcp.ResourceTemplate{}
+	for _, matcher := range m.matchers {
+		templates = append(templates, matcher.template.GetResourceTemplate())
+	}
+	return templates
+}
+
+// GetResources returns the resources of the inner mux then the ones listed by the templates
+func (m *ResourceMux) GetResources(ctx context.Context) ([]mcp.Resource, error) {
+	resources, err := m.ResourceMux.GetResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, matcher := range m.matchers {
+		listed, err := matcher.template.List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", matcher.template.GetResourceTemplate().Name, err)
+		}
+		resources = append(resources, listed...)
+	}
+	return resources, nil
+}
+
+// ReadResource reads the resource of the URI, ErrNotFound when nothing has this URI
+func (m *ResourceMux) ReadResource(ctx context.Context, uri string) (*mcp.ReadResourceResult, error) {
+	for _, matcher := range m.matchers {
+		if vars, ok := matcher.match(uri); ok {
+			return matcher.template.Read(ctx, uri, vars)
+		}
+	}
+	result, err := m.ResourceMux.ReadResource(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	// the foxy-contexts mux reads unknown URIs as empty resources
+	if result == nil || len(result.Contents) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, uri)
+	}
+	return result, nil
+}
+
+// read reads a resource for a request, logging the read with a correlation id and the caller.
+// The API keys and tokens without the scope of the resources are refused.
+func (m *ResourceMux) read(ctx context.Context, uri string) (*mcp.ReadResourceResult, *jsonrpc2.Error) {
+	fields := []zap.Field{zap.String("uri", uri)}
+	identity, authenticated := auth.FromContext(ctx)
+	if authenticated {
+		fields = append(fields, zap.String("caller", identity.Name))
+	}
+	ctx, logger := logging.WithCorrelationID(ctx, m.logger.With(fields...))
+	if authenticated && !identity.Allows(m.scope) {
+		logger.Warn("resource read forbidden", zap.String("scope", m.scope))
+		return nil, jsonrpc2.NewServerError(fxctx.ReadResourceFailed, fmt.Sprintf("reading resources requires the %s scope", m.scope))
+	}
+
+	start := time.Now()
+	result, err := m.ReadResource(ctx, uri)
+	duration := zap.Duration("duration", time.Since(start))
+	if errors.Is(err, ErrNotFound) {
+		logger.Warn("resource not found", duration, zap.Error(err))
+		return nil, &jsonrpc2.Error{
+			Code:    ResourceNotFound,
+			Message: "Resource not found",
+			Data:    map[string]string{"uri": uri},
+		}
+	}
+	if err != nil {
+		logger.Warn("resource read failed", duration, zap.Error(err))
+		return nil, jsonrpc2.NewServerError(fxctx.ReadResourceFailed, fmt.Sprintf("failed to read resource: %v", err))
+	}
+	logger.Info("resource read", duration)
+	return result, nil
+}
+
+// RegisterHandlers serves resources/list, resources/read and resources/templates/list
+func (m *ResourceMux) RegisterHandlers(s server.Server) {
+	s.SetRequestHandler(&mcp.ListResourcesRequest{}, func(ctx context.Context, _ jsonrpc2.Request) (jsonrpc2.Result, *jsonrpc2.Error) {
+		resources, err := m.GetResources(ctx)
+		if err != nil {
+			return nil, jsonrpc2.NewServerError(fxctx.ListResourcesFailed, fmt.Sprintf("failed to get resources: %v", err))
+		}
+		return &mcp.ListResourcesResult{Resources: resources}, nil
+	})
+
+	s.SetRequestHandler(&mcp.ReadResourceRequest{}, func(ctx context.Context, r jsonrpc2.Request) (jsonrpc2.Result, *jsonrpc2.Error) {
+		req := r.(*mcp.ReadResourceRequest)
+		result, err := m.read(ctx, req.Params.Uri)
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	})
+
+	s.SetRequestHandler(&mcp.ListResourceTemplatesRequest{}, func(_ context.Context, _ jsonrpc2.Request) (jsonrpc2.Result, *jsonrpc2.Error) {
+		return &mcp.ListResourceTemplatesResult{ResourceTemplates: m.Templates()}, nil
+	})
+}
@@ -0,0 +1,137 @@
+package mcpresource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/AlexisZankowitch/concept-insight/mcp/auth"
+	"github.com/strowk/foxy-contexts/pkg/fxctx"
+	"github.com/strowk/foxy-contexts/pkg/mcp"
+	"go.uber.org/fx"
+)
+
+// newTestTemplate returns a template of items reading the items a and b, the URI being the text
+func newTestTemplate() Template {
+	return NewTemplate(
+		mcp.ResourceTemplate{Name: "item", UriTemplate: "test://items/{group}/{id}"},
+		func(ctx context.Context) ([]mcp.Resource, error) {
+			return []mcp.Resource{{Name: "a", Uri: "test://items/x/a"}}, nil
+		},
+		func(ctx context.Context, uri string, vars map[string]string) (*mcp.ReadResourceResult, error) {
+			if vars["id"] != "a" && vars["id"] != "b c" {
+				return nil, fmt.Errorf("%w: no item %s", ErrNotFound, vars["id"])
+			}
+			return &mcp.ReadResourceResult{Contents: []interface{}{
+				mcp.TextResourceContents{Uri: uri, Text: vars["group"] + "/" + vars["id"]},
+			}}, nil
+		},
+	)
+}
+
+// newTestMux returns a mux of the test template next to a static resource
+func newTestMux(t *testing.T) *ResourceMux {
+	t.Helper()
+	static := fxctx.NewResource(mcp.Resource{Name: "readme", Uri: "test://readme"}, func(ctx context.Context, uri string) (*mcp.ReadResourceResult, error) {
+		return &mcp.ReadResourceResult{Contents: []interface{}{mcp.TextResourceContents{Uri: uri, Text: "readme"}}}, nil
+	})
+	mux, err := NewResourceMux(fxctx.NewResourceMux([]fxctx.Resource{static}, nil), []Template{newTestTemplate()})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return mux
+}
+
+func Test_ResourceMuxRead(t *testing.T) {
+	mux := newTestMux(t)
+
+	for uri, expected := range map[string]string{
+		"test://items/x/a":     "x/a",
+		"test://items/y/b%20c": "y/b c",
+		"test://readme":        "readme",
+	} {
+		result, err := mux.ReadResource(context.Background(), uri)
+		if err != nil {
+			t.Fatalf("unexpected error reading %s: %v", uri, err)
+		}
+		if text := result.Contents[0].(mcp.TextResourceContents).Text; text != expected {
+			t.Errorf("expected %s read as %q, got %q", uri, expected, text)
+		}
+	}
+
+	for _, uri := range []string{"test://items/x/z", "test://items/x", "test://items/x/a/b", "test://other"} {
+		if _, err := mux.ReadResource(context.Background(), uri); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected %s not found, got %v", uri, err)
+		}
+	}
+}
+
+func Test_ResourceMuxReadErrors(t *testing.T) {
+	mux := newTestMux(t)
+	mux.scope = "slack:read"
+
+	_, err := mux.read(context.Background(), "test://items/x/z")
+	if err == nil || err.Code != ResourceNotFound || !reflect.DeepEqual(err.Data, map[string]string{"uri": "test://items/x/z"}) {
+		t.Fatalf("expected the resource not found error, got %+v", err)
+	}
+
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{Name: "alice", Scopes: []string{}})
+	_, err = mux.read(ctx, "test://items/x/a")
+	if err == nil || err.Code != fxctx.ReadResourceFailed || err.Data != "reading resources requires the slack:read scope" {
+		t.Fatalf("expected the token without scope refused, got %+v", err)
+	}
+	ctx = auth.WithIdentity(context.Background(), auth.Identity{Name: "n8n"})
+	if _, err := mux.read(ctx, "test://items/x/a"); err == nil {
+		t.Fatalf("expected the API key without scope refused")
+	}
+	ctx = auth.WithIdentity(context.Background(), auth.Identity{Name: "n8n", Scopes: []string{"slack:read"}})
+	if _, err := mux.read(ctx, "test://items/x/a"); err != nil {
+		t.Fatalf("expected the API key with the scope allowed, got %+v", err)
+	}
+}
+
+func Test_ResourceMuxList(t *testing.T) {
+	mux := newTestMux(t)
+
+	resources, err := mux.GetResources(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(resources) != 2 || resources[0].Uri != "test://readme" || resources[1].Uri != "test://items/x/a" {
+		t.Fatalf("expected the static resource then the listed items, got %+v", resources)
+	}
+	if templates := mux.Templates(); len(templates) != 1 || templates[0].UriTemplate != "test://items/{group}/{id}" {
+		t.Fatalf("unexpected templates %+v", templates)
+	}
+}
+
+func Test_NewResourceMuxUnsupportedTemplate(t *testing.T) {
+	for _, uriTemplate := range []string{"test://files/{+path}", "test://search{?q}", "test://items/{id"} {
+		template := NewTemplate(mcp.ResourceTemplate{Name: "bad", UriTemplate: uriTemplate}, nil, nil)
+		if _, err := NewResourceMux(fxctx.NewResourceMux(nil, nil), []Template{template}); err == nil {
+			t.Errorf("expected %s refused", uriTemplate)
+		}
+	}
+}
+
+func Test_ProvideResourceMux(t *testing.T) {
+	var mux fxctx.ResourceMux
+	app := fx.New(
+		fx.NopLogger,
+		fx.Provide(AsTemplate(func() Template { return newTestTemplate() })),
+		fxctx.ProvideResourceMux(),
+		ProvideResourceMux(),
+		fx.Populate(&mux),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, ok := mux.(*ResourceMux); !ok {
+		t.Fatalf("expected the templated resource mux, got %T", mux)
+	}
+	if _, err := mux.ReadResource(context.Background(), "test://items/x/a"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package mcptool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/strowk/foxy-contexts/pkg/mcp"
+)
+
+// Timeouts bounds the duration of the tool calls, a tool without timeout runs until its request is done
+type Timeouts struct {
+	// Default applies to the tools without their own timeout
+	Default time.Duration
+	// Tools overrides the default timeout per tool id, see toolid
+	Tools map[string]time.Duration
+}
+
+// For returns the timeout of a tool, zero when it has none
+func (t Timeouts) For(name string) time.Duration {
+	if timeout, ok := t.Tools[name]; ok {
+		return timeout
+	}
+	return t.Default
+}
+
+// callToolRequest is a tools/call request with its JSON-RPC id, which the foxy-contexts
+// request drops, so notifications/cancelled can find the call
+type callToolRequest struct {
+	mcp.CallToolRequest
+	id string
+}
+
+func (r *callToolRequest) UnmarshalJSON(b []byte) error {
+	if err := r.CallToolRequest.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	var envelope struct {
+		Id json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(b, &envelope); err != nil {
+		return err
+	}
+	r.id = requestKey(envelope.Id)
+	return nil
+}
+
+// cancelledNotification is notifications/cancelled, the foxy-contexts notification only accepts number ids
+type cancelledNotification struct {
+	Method string `json:"method"`
+	Params struct {
+		RequestId json.RawMessage `json:"requestId"`
+		Reason    string          `json:"reason,omitempty"`
+	} `json:"params"`
+}
+
+func (n cancelledNotification) GetMethod() string {
+	return mcp.CancelledNotification{}.GetMethod()
+}
+
+// requestKey identifies a JSON-RPC id, 1 and "1" being different ids
+func requestKey(id json.RawMessage) string {
+	return string(bytes.TrimSpace(id))
+}
+
+// track makes the context of a call cancellable by notifications/cancelled until done is called
+func (m *ToolMux) track(ctx context.Context, id string, name string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	if id == "" {
+		return ctx, func() { cancel(nil) }
+	}
+
+	key := m.sessionKey(ctx) + id
+	m.callsMu.Lock()
+	m.calls[key] = func(reason string) {
+		cancel(fmt.Errorf("%s was cancelled by the client%s", name, reason))
+	}
+	m.callsMu.Unlock()
+
+	return ctx, func() {
+		m.callsMu.Lock()
+		delete(m.calls, key)
+		m.callsMu.Unlock()
+		cancel(nil)
+	}
+}
+
+// cancel aborts the call of the request id, calls already answered are ignored
+func (m *ToolMux) cancel(ctx context.Context, id string, reason string) bool {
+	m.callsMu.Lock()
+	cancel, ok := m.calls[m.sessionKey(ctx)+id]
+	m.callsMu.Unlock()
+	if !ok {
+		return false
+	}
+	if reason != "" {
+		reason = ": " + reason
+	}
+	cancel(reason)
+	return true
+}
+
+// sessionKey scopes the request ids to the session of the client, ids are only unique per session
+func (m *ToolMux) sessionKey(ctx context.Context) string {
+	if m.sessions == nil {
+		return ""
+	}
+	if session, ok := m.sessions.GetSessionFromContext(ctx); ok {
+		return session.SessionID.String() + "/"
+	}
+	return ""
+}
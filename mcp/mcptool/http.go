@@ -0,0 +1,305 @@
+package mcptool
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/AlexisZankowitch/concept-insight/mcp/auth"
+	"github.com/google/uuid"
+	foxyevent "github.com/strowk/foxy-contexts/pkg/foxy_event"
+	"github.com/strowk/foxy-contexts/pkg/jsonrpc2"
+	"github.com/strowk/foxy-contexts/pkg/mcp"
+	"github.com/strowk/foxy-contexts/pkg/server"
+	"github.com/strowk/foxy-contexts/pkg/session"
+	"github.com/strowk/foxy-contexts/pkg/sse"
+)
+
+const sessionIDHeader = "Mcp-Session-Id"
+
+// DefaultSessionIdleTimeout is how long a session is kept without request
+const DefaultSessionIdleTimeout = 30 * time.Minute
+
+// HTTPTransport is the streamable HTTP transport of foxy-contexts served by net/http, so the
+// MCP endpoint can be wrapped by middleware such as the authentication. The tool calls get
+// the context of their HTTP request.
+type HTTPTransport struct {
+	addr       string
+	path       string
+	middleware []func(http.Handler) http.Handler
+	// routes are served next to the MCP endpoint, without the middleware
+	routes map[string]http.Handler
+
+	sessions *session.SessionManager
+	// idleTimeout expires the sessions without request for longer
+	idleTimeout  time.Duration
+	now          func() time.Time
+	httpServerMu sync.Mutex
+	httpServer   *http.Server
+}
+
+// NewHTTPTransport creates the transport listening on addr, serving MCP on path through the
+// middleware, the first one being the outermost
+func NewHTTPTransport(addr string, path string, middleware ...func(http.Handler) http.Handler) *HTTPTransport {
+	return &HTTPTransport{
+		addr:        addr,
+		path:        path,
+		middleware:  middleware,
+		routes:      map[string]http.Handler{},
+		sessions:    session.NewSessionManager(),
+		idleTimeout: DefaultSessionIdleTimeout,
+		now:         time.Now,
+	}
+}
+
+// SetSessionIdleTimeout expires the sessions without request for longer than idle,
+// DefaultSessionIdleTimeout otherwise
+func (t *HTTPTransport) SetSessionIdleTimeout(idle time.Duration) {
+	t.idleTimeout = idle
+}
+
+// Handle serves the handler for the pattern next to the MCP endpoint, without the middleware,
+// e.g. the metadata the clients read before authenticating
+func (t *HTTPTransport) Handle(pattern string, handler http.Handler) {
+	t.routes[pattern] = handler
+}
+
+func (t *HTTPTransport) Run(capabilities *mcp.ServerCapabilities, serverInfo *mcp.Implementation, options ...server.ServerOption) error {
+	t.httpServerMu.Lock()
+	t.httpServer = &http.Server{Addr: t.addr, Handler: t.Handler(capabilities, serverInfo, options...)}
+	httpServer := t.httpServer
+	t.httpServerMu.Unlock()
+	return httpServer.ListenAndServe()
+}
+
+func (t *HTTPTransport) Shutdown(ctx context.Context) error {
+	t.httpServerMu.Lock()
+	httpServer := t.httpServer
+	t.httpServerMu.Unlock()
+	if httpServer == nil {
+		return nil
+	}
+	return httpServer.Shutdown(ctx)
+}
+
+func (t *HTTPTransport) GetSessionManager() *session.SessionManager {
+	return t.sessions
+}
+
+// Handler serves MCP on the path of the transport, a server per session. A session is created
+// by an initialize request, is only served to the identity which created it and expires when
+// idle for longer than the idle timeout.
+func (t *HTTPTransport) Handler(capabilities *mcp.ServerCapabilities, serverInfo *mcp.Implementation, options ...server.ServerOption) http.Handler {
+	// the negotiated version is at least the one introducing streamable HTTP
+	options = append(options, server.MinimalProtocolVersionOption{Version: server.MINIMAL_FOR_STREAMABLE_HTTP})
+	sessions := &httpSessions{sessions: map[uuid.UUID]*httpSession{}}
+
+	mux := http.NewServeMux()
+	for pattern, handler := range t.routes {
+		mux.Handle(pattern, handler)
+	}
+	mux.Handle("POST "+t.path, t.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.expire(sessions)
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusInternalServerError)
+			return
+		}
+
+		var sess *httpSession
+		sessionID := uuid.New()
+		if header := r.Header.Get(sessionIDHeader); header != "" {
+			if sessionID, sess = t.resolve(w, r, sessions, header); sess == nil {
+				return
+			}
+		} else {
+			if !isInitialize(body) {
+				http.Error(w, "Mcp-Session-Id header is required, a session is created by an initialize request", http.StatusBadRequest)
+				return
+			}
+			sess = &httpSession{server: server.NewServer(capabilities, serverInfo, options...), owner: owner(r), lastUsed: t.now()}
+			sessions.add(sessionID, sess)
+		}
+		w.Header().Set(sessionIDHeader, sessionID.String())
+
+		ctx, _, err := t.sessions.ResolveSessionOrCreateNew(r.Context(), sessionID)
+		if err != nil {
+			http.Error(w, "Failed to resolve session", http.StatusNotFound)
+			return
+		}
+		writeResponses(w, sess.server, sess.server.HandleAndGetResponses(ctx, body))
+	})))
+
+	mux.Handle("DELETE "+t.path, t.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.expire(sessions)
+		header := r.Header.Get(sessionIDHeader)
+		if header == "" {
+			http.Error(w, "Mcp-Session-Id header is required", http.StatusBadRequest)
+			return
+		}
+		sessionID, sess := t.resolve(w, r, sessions, header)
+		if sess == nil {
+			return
+		}
+		sessions.remove(sessionID)
+		t.sessions.DeleteSession(sessionID)
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	return mux
+}
+
+// resolve returns the session of the header, nil after answering the error when it is unknown,
+// or when the request is not made by the identity which created it
+func (t *HTTPTransport) resolve(w http.ResponseWriter, r *http.Request, sessions *httpSessions, header string) (uuid.UUID, *httpSession) {
+	// an unknown session and a malformed id are both not found
+	sessionID, err := uuid.Parse(header)
+	if err != nil {
+		http.Error(w, "Wrong session id format, expected UUID", http.StatusNotFound)
+		return sessionID, nil
+	}
+	sess, ok := sessions.get(sessionID)
+	if !ok {
+		http.Error(w, "Requested session id not found in session store", http.StatusNotFound)
+		return sessionID, nil
+	}
+	if sess.owner != owner(r) {
+		http.Error(w, "Requested session belongs to another identity", http.StatusForbidden)
+		return sessionID, nil
+	}
+	sess.touch(t.now())
+	return sessionID, sess
+}
+
+// expire removes the sessions idle for longer than the idle timeout
+func (t *HTTPTransport) expire(sessions *httpSessions) {
+	for _, sessionID := range sessions.expire(t.now().Add(-t.idleTimeout)) {
+		t.sessions.DeleteSession(sessionID)
+	}
+}
+
+// owner is the name of the identity of the request, empty when the transport is not authenticated
+func owner(r *http.Request) string {
+	identity, _ := auth.FromContext(r.Context())
+	return identity.Name
+}
+
+// isInitialize tells if the body is an initialize request, which is never part of a batch
+func isInitialize(body []byte) bool {
+	var request struct {
+		Method string `json:"method"`
+	}
+	return json.Unmarshal(body, &request) == nil && request.Method == "initialize"
+}
+
+// httpSession is the server of a session, with the identity which created it
+type httpSession struct {
+	server server.Server
+	owner  string
+
+	mu       sync.Mutex
+	lastUsed time.Time
+}
+
+func (s *httpSession) touch(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastUsed = now
+}
+
+func (s *httpSession) usedSince(since time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.lastUsed.Before(since)
+}
+
+// httpSessions are the sessions of the transport by id
+type httpSessions struct {
+	mu       sync.Mutex
+	sessions map[uuid.UUID]*httpSession
+}
+
+func (s *httpSessions) add(sessionID uuid.UUID, sess *httpSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[sessionID] = sess
+}
+
+func (s *httpSessions) get(sessionID uuid.UUID) (*httpSession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[sessionID]
+	return sess, ok
+}
+
+func (s *httpSessions) remove(sessionID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, sessionID)
+}
+
+// expire removes the sessions unused since the time, returning their ids
+func (s *httpSessions) expire(since time.Time) []uuid.UUID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expired := []uuid.UUID{}
+	for sessionID, sess := range s.sessions {
+		if !sess.usedSince(since) {
+			delete(s.sessions, sessionID)
+			expired = append(expired, sessionID)
+		}
+	}
+	return expired
+}
+
+func (t *HTTPTransport) wrap(handler http.Handler) http.Handler {
+	for i := len(t.middleware) - 1; i >= 0; i-- {
+		handler = t.middleware[i](handler)
+	}
+	return handler
+}
+
+// writeResponses writes a single response as JSON and several as an event stream,
+// notifications have no response
+func writeResponses(w http.ResponseWriter, serv server.Server, responses []*jsonrpc2.JsonRpcResponse) {
+	written := 0
+	for _, response := range responses {
+		if response != nil {
+			responses[written] = response
+			written++
+		}
+	}
+	responses = responses[:written]
+
+	switch len(responses) {
+	case 0:
+		w.WriteHeader(http.StatusAccepted)
+	case 1:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(responses[0])
+	default:
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for _, response := range responses {
+			data, err := json.Marshal(response)
+			if err != nil {
+				data = marshalServerError(response, err)
+			}
+			if err := (&sse.Event{Data: data}).MarshalTo(w); err != nil {
+				serv.GetLogger().LogEvent(foxyevent.StreamingHTTPFailedMarshalEvent{Err: err})
+			}
+		}
+	}
+}
+
+func marshalServerError(response *jsonrpc2.JsonRpcResponse, err error) []byte {
+	id := response.Id
+	if id.IdIsMissing {
+		id = jsonrpc2.NewNullRequestId()
+	}
+	data, _ := jsonrpc2.Marshal(id, nil, jsonrpc2.NewServerError(-32000, err.Error()))
+	return data
+}
@@ -0,0 +1,283 @@
+// Package mcptool adds structured results to the foxy-contexts tools: tools
+// declare an outputSchema and return structuredContent next to their text content.
+package mcptool
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AlexisZankowitch/concept-insight/mcp/auth"
+	"github.com/AlexisZankowitch/concept-insight/mcp/logging"
+	"github.com/AlexisZankowitch/concept-insight/toolid"
+	"github.com/AlexisZankowitch/concept-insight/utils"
+	"github.com/strowk/foxy-contexts/pkg/fxctx"
+	"github.com/strowk/foxy-contexts/pkg/jsonrpc2"
+	"github.com/strowk/foxy-contexts/pkg/mcp"
+	"github.com/strowk/foxy-contexts/pkg/server"
+	"github.com/strowk/foxy-contexts/pkg/session"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// Tool is a tool with an output schema, returning structured content
+type Tool interface {
+	fxctx.Tool
+	OutputSchema() map[string]interface{}
+	CallStructured(ctx context.Context, args map[string]interface{}) *Result
+}
+
+// Result is a tool call result with its structured content
+type Result struct {
+	*mcp.CallToolResult
+	// StructuredContent matches the output schema of the tool, it is nil for errors
+	StructuredContent interface{} `json:"structuredContent,omitempty"`
+}
+
+// Counted is implemented by the structured content of the tools, to log how many results a call returned
+type Counted interface {
+	ResultCount() int
+}
+
+// Description is a tool as listed by tools/list
+type Description struct {
+	mcp.Tool
+	OutputSchema map[string]interface{} `json:"outputSchema,omitempty"`
+}
+
+type tool struct {
+	mcpTool      *mcp.Tool
+	outputSchema map[string]interface{}
+	callback     func(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, interface{})
+}
+
+// NewTool creates a tool whose callback returns its result and the structured content
+// described by the output schema. Errors are returned without structured content.
+func NewTool(
+	mcpTool *mcp.Tool,
+	outputSchema map[string]interface{},
+	callback func(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, interface{})) Tool {
+	return &tool{
+		mcpTool:      mcpTool,
+		outputSchema: outputSchema,
+		callback:     callback,
+	}
+}
+
+func (t *tool) GetMcpTool() *mcp.Tool {
+	return t.mcpTool
+}
+
+func (t *tool) OutputSchema() map[string]interface{} {
+	return t.outputSchema
+}
+
+func (t *tool) Callback(ctx context.Context, args map[string]interface{}) *mcp.CallToolResult {
+	return t.CallStructured(ctx, args).CallToolResult
+}
+
+func (t *tool) CallStructured(ctx context.Context, args map[string]interface{}) *Result {
+	result, structured := t.callback(ctx, args)
+	if result.IsError != nil && *result.IsError {
+		structured = nil
+	}
+	return &Result{CallToolResult: result, StructuredContent: structured}
+}
+
+// ToolMux serves tools/list and tools/call with the output schemas and structured content
+// of the tools, which the foxy-contexts tool mux drops. Tool calls are aborted past their
+// timeout or when the client sends notifications/cancelled.
+type ToolMux struct {
+	tools    map[string]fxctx.Tool
+	timeouts Timeouts
+	// scopes are the scopes the tokens need to call the tools
+	scopes auth.Scopes
+	logger *zap.Logger
+	// sessions scopes the request ids of the calls, nil when the transport has no sessions
+	sessions *session.SessionManager
+
+	callsMu sync.Mutex
+	// calls cancels the calls in progress by session and request id
+	calls map[string]func(reason string)
+}
+
+// NewToolMux creates a mux serving the tools
+func NewToolMux(tools []fxctx.Tool, timeouts Timeouts) *ToolMux {
+	m := &ToolMux{
+		tools:    map[string]fxctx.Tool{},
+		timeouts: timeouts,
+		logger:   zap.NewNop(),
+		calls:    map[string]func(reason string){},
+	}
+	for _, tool := range tools {
+		m.tools[tool.GetMcpTool().Name] = tool
+	}
+	return m
+}
+
+// ProvideToolMux replaces the foxy-contexts tool mux by a ToolMux of the same tools,
+// logging the calls with the logger of the app and checking the scopes of the app
+func ProvideToolMux(timeouts Timeouts) fx.Option {
+	return fx.Decorate(fx.Annotate(
+		func(_ fxctx.ToolMux, tools []fxctx.Tool, sessions *session.SessionManager, logger *zap.Logger, scopes auth.Scopes) fxctx.ToolMux {
+			m := NewToolMux(tools, timeouts)
+			m.sessions = sessions
+			m.scopes = scopes
+			if logger != nil {
+				m.logger = logger
+			}
+			return m
+		},
+		fx.ParamTags(``, `group:"tools"`, `optional:"true"`, `optional:"true"`, `optional:"true"`),
+	))
+}
+
+// GetMcpTools returns the tools without their output schema
+func (m *ToolMux) GetMcpTools() []mcp.Tool {
+	tools := []mcp.Tool{}
+	for _, description := range m.Descriptions() {
+		tools = append(tools, description.Tool)
+	}
+	return tools
+}
+
+// Descriptions returns the tools with their output schema, by name
+func (m *ToolMux) Descriptions() []Description {
+	descriptions := []Description{}
+	for _, tool := range m.tools {
+		description := Description{Tool: *tool.GetMcpTool()}
+		if structured, ok := tool.(Tool); ok {
+			description.OutputSchema = structured.OutputSchema()
+		}
+		descriptions = append(descriptions, description)
+	}
+	sort.Slice(descriptions, func(i, j int) bool {
+		return descriptions[i].Name < descriptions[j].Name
+	})
+	return descriptions
+}
+
+// CallToolNamed calls a tool, dropping its structured content
+func (m *ToolMux) CallToolNamed(ctx context.Context, name string, args map[string]interface{}) (*mcp.CallToolResult, error) {
+	result, err := m.CallStructured(ctx, name, args)
+	if err != nil {
+		return nil, err
+	}
+	return result.CallToolResult, nil
+}
+
+// CallStructured calls a tool, with its structured content when it has an output schema.
+// The call is aborted past the timeout of the tool. The call and the Slack requests it makes
+// are logged with the same correlation id, and the caller of authenticated requests. The
+// API keys and tokens without the scope of the tool are refused.
+func (m *ToolMux) CallStructured(ctx context.Context, name string, args map[string]interface{}) (*Result, error) {
+	tool, ok := m.tools[name]
+	if !ok {
+		return nil, fxctx.ErrToolNotFound
+	}
+	encodedArgs, _ := json.Marshal(args)
+	fields := []zap.Field{zap.String("tool", name), zap.String("args_hash", argsHash(encodedArgs))}
+	// the audit trail of the calls records who made them
+	identity, authenticated := auth.FromContext(ctx)
+	if authenticated {
+		fields = append(fields, zap.String("caller", identity.Name))
+	}
+	ctx, logger := logging.WithCorrelationID(ctx, m.logger.With(fields...))
+	// the overrides of the configuration are keyed by tool id
+	id := toolid.ForName(name)
+	if scope := m.scopes.For(id); authenticated && !identity.Allows(scope) {
+		logger.Warn("tool call forbidden", zap.String("scope", scope))
+		return &Result{CallToolResult: &mcp.CallToolResult{
+			IsError: utils.Ptr(true),
+			Content: []interface{}{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: calling %s requires the %s scope", name, scope),
+				},
+			},
+		}}, nil
+	}
+	start := time.Now()
+	if timeout := m.timeouts.For(id); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, fmt.Errorf("%s timed out after %s", name, timeout.Round(time.Second)))
+		defer cancel()
+	}
+
+	var result *Result
+	if structured, ok := tool.(Tool); ok {
+		result = structured.CallStructured(ctx, args)
+	} else {
+		result = &Result{CallToolResult: tool.Callback(ctx, args)}
+	}
+	// the error of an aborted call is the one of its last Slack request, tell why it was aborted
+	if ctx.Err() != nil && result.IsError != nil && *result.IsError {
+		result = &Result{CallToolResult: &mcp.CallToolResult{
+			IsError: result.IsError,
+			Content: []interface{}{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Error: %v", context.Cause(ctx)),
+				},
+			},
+		}}
+	}
+
+	fields = []zap.Field{zap.Duration("duration", time.Since(start))}
+	if counted, ok := result.StructuredContent.(Counted); ok {
+		fields = append(fields, zap.Int("result_count", counted.ResultCount()))
+	}
+	if result.IsError != nil && *result.IsError {
+		logger.Warn("tool call failed", append(fields, zap.String("error", resultText(result)))...)
+	} else {
+		logger.Info("tool call", fields...)
+	}
+	logger.Debug("tool call content", logging.Content("args", string(encodedArgs)), logging.Content("result", resultText(result)))
+	return result, nil
+}
+
+// argsHash identifies the arguments of a call without logging them
+func argsHash(encodedArgs []byte) string {
+	hash := sha256.Sum256(encodedArgs)
+	return hex.EncodeToString(hash[:6])
+}
+
+// resultText returns the text content of a result
+func resultText(result *Result) string {
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			return text.Text
+		}
+	}
+	return ""
+}
+
+// RegisterHandlers serves tools/list, tools/call and notifications/cancelled
+func (m *ToolMux) RegisterHandlers(s server.Server) {
+	s.SetRequestHandler(&mcp.ListToolsRequest{}, func(_ context.Context, _ jsonrpc2.Request) (jsonrpc2.Result, *jsonrpc2.Error) {
+		return map[string]interface{}{
+			"tools": m.Descriptions(),
+		}, nil
+	})
+
+	s.SetRequestHandler(&callToolRequest{}, func(ctx context.Context, r jsonrpc2.Request) (jsonrpc2.Result, *jsonrpc2.Error) {
+		req := r.(*callToolRequest)
+		ctx, done := m.track(ctx, req.id, req.Params.Name)
+		defer done()
+		result, err := m.CallStructured(ctx, req.Params.Name, req.Params.Arguments)
+		if err != nil {
+			return nil, jsonrpc2.NewServerError(fxctx.ToolNotFound, fmt.Sprintf("tool not found: %s", req.Params.Name))
+		}
+		return result, nil
+	})
+
+	s.SetNotificationHandler(&cancelledNotification{}, func(ctx context.Context, r jsonrpc2.Request) {
+		n := r.(*cancelledNotification)
+		m.cancel(ctx, requestKey(n.Params.RequestId), n.Params.Reason)
+	})
+}
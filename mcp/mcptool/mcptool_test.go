@@ -0,0 +1,434 @@
+package mcptool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexisZankowitch/concept-insight/mcp/auth"
+	"github.com/AlexisZankowitch/concept-insight/utils"
+	"github.com/strowk/foxy-contexts/pkg/fxctx"
+	"github.com/strowk/foxy-contexts/pkg/jsonrpc2"
+	"github.com/strowk/foxy-contexts/pkg/mcp"
+	"github.com/strowk/foxy-contexts/pkg/server"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type testPage struct {
+	Next_Cursor string `json:"next_cursor,omitempty" description:"next page"`
+}
+
+type testItem struct {
+	Name    string          `json:"name"`
+	Count   int             `json:"count"`
+	Score   float64         `json:"score,omitempty"`
+	Seen    time.Time       `json:"seen"`
+	Tags    []string        `json:"tags"`
+	Labels  map[string]bool `json:"labels,omitempty"`
+	Ignored string          `json:"-"`
+	hidden  string
+}
+
+type testOutput struct {
+	Items []testItem `json:"items"`
+	testPage
+}
+
+func (o testOutput) ResultCount() int {
+	return len(o.Items)
+}
+
+func Test_SchemaFor(t *testing.T) {
+	schema := SchemaFor(testOutput{})
+	if schema["type"] != "object" || !reflect.DeepEqual(schema["required"], []string{"items"}) {
+		t.Fatalf("unexpected schema %+v", schema)
+	}
+	properties := schema["properties"].(map[string]interface{})
+	cursor := properties["next_cursor"].(map[string]interface{})
+	if cursor["type"] != "string" || cursor["description"] != "next page" {
+		t.Fatalf("expected the embedded field inlined, got %+v", properties)
+	}
+
+	items := properties["items"].(map[string]interface{})["items"].(map[string]interface{})
+	if !reflect.DeepEqual(items["required"], []string{"name", "count", "seen", "tags"}) {
+		t.Fatalf("unexpected required fields %+v", items["required"])
+	}
+	fields := items["properties"].(map[string]interface{})
+	if len(fields) != 6 {
+		t.Fatalf("expected the ignored and hidden fields dropped, got %+v", fields)
+	}
+	for name, expected := range map[string]string{"name": "string", "count": "integer", "score": "number", "seen": "string", "tags": "array", "labels": "object"} {
+		if got := fields[name].(map[string]interface{})["type"]; got != expected {
+			t.Errorf("expected %s to be a %s, got %v", name, expected, got)
+		}
+	}
+	if fields["seen"].(map[string]interface{})["format"] != "date-time" {
+		t.Fatalf("expected times to be date-time strings, got %+v", fields["seen"])
+	}
+}
+
+func newTestTool() Tool {
+	return NewTool(
+		&mcp.Tool{
+			Name:        "echo",
+			Description: utils.Ptr("Echo the name"),
+			InputSchema: mcp.ToolInputSchema{Type: "object"},
+		},
+		SchemaFor(testOutput{}),
+		func(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, interface{}) {
+			name, _ := args["name"].(string)
+			output := testOutput{Items: []testItem{{Name: name, Tags: []string{}}}}
+			return &mcp.CallToolResult{
+				IsError: utils.Ptr(name == ""),
+				Content: []interface{}{
+					mcp.TextContent{
+						Type: "text",
+						Text: "echo " + name,
+					},
+				},
+			}, output
+		},
+	)
+}
+
+func Test_ToolMuxCall(t *testing.T) {
+	mux := NewToolMux([]fxctx.Tool{newTestTool()}, Timeouts{})
+
+	result, err := mux.CallStructured(context.Background(), "echo", map[string]interface{}{"name": "go"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, expected := range []string{`"content":[{"text":"echo go","type":"text"}]`, `"structuredContent":{"items":[{"name":"go"`} {
+		if !strings.Contains(string(encoded), expected) {
+			t.Errorf("expected %s in %s", expected, encoded)
+		}
+	}
+
+	// errors come without structured content
+	result, _ = mux.CallStructured(context.Background(), "echo", map[string]interface{}{})
+	if !*result.IsError || result.StructuredContent != nil {
+		t.Fatalf("unexpected error result %+v", result)
+	}
+
+	if _, err := mux.CallToolNamed(context.Background(), "missing", nil); err != fxctx.ErrToolNotFound {
+		t.Fatalf("expected tool not found, got %v", err)
+	}
+}
+
+func Test_ToolMuxLogging(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	mux := NewToolMux([]fxctx.Tool{newTestTool()}, Timeouts{})
+	mux.logger = zap.New(core)
+
+	mux.CallStructured(context.Background(), "echo", map[string]interface{}{"name": "go"})
+	mux.CallStructured(context.Background(), "echo", map[string]interface{}{"name": "go"})
+	mux.CallStructured(context.Background(), "echo", map[string]interface{}{})
+
+	calls := logs.FilterMessage("tool call").All()
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 successful calls logged, got %d", len(calls))
+	}
+	first, second := calls[0].ContextMap(), calls[1].ContextMap()
+	if first["tool"] != "echo" || first["result_count"] != int64(1) || first["duration"] == nil {
+		t.Fatalf("unexpected fields %+v", first)
+	}
+	// the same arguments hash the same, each call has its own correlation id
+	if first["args_hash"] != second["args_hash"] || first["correlation_id"] == second["correlation_id"] {
+		t.Fatalf("unexpected hash or correlation id %+v %+v", first, second)
+	}
+	failed := logs.FilterMessage("tool call failed").All()
+	if len(failed) != 1 || failed[0].ContextMap()["error"] != "echo " {
+		t.Fatalf("expected the failed call logged, got %+v", failed)
+	}
+	if content := logs.FilterMessage("tool call content").All(); len(content) != 3 || content[0].ContextMap()["args"] != `{"name":"go"}` {
+		t.Fatalf("expected the content logged at debug level, got %+v", content)
+	}
+}
+
+func Test_ToolMuxDescriptions(t *testing.T) {
+	plain := fxctx.NewTool(&mcp.Tool{Name: "plain", InputSchema: mcp.ToolInputSchema{Type: "object"}}, func(ctx context.Context, args map[string]interface{}) *mcp.CallToolResult {
+		return &mcp.CallToolResult{Content: []interface{}{}}
+	})
+	mux := NewToolMux([]fxctx.Tool{plain, newTestTool()}, Timeouts{})
+
+	descriptions := mux.Descriptions()
+	if len(descriptions) != 2 || descriptions[0].Name != "echo" || descriptions[1].Name != "plain" {
+		t.Fatalf("unexpected descriptions %+v", descriptions)
+	}
+	encoded, _ := json.Marshal(descriptions)
+	if !strings.Contains(string(encoded), `"name":"echo","outputSchema":{"properties"`) {
+		t.Fatalf("expected the output schema of echo, got %s", encoded)
+	}
+	if strings.Count(string(encoded), "outputSchema") != 1 {
+		t.Fatalf("expected no output schema for plain, got %s", encoded)
+	}
+}
+
+func Test_ProvideToolMux(t *testing.T) {
+	var mux fxctx.ToolMux
+	app := fx.New(
+		fx.NopLogger,
+		fx.Provide(fxctx.AsTool(func() fxctx.Tool { return newTestTool() })),
+		fxctx.ProvideToolMux(),
+		ProvideToolMux(Timeouts{}),
+		fx.Populate(&mux),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, ok := mux.(*ToolMux); !ok {
+		t.Fatalf("expected the structured tool mux, got %T", mux)
+	}
+	if tools := mux.GetMcpTools(); len(tools) != 1 || tools[0].Name != "echo" {
+		t.Fatalf("unexpected tools %+v", tools)
+	}
+}
+
+// newBlockingTool returns a tool waiting for its context to be done, started is closed once it runs
+func newBlockingTool(started chan struct{}) Tool {
+	return NewTool(
+		&mcp.Tool{Name: "block", InputSchema: mcp.ToolInputSchema{Type: "object"}},
+		SchemaFor(testOutput{}),
+		func(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, interface{}) {
+			close(started)
+			<-ctx.Done()
+			return &mcp.CallToolResult{
+				IsError: utils.Ptr(true),
+				Content: []interface{}{mcp.TextContent{Type: "text", Text: "Error: " + ctx.Err().Error()}},
+			}, nil
+		},
+	)
+}
+
+func Test_ToolMuxTimeout(t *testing.T) {
+	mux := NewToolMux([]fxctx.Tool{newBlockingTool(make(chan struct{})), newTestTool()}, Timeouts{
+		Default: time.Hour,
+		Tools:   map[string]time.Duration{"block": 10 * time.Millisecond},
+	})
+	if mux.timeouts.For("echo") != time.Hour {
+		t.Fatalf("expected the default timeout for echo")
+	}
+
+	result, err := mux.CallStructured(context.Background(), "block", nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !*result.IsError || text != "Error: block timed out after 0s" {
+		t.Fatalf("expected a timeout error, got %+v", result.Content)
+	}
+}
+
+func Test_ToolMuxCancelled(t *testing.T) {
+	started := make(chan struct{})
+	mux := NewToolMux([]fxctx.Tool{newBlockingTool(started)}, Timeouts{})
+	s := server.NewServer(&mcp.ServerCapabilities{}, &mcp.Implementation{Name: "test", Version: "0"})
+	mux.RegisterHandlers(s)
+
+	responses := make(chan []*jsonrpc2.JsonRpcResponse)
+	go func() {
+		responses <- s.HandleAndGetResponses(context.Background(), []byte(`{"jsonrpc":"2.0","id":"call-1","method":"tools/call","params":{"name":"block"}}`))
+	}()
+	<-started
+
+	// another id does not cancel the call
+	s.HandleAndGetResponses(context.Background(), []byte(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":1}}`))
+	if mux.cancel(context.Background(), `"call-2"`, "") {
+		t.Fatalf("expected no call with the id call-2")
+	}
+	s.HandleAndGetResponses(context.Background(), []byte(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":"call-1","reason":"user stopped"}}`))
+
+	select {
+	case response := <-responses:
+		encoded, _ := json.Marshal(response[0].Result)
+		if !strings.Contains(string(encoded), "Error: block was cancelled by the client: user stopped") {
+			t.Fatalf("expected a cancelled result, got %s", encoded)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the call to be cancelled")
+	}
+	if len(mux.calls) != 0 {
+		t.Fatalf("expected the call to be forgotten once answered, got %d", len(mux.calls))
+	}
+}
+
+func Test_StdioTransportCancelled(t *testing.T) {
+	started := make(chan struct{})
+	mux := NewToolMux([]fxctx.Tool{newBlockingTool(started)}, Timeouts{})
+	in, input := io.Pipe()
+	var out bytes.Buffer
+	transport := NewStdioTransport(in, &out)
+
+	done := make(chan error)
+	go func() {
+		done <- transport.Run(&mcp.ServerCapabilities{}, &mcp.Implementation{Name: "test", Version: "0"}, server.ServerStartCallbackOption{Callback: mux.RegisterHandlers})
+	}()
+	fmt.Fprintln(input, `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"block"}}`)
+	<-started
+	// the call is still running, the notification is read anyway
+	fmt.Fprintln(input, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":7}}`)
+	input.Close()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the call to be cancelled")
+	}
+	// the response is written before the transport stops
+	if !strings.Contains(out.String(), `"id":7`) || !strings.Contains(out.String(), "block was cancelled by the client") {
+		t.Fatalf("expected a cancelled result, got %s", out.String())
+	}
+}
+
+const initializeRequest = `{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"0"}}}`
+
+const echoRequest = `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"name":"go"}}}`
+
+func newTestHTTPTransport(t *testing.T, mux *ToolMux, keys map[string]string) (*HTTPTransport, *httptest.Server) {
+	hashes := map[string]string{}
+	for name, key := range keys {
+		hashes[name] = auth.HashAPIKey(key)
+	}
+	authenticator, _ := auth.NewAPIKeys(hashes, nil, "")
+	transport := NewHTTPTransport("", "/mcp", auth.Middleware(authenticator, `Bearer realm="test"`, zap.NewNop()))
+	httpServer := httptest.NewServer(transport.Handler(&mcp.ServerCapabilities{}, &mcp.Implementation{Name: "test", Version: "0"}, server.ServerStartCallbackOption{Callback: mux.RegisterHandlers}))
+	t.Cleanup(httpServer.Close)
+	return transport, httpServer
+}
+
+// sendHTTP sends the body to the MCP endpoint with the key and the session, when not empty
+func sendHTTP(t *testing.T, httpServer *httptest.Server, method string, key string, sessionID string, body string) (*http.Response, string) {
+	request, _ := http.NewRequest(method, httpServer.URL+"/mcp", strings.NewReader(body))
+	if key != "" {
+		request.Header.Set("Authorization", "Bearer "+key)
+	}
+	if sessionID != "" {
+		request.Header.Set("Mcp-Session-Id", sessionID)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	content, _ := io.ReadAll(response.Body)
+	return response, string(content)
+}
+
+func Test_HTTPTransportAuthenticated(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	mux := NewToolMux([]fxctx.Tool{newTestTool()}, Timeouts{})
+	mux.logger = zap.New(core)
+	_, httpServer := newTestHTTPTransport(t, mux, map[string]string{"n8n": "n8n-secret"})
+
+	if response, _ := sendHTTP(t, httpServer, http.MethodPost, "", "", echoRequest); response.StatusCode != http.StatusUnauthorized || response.Header.Get("WWW-Authenticate") != `Bearer realm="test"` {
+		t.Fatalf("expected 401 with a challenge, got %d %v", response.StatusCode, response.Header)
+	}
+	if len(logs.All()) != 0 {
+		t.Fatalf("expected no call without key, got %+v", logs.All())
+	}
+
+	response, _ := sendHTTP(t, httpServer, http.MethodPost, "n8n-secret", "", initializeRequest)
+	sessionID := response.Header.Get("Mcp-Session-Id")
+	if response.StatusCode != http.StatusOK || sessionID == "" {
+		t.Fatalf("expected a session, got %d", response.StatusCode)
+	}
+	response, body := sendHTTP(t, httpServer, http.MethodPost, "n8n-secret", sessionID, echoRequest)
+	if response.StatusCode != http.StatusOK || !strings.Contains(body, `"structuredContent"`) {
+		t.Fatalf("expected the call answered in the session, got %d %s", response.StatusCode, body)
+	}
+	// the audit log records the key the call was made with
+	if calls := logs.FilterMessage("tool call").All(); len(calls) != 1 || calls[0].ContextMap()["caller"] != "n8n" {
+		t.Fatalf("expected the call logged with its caller, got %+v", logs.All())
+	}
+}
+
+func Test_HTTPTransportSessions(t *testing.T) {
+	mux := NewToolMux([]fxctx.Tool{newTestTool()}, Timeouts{})
+	transport, httpServer := newTestHTTPTransport(t, mux, map[string]string{"n8n": "n8n-secret", "ci": "ci-secret"})
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	transport.now = func() time.Time { return now }
+
+	// only an initialize request creates a session
+	if response, _ := sendHTTP(t, httpServer, http.MethodPost, "n8n-secret", "", echoRequest); response.StatusCode != http.StatusBadRequest || response.Header.Get("Mcp-Session-Id") != "" {
+		t.Fatalf("expected 400 without session, got %d", response.StatusCode)
+	}
+	response, _ := sendHTTP(t, httpServer, http.MethodPost, "n8n-secret", "", initializeRequest)
+	sessionID := response.Header.Get("Mcp-Session-Id")
+
+	// the session is only served to the identity which created it
+	for _, method := range []string{http.MethodPost, http.MethodDelete} {
+		if response, _ := sendHTTP(t, httpServer, method, "ci-secret", sessionID, echoRequest); response.StatusCode != http.StatusForbidden {
+			t.Fatalf("expected 403 for %s by another key, got %d", method, response.StatusCode)
+		}
+	}
+
+	// a request keeps the session alive
+	now = now.Add(DefaultSessionIdleTimeout - time.Minute)
+	if response, _ := sendHTTP(t, httpServer, http.MethodPost, "n8n-secret", sessionID, echoRequest); response.StatusCode != http.StatusOK {
+		t.Fatalf("expected the session kept, got %d", response.StatusCode)
+	}
+	now = now.Add(DefaultSessionIdleTimeout - time.Minute)
+	if response, _ := sendHTTP(t, httpServer, http.MethodPost, "n8n-secret", sessionID, echoRequest); response.StatusCode != http.StatusOK {
+		t.Fatalf("expected the session kept, got %d", response.StatusCode)
+	}
+	now = now.Add(DefaultSessionIdleTimeout + time.Minute)
+	if response, _ := sendHTTP(t, httpServer, http.MethodPost, "n8n-secret", sessionID, echoRequest); response.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected the idle session expired, got %d", response.StatusCode)
+	}
+
+	response, _ = sendHTTP(t, httpServer, http.MethodPost, "n8n-secret", "", initializeRequest)
+	sessionID = response.Header.Get("Mcp-Session-Id")
+	if response, _ := sendHTTP(t, httpServer, http.MethodDelete, "n8n-secret", sessionID, ""); response.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected the session deleted, got %d", response.StatusCode)
+	}
+	if response, _ := sendHTTP(t, httpServer, http.MethodPost, "n8n-secret", sessionID, echoRequest); response.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected the deleted session not found, got %d", response.StatusCode)
+	}
+}
+
+func Test_ToolMuxScopes(t *testing.T) {
+	mux := NewToolMux([]fxctx.Tool{newTestTool()}, Timeouts{})
+	mux.scopes = auth.Scopes{Default: "slack:read", Tools: map[string]string{"other": "slack:admin"}}
+	args := map[string]interface{}{"name": "go"}
+
+	for name, test := range map[string]struct {
+		identity *auth.Identity
+		allowed  bool
+	}{
+		"stdio":            {nil, true},
+		"api key":          {&auth.Identity{Name: "n8n", Scopes: []string{"slack:read"}}, true},
+		"api key without":  {&auth.Identity{Name: "n8n", Scopes: []string{"slack:users"}}, false},
+		"token with scope": {&auth.Identity{Name: "alice", Scopes: []string{"slack:read"}}, true},
+		"token without":    {&auth.Identity{Name: "alice", Scopes: []string{}}, false},
+	} {
+		ctx := context.Background()
+		if test.identity != nil {
+			ctx = auth.WithIdentity(ctx, *test.identity)
+		}
+		result, _ := mux.CallStructured(ctx, "echo", args)
+		if allowed := !*result.IsError; allowed != test.allowed {
+			t.Errorf("%s: expected allowed %v, got %+v", name, test.allowed, result.Content)
+		}
+		if !test.allowed && result.Content[0].(mcp.TextContent).Text != "Error: calling echo requires the slack:read scope" {
+			t.Errorf("%s: unexpected error %+v", name, result.Content)
+		}
+	}
+}
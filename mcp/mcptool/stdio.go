@@ -0,0 +1,58 @@
+package mcptool
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/strowk/foxy-contexts/pkg/mcp"
+	"github.com/strowk/foxy-contexts/pkg/server"
+	"github.com/strowk/foxy-contexts/pkg/stdio"
+)
+
+// NewStdioTransport is the foxy-contexts stdio transport, handling the messages concurrently.
+// The foxy-contexts transport handles a message after the other, so a long tool call would
+// hold the other calls and the notifications/cancelled meant to abort it.
+func NewStdioTransport(in io.Reader, out io.Writer) server.Transport {
+	handling := &sync.WaitGroup{}
+	return stdio.NewTransport(
+		stdio.WithIn(&drainingReader{Reader: in, handling: handling}),
+		stdio.WithOut(out),
+		stdio.WithNewServerFunc(func(capabilities *mcp.ServerCapabilities, serverInfo *mcp.Implementation, options ...server.ServerOption) server.Server {
+			return &concurrentServer{Server: server.NewServer(capabilities, serverInfo, options...), handling: handling}
+		}),
+	)
+}
+
+// concurrentServer handles each message in its own goroutine
+type concurrentServer struct {
+	server.Server
+	handling *sync.WaitGroup
+}
+
+func (s *concurrentServer) Handle(ctx context.Context, b []byte) {
+	s.handling.Add(1)
+	go func() {
+		defer s.handling.Done()
+		s.Server.Handle(ctx, b)
+	}()
+}
+
+// drainingReader holds the end of the input until the messages read are answered,
+// the transport stops writing responses once the input is done
+type drainingReader struct {
+	io.Reader
+	handling *sync.WaitGroup
+}
+
+func (r *drainingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err == io.EOF && n > 0 {
+		// the messages of this read are handled before the end of the input is read again
+		return n, nil
+	}
+	if err == io.EOF {
+		r.handling.Wait()
+	}
+	return n, err
+}
@@ -0,0 +1,220 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// Channel is a Slack channel resolved from its name
+type Channel struct {
+	ID   string
+	Name string
+}
+
+// ChannelConfig lists channels by name, before they are resolved
+type ChannelConfig struct {
+	// Default channels searched by every tool without override
+	Default []string
+	// Allowed channels a caller can ask for with the channels argument
+	Allowed []string
+	// Tools overrides the default channels per tool id
+	Tools map[string][]string
+}
+
+// ChannelSet holds the resolved channels searched by the tools
+type ChannelSet struct {
+	defaults []Channel
+	allowed  map[string]Channel
+	tools    map[string][]Channel
+}
+
+// ResolveChannels resolves every configured channel name to its id.
+// It fails listing every unknown channel, so typos are caught at startup.
+func (s *SlackService) ResolveChannels(ctx context.Context, config ChannelConfig) (*ChannelSet, error) {
+	known, err := s.listChannels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list channels: %w", err)
+	}
+
+	unknown := map[string]bool{}
+	resolve := func(names []string) []Channel {
+		channels := []Channel{}
+		for _, name := range names {
+			channel, ok := known[normalizeChannelName(name)]
+			if !ok {
+				unknown[name] = true
+				continue
+			}
+			channels = append(channels, channel)
+		}
+		return channels
+	}
+
+	set := &ChannelSet{
+		defaults: resolve(config.Default),
+		allowed:  map[string]Channel{},
+		tools:    map[string][]Channel{},
+	}
+	for tool, names := range config.Tools {
+		set.tools[tool] = resolve(names)
+	}
+	allowed := append([]Channel{}, set.defaults...)
+	for _, channels := range set.tools {
+		allowed = append(allowed, channels...)
+	}
+	allowed = append(allowed, resolve(config.Allowed)...)
+	for _, channel := range allowed {
+		set.allowed[normalizeChannelName(channel.Name)] = channel
+	}
+
+	if len(unknown) > 0 {
+		names := []string{}
+		for name := range unknown {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown channels: %s", strings.Join(names, ", "))
+	}
+	if len(set.defaults) == 0 {
+		return nil, fmt.Errorf("at least one default channel is required")
+	}
+
+	return set, nil
+}
+
+// listChannels returns the public and private channels visible to the token, by name
+func (s *SlackService) listChannels(ctx context.Context) (map[string]Channel, error) {
+	channels := map[string]Channel{}
+	params := &slack.GetConversationsParameters{
+		Types:           []string{"public_channel", "private_channel"},
+		ExcludeArchived: true,
+		Limit:           200,
+	}
+
+	for {
+		var page []slack.Channel
+		var next string
+		err := s.limiter.Do(ctx, "conversations.list", func() error {
+			var err error
+			page, next, err = s.client.GetConversationsContext(ctx, params)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, channel := range page {
+			channels[normalizeChannelName(channel.Name)] = Channel{ID: channel.ID, Name: channel.Name}
+		}
+		if next == "" {
+			return channels, nil
+		}
+		params.Cursor = next
+	}
+}
+
+// ForTool returns the channels searched by a tool
+func (c *ChannelSet) ForTool(tool string) []Channel {
+	if channels, ok := c.tools[tool]; ok && len(channels) > 0 {
+		return channels
+	}
+	return c.defaults
+}
+
+// Select returns the requested channels, or the tool channels when none is requested.
+// Requested channels must be in the allowlist.
+func (c *ChannelSet) Select(tool string, requested []string) ([]Channel, error) {
+	if len(requested) == 0 {
+		return c.ForTool(tool), nil
+	}
+
+	channels := []Channel{}
+	for _, name := range requested {
+		channel, ok := c.allowed[normalizeChannelName(name)]
+		if !ok {
+			return nil, fmt.Errorf("channel %q is not allowed, allowed channels are: %s", name, strings.Join(c.AllowedNames(), ", "))
+		}
+		channels = append(channels, channel)
+	}
+	return channels, nil
+}
+
+// Lookup returns the allowed channel with the given name or id
+func (c *ChannelSet) Lookup(nameOrID string) (Channel, error) {
+	if channel, ok := c.allowed[normalizeChannelName(nameOrID)]; ok {
+		return channel, nil
+	}
+	for _, channel := range c.allowed {
+		if channel.ID == strings.TrimSpace(nameOrID) {
+			return channel, nil
+		}
+	}
+	return Channel{}, fmt.Errorf("channel %q is not allowed, allowed channels are: %s", nameOrID, strings.Join(c.AllowedNames(), ", "))
+}
+
+// All returns every allowed channel, by name
+func (c *ChannelSet) All() []Channel {
+	channels := []Channel{}
+	for _, name := range c.AllowedNames() {
+		channels = append(channels, c.allowed[name])
+	}
+	return channels
+}
+
+// AllowedNames returns the names of the channels a caller can ask for
+func (c *ChannelSet) AllowedNames() []string {
+	names := []string{}
+	for name := range c.allowed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func normalizeChannelName(name string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+}
+
+// channelNames returns the names of the channels
+func channelNames(channels []Channel) []string {
+	names := []string{}
+	for _, channel := range channels {
+		names = append(names, channel.Name)
+	}
+	return names
+}
+
+// channelsArg extracts the optional channels tool argument
+func channelsArg(args map[string]interface{}) ([]string, error) {
+	value, ok := args["channels"]
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("'channels' must be an array of channel names")
+	}
+	channels := []string{}
+	for _, v := range values {
+		channel, ok := v.(string)
+		if !ok || channel == "" {
+			return nil, fmt.Errorf("'channels' must be an array of channel names")
+		}
+		channels = append(channels, channel)
+	}
+	return channels, nil
+}
+
+// channelsInputProperty describes the channels tool argument
+func channelsInputProperty(channels *ChannelSet) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "array",
+		"items":       map[string]interface{}{"type": "string"},
+		"description": fmt.Sprintf("Channels to search instead of the default ones, among: %s", strings.Join(channels.AllowedNames(), ", ")),
+	}
+}
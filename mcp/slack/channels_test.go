@@ -0,0 +1,61 @@
+package slack
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/AlexisZankowitch/concept-insight/toolid"
+)
+
+func Test_ResolveChannels(t *testing.T) {
+	s, _ := newTestService(t, loadFixtures(t))
+
+	channels, err := s.ResolveChannels(context.Background(), ChannelConfig{
+		Default: []string{"#Concept-Tech", "today-i-learned"},
+		Allowed: []string{"random"},
+		Tools:   map[string][]string{toolid.LatestPostsByUser: {"today-i-learned"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	defaults := channels.ForTool(toolid.FindTechnologyPosts)
+	if len(defaults) != 2 || defaults[0].ID != "C01CONCEPT" || defaults[0].Name != "concept-tech" {
+		t.Fatalf("unexpected default channels %+v", defaults)
+	}
+	override := channels.ForTool(toolid.LatestPostsByUser)
+	if len(override) != 1 || override[0].ID != "C02TIL0001" {
+		t.Fatalf("unexpected tool channels %+v", override)
+	}
+
+	selected, err := channels.Select(toolid.FindTechnologyPosts, []string{"random"})
+	if err != nil || len(selected) != 1 || selected[0].ID != "C03RANDOM1" {
+		t.Fatalf("expected the allowed channel, got %+v err %v", selected, err)
+	}
+}
+
+func Test_ResolveChannelsUnknown(t *testing.T) {
+	s, _ := newTestService(t, loadFixtures(t))
+
+	_, err := s.ResolveChannels(context.Background(), ChannelConfig{
+		Default: []string{"concept-tech", "concpet-tech"},
+		Allowed: []string{"hr-private"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "concpet-tech, hr-private") {
+		t.Fatalf("expected every unknown channel to be reported, got %v", err)
+	}
+}
+
+func Test_SelectChannelNotAllowed(t *testing.T) {
+	s, _ := newTestService(t, loadFixtures(t))
+	tool := NewFindTechnologyPost(s, newTestChannels(t, s))
+
+	result := tool.Callback(context.Background(), map[string]interface{}{
+		"technology": "golang",
+		"channels":   []interface{}{"random"},
+	})
+	if !*result.IsError {
+		t.Fatalf("expected channels outside the allowlist to be rejected, got %+v", result)
+	}
+}
@@ -0,0 +1,170 @@
+package slack
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultDirectoryTTL is how long the user list is served from cache before being reloaded
+const defaultDirectoryTTL = time.Hour
+
+// userIndex is an immutable snapshot of the workspace users
+type userIndex struct {
+	users    []ConceptUser
+	byID     map[string]int
+	byName   map[string][]int
+	loadedAt time.Time
+}
+
+func newUserIndex(users []ConceptUser, loadedAt time.Time) *userIndex {
+	index := &userIndex{
+		users:    users,
+		byID:     map[string]int{},
+		byName:   map[string][]int{},
+		loadedAt: loadedAt,
+	}
+
+	for i, user := range users {
+		index.byID[user.Slack_id] = i
+		// a user can have the same handle, real name and display name
+		seen := map[string]bool{}
+		for _, name := range []string{user.Slack_Name, user.Real_Name, user.Display_Name} {
+			key := strings.ToLower(strings.TrimSpace(name))
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			index.byName[key] = append(index.byName[key], i)
+		}
+	}
+
+	return index
+}
+
+// UserDirectory caches the workspace users, so resolving a user does not
+// pull the whole directory from Slack every time.
+// It is safe for concurrent use, readers keep using the current snapshot
+// while it is being refreshed.
+type UserDirectory struct {
+	load func(ctx context.Context) ([]ConceptUser, error)
+	ttl  time.Duration
+	now  func() time.Time
+
+	index     atomic.Pointer[userIndex]
+	refreshMu sync.Mutex
+}
+
+// NewUserDirectory creates a directory loading users with load, and reloading them once older than ttl
+func NewUserDirectory(load func(ctx context.Context) ([]ConceptUser, error), ttl time.Duration) *UserDirectory {
+	return &UserDirectory{
+		load: load,
+		ttl:  ttl,
+		now:  time.Now,
+	}
+}
+
+// Refresh reloads the users from Slack
+func (d *UserDirectory) Refresh(ctx context.Context) error {
+	d.refreshMu.Lock()
+	defer d.refreshMu.Unlock()
+	return d.refresh(ctx)
+}
+
+func (d *UserDirectory) refresh(ctx context.Context) error {
+	users, err := d.load(ctx)
+	if err != nil {
+		return err
+	}
+	d.index.Store(newUserIndex(users, d.now()))
+	return nil
+}
+
+// current returns the user snapshot, loading it first when missing or expired
+func (d *UserDirectory) current(ctx context.Context) (*userIndex, error) {
+	if index := d.index.Load(); index != nil && !d.expired(index) {
+		return index, nil
+	}
+
+	d.refreshMu.Lock()
+	defer d.refreshMu.Unlock()
+
+	// another caller may have refreshed while we were waiting
+	if index := d.index.Load(); index != nil && !d.expired(index) {
+		return index, nil
+	}
+	if err := d.refresh(ctx); err != nil {
+		// serve stale users rather than failing when Slack is unavailable
+		if index := d.index.Load(); index != nil {
+			return index, nil
+		}
+		return nil, err
+	}
+	return d.index.Load(), nil
+}
+
+func (d *UserDirectory) expired(index *userIndex) bool {
+	return d.now().Sub(index.loadedAt) >= d.ttl
+}
+
+// Users returns every active user of the workspace
+func (d *UserDirectory) Users(ctx context.Context) ([]ConceptUser, error) {
+	index, err := d.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return index.users, nil
+}
+
+// Get returns the user with the given Slack id
+func (d *UserDirectory) Get(ctx context.Context, slackId string) (ConceptUser, bool, error) {
+	index, err := d.current(ctx)
+	if err != nil {
+		return ConceptUser{}, false, err
+	}
+	i, ok := index.byID[strings.ToUpper(strings.TrimSpace(slackId))]
+	if !ok {
+		return ConceptUser{}, false, nil
+	}
+	return index.users[i], true, nil
+}
+
+// Search returns the users whose id, handle, real name or display name match the search.
+// Exact matches come first, followed by the users containing the search.
+func (d *UserDirectory) Search(ctx context.Context, search string) ([]ConceptUser, error) {
+	index, err := d.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	searchLower := strings.ToLower(strings.TrimSpace(search))
+	matches := []ConceptUser{}
+	found := map[int]bool{}
+
+	if i, ok := index.byID[strings.ToUpper(searchLower)]; ok {
+		matches = append(matches, index.users[i])
+		found[i] = true
+	}
+	for _, i := range index.byName[searchLower] {
+		if !found[i] {
+			matches = append(matches, index.users[i])
+			found[i] = true
+		}
+	}
+
+	for i, u := range index.users {
+		if found[i] {
+			continue
+		}
+		if strings.Contains(strings.ToLower(u.Slack_id), searchLower) ||
+			strings.Contains(strings.ToLower(u.Slack_Name), searchLower) ||
+			strings.Contains(strings.ToLower(u.Real_Name), searchLower) ||
+			strings.Contains(strings.ToLower(u.Display_Name), searchLower) {
+			matches = append(matches, u)
+		}
+	}
+
+	return matches, nil
+}
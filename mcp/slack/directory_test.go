@@ -0,0 +1,100 @@
+package slack
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_UserDirectoryCache(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+
+	for i := 0; i < 3; i++ {
+		user, ok, err := s.Users().Get(context.Background(), "U02MARIE01")
+		if err != nil || !ok {
+			t.Fatalf("expected to find the user, got ok %v err %v", ok, err)
+		}
+		if user.Display_Name != "marie" {
+			t.Fatalf("unexpected user %+v", user)
+		}
+	}
+
+	if calls := server.Calls("users.list"); calls != 1 {
+		t.Fatalf("expected the users to be loaded once, got %d calls", calls)
+	}
+
+	if _, ok, _ := s.Users().Get(context.Background(), "U04GONE001"); ok {
+		t.Fatalf("deleted users must not be in the directory")
+	}
+}
+
+func Test_UserDirectoryRefresh(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	now := time.Now()
+	s.Users().now = func() time.Time { return now }
+
+	if _, err := s.Users().Users(context.Background()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// expired entries are reloaded
+	now = now.Add(defaultDirectoryTTL)
+	if _, err := s.Users().Users(context.Background()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if calls := server.Calls("users.list"); calls != 2 {
+		t.Fatalf("expected a reload after the TTL, got %d calls", calls)
+	}
+
+	// and can be refreshed on demand
+	if err := s.Users().Refresh(context.Background()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if calls := server.Calls("users.list"); calls != 3 {
+		t.Fatalf("expected a reload on refresh, got %d calls", calls)
+	}
+}
+
+func Test_UserDirectorySearch(t *testing.T) {
+	s, _ := newTestService(t, loadFixtures(t))
+
+	// exact display name match comes before partial matches
+	users, err := s.Users().Search(context.Background(), "Linus")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(users) != 1 || users[0].Slack_id != "U03LINUS01" {
+		t.Fatalf("unexpected users %+v", users)
+	}
+
+	users, err = s.Users().Search(context.Background(), "u7d3q7n8y")
+	if err != nil || len(users) != 1 || users[0].Slack_Name != "alexis" {
+		t.Fatalf("expected to find the user by id, got %+v err %v", users, err)
+	}
+
+	users, err = s.Users().Search(context.Background(), "ri")
+	if err != nil || len(users) != 1 || users[0].Slack_id != "U02MARIE01" {
+		t.Fatalf("expected a partial match, got %+v err %v", users, err)
+	}
+}
+
+func Test_UserDirectoryConcurrentReaders(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := s.Users().Get(context.Background(), "U7D3Q7N8Y"); err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls := server.Calls("users.list"); calls != 1 {
+		t.Fatalf("expected concurrent readers to share one load, got %d calls", calls)
+	}
+}
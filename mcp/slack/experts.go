@@ -0,0 +1,192 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// expertPostsPerChannel is the number of technology posts per channel used to rank experts
+	expertPostsPerChannel = 100
+	// expertTopPosts is the number of supporting permalinks returned per expert
+	expertTopPosts = 3
+	// expertRecencyHalfLife is the age at which the recency bonus of a post is halved
+	expertRecencyHalfLife = 180 * 24 * time.Hour
+)
+
+// Expert is a person ranked by the posts they wrote about a technology
+type Expert struct {
+	User           ConceptUser `json:"user"`
+	Score          float64     `json:"score"`
+	Post_Count     int         `json:"post_count"`
+	Reaction_Count int         `json:"reaction_count"`
+	Reply_Count    int         `json:"reply_count"`
+	Last_Posted    time.Time   `json:"last_posted"`
+	Top_Permalinks []string    `json:"top_permalinks"`
+	// Reason explains the ranking to the agent
+	Reason string `json:"reason"`
+}
+
+// FindExperts ranks the authors of the posts tagged with the technology, or the technologies
+// under it, in the channels.
+// The posts searched in Slack come without their reactions and replies: only the top posts
+// of the experts are described, with a history call per channel, before the final ranking.
+// Authors who left the workspace are not returned.
+func (s *SlackService) FindExperts(ctx context.Context, tech string, channels []string) ([]Expert, error) {
+	posts := []MessageInfo{}
+	// live tells the posts searched in Slack by permalink
+	live := map[string]bool{}
+	for _, channel := range channels {
+		messages, _, isLive, err := s.technologyPosts(ctx, tech, channel, TimeRange{}, expertPostsPerChannel, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to search %s: %w", channel, err)
+		}
+		if isLive {
+			for _, message := range messages {
+				live[message.Permalink] = true
+			}
+		}
+		posts = append(posts, messages...)
+	}
+
+	name := s.taxonomy.Expand(tech).Name
+	experts := rankExperts(name, posts, time.Now())
+	if len(live) > 0 {
+		s.addTopPostsInfo(ctx, experts, posts, live)
+		experts = rankExperts(name, posts, time.Now())
+	}
+
+	active := []Expert{}
+	for _, expert := range experts {
+		user, ok, err := s.users.Get(ctx, expert.User.Slack_id)
+		if err != nil {
+			// keep the ranking when the directory is unavailable, with the name found in the posts
+			s.log(ctx).Warn("could not resolve expert", zap.String("slack_id", expert.User.Slack_id), zap.Error(err))
+			active = append(active, expert)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		expert.User = user
+		active = append(active, expert)
+	}
+	return active, nil
+}
+
+// addTopPostsInfo fills the reply and reaction counts of the top posts of the experts searched in Slack
+func (s *SlackService) addTopPostsInfo(ctx context.Context, experts []Expert, posts []MessageInfo, live map[string]bool) {
+	top := map[string]bool{}
+	for _, expert := range experts {
+		for _, permalink := range expert.Top_Permalinks {
+			top[permalink] = live[permalink]
+		}
+	}
+
+	indexes := []int{}
+	described := []MessageInfo{}
+	for i, post := range posts {
+		if top[post.Permalink] {
+			indexes = append(indexes, i)
+			described = append(described, post)
+		}
+	}
+	s.addHistoryInfo(ctx, described)
+	for j, i := range indexes {
+		posts[i] = described[j]
+	}
+}
+
+// rankExperts aggregates the posts per author and sorts the authors by score, best first.
+// Each post is worth one point, plus a bonus for its reactions and replies, and is
+// weighted by its age so that recent knowledge ranks higher without erasing older posts.
+func rankExperts(tech string, posts []MessageInfo, now time.Time) []Expert {
+	type scoredPost struct {
+		permalink string
+		score     float64
+	}
+
+	byAuthor := map[string]*Expert{}
+	authorPosts := map[string][]scoredPost{}
+	for _, post := range posts {
+		if post.Slack_id == "" {
+			continue
+		}
+		expert, ok := byAuthor[post.Slack_id]
+		if !ok {
+			expert = &Expert{
+				User: ConceptUser{Slack_id: post.Slack_id, Slack_Name: post.Slack_Author_Name},
+			}
+			byAuthor[post.Slack_id] = expert
+		}
+
+		age := max(now.Sub(post.Posted), 0)
+		recency := 0.5 + 0.5*math.Pow(0.5, float64(age)/float64(expertRecencyHalfLife))
+		score := (1 + 0.5*math.Log1p(float64(post.Reaction_Count)) + 0.5*math.Log1p(float64(post.Reply_Count))) * recency
+
+		expert.Score += score
+		expert.Post_Count++
+		expert.Reaction_Count += post.Reaction_Count
+		expert.Reply_Count += post.Reply_Count
+		if post.Posted.After(expert.Last_Posted) {
+			expert.Last_Posted = post.Posted
+		}
+		authorPosts[post.Slack_id] = append(authorPosts[post.Slack_id], scoredPost{permalink: post.Permalink, score: score})
+	}
+
+	experts := []Expert{}
+	for id, expert := range byAuthor {
+		scored := authorPosts[id]
+		sort.SliceStable(scored, func(i, j int) bool { return scored[i].score > scored[j].score })
+		expert.Top_Permalinks = []string{}
+		for _, post := range scored[:min(len(scored), expertTopPosts)] {
+			expert.Top_Permalinks = append(expert.Top_Permalinks, post.permalink)
+		}
+		expert.Score = math.Round(expert.Score*100) / 100
+		expert.Reason = expertReason(tech, expert)
+		experts = append(experts, *expert)
+	}
+
+	sort.Slice(experts, func(i, j int) bool {
+		if experts[i].Score != experts[j].Score {
+			return experts[i].Score > experts[j].Score
+		}
+		return experts[i].User.Slack_id < experts[j].User.Slack_id
+	})
+	return experts
+}
+
+// expertReason summarizes the numbers behind the score of an expert
+func expertReason(tech string, expert *Expert) string {
+	parts := []string{plural(expert.Post_Count, "post") + " tagged " + tech}
+	if expert.Reaction_Count > 0 {
+		parts = append(parts, plural(expert.Reaction_Count, "reaction"))
+	}
+	if expert.Reply_Count > 0 {
+		parts = append(parts, plural(expert.Reply_Count, "thread reply")+" received")
+	}
+	parts = append(parts, "last post on "+expert.Last_Posted.Format(time.DateOnly))
+	return strings.Join(parts, ", ")
+}
+
+func plural(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	if strings.HasSuffix(noun, "y") {
+		return fmt.Sprintf("%d %sies", count, strings.TrimSuffix(noun, "y"))
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+// tsTime converts a Slack timestamp to a time
+func tsTime(ts string) time.Time {
+	seconds, sequence := splitTs(ts)
+	return time.Unix(seconds, sequence*int64(time.Microsecond)).UTC()
+}
@@ -0,0 +1,60 @@
+package slack
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_RankExperts(t *testing.T) {
+	now := time.Unix(1718000000, 0)
+	posts := []MessageInfo{
+		{Slack_id: "U1", Posted: tsTime("1718000000.000100"), Permalink: "popular", Reaction_Count: 10, Reply_Count: 4},
+		{Slack_id: "U2", Posted: tsTime("1718000000.000200"), Permalink: "recent"},
+		{Slack_id: "U2", Posted: tsTime("1690000000.000100"), Permalink: "old"},
+		{Slack_id: "U3", Posted: tsTime("1600000000.000100"), Permalink: "very-old"},
+	}
+
+	experts := rankExperts("golang", posts, now)
+	if len(experts) != 3 {
+		t.Fatalf("expected 3 experts, got %+v", experts)
+	}
+	// a post with many reactions and replies beats two quiet posts, which beat a single old post
+	if experts[0].User.Slack_id != "U1" || experts[1].User.Slack_id != "U2" || experts[2].User.Slack_id != "U3" {
+		t.Fatalf("unexpected ranking %+v", experts)
+	}
+	if experts[1].Post_Count != 2 || !experts[1].Last_Posted.Equal(tsTime("1718000000.000200")) {
+		t.Fatalf("unexpected aggregation %+v", experts[1])
+	}
+	if experts[1].Top_Permalinks[0] != "recent" {
+		t.Fatalf("expected the most recent post first, got %v", experts[1].Top_Permalinks)
+	}
+	if experts[0].Reason != "1 post tagged golang, 10 reactions, 4 thread replies received, last post on 2024-06-10" {
+		t.Fatalf("unexpected reason %q", experts[0].Reason)
+	}
+}
+
+func Test_FindExpertsTool(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	tool := NewFindExperts(s, newTestChannels(t, s))
+
+	result := tool.CallStructured(context.Background(), map[string]interface{}{"technology": "golang"})
+	if *result.IsError {
+		t.Fatalf("unexpected error result %+v", result)
+	}
+	output, ok := result.StructuredContent.(ExpertsOutput)
+	experts := output.Experts
+	if !ok || len(experts) != 2 {
+		t.Fatalf("expected 2 experts, got %+v", result.StructuredContent)
+	}
+	if experts[0].User.Real_Name != "Alexis Zankowitch" || experts[0].Post_Count != 2 || experts[0].Reply_Count != 2 {
+		t.Fatalf("unexpected first expert %+v", experts[0])
+	}
+	if experts[1].User.Slack_id != "U02MARIE01" {
+		t.Fatalf("unexpected second expert %+v", experts[1])
+	}
+	// the top posts are described with a history call per channel, golang being posted in two
+	if calls := server.Calls("conversations.history"); calls != 2 {
+		t.Fatalf("expected a history call per channel, got %d", calls)
+	}
+}
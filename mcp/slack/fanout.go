@@ -0,0 +1,143 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// maxConcurrentChannelSearches bounds the channels searched at the same time by a tool,
+// the rate limiter still spaces the calls to Slack
+const maxConcurrentChannelSearches = 4
+
+// Statuses of the search of a channel
+const (
+	ChannelOK          = "ok"
+	ChannelError       = "error"
+	ChannelRateLimited = "rate_limited"
+	ChannelTimedOut    = "timed_out"
+)
+
+// ChannelStatus tells how the search of a channel went, so the agent knows when coverage was incomplete
+type ChannelStatus struct {
+	Channel    string `json:"channel"`
+	Status     string `json:"status" description:"ok, error, rate_limited or timed_out"`
+	Post_Count int    `json:"post_count"`
+	Error      string `json:"error,omitempty"`
+	// Retry_After is set for rate limited channels, in seconds
+	Retry_After int `json:"retry_after,omitempty" description:"Seconds to wait before retrying a rate limited channel"`
+}
+
+// channelResult is what the search of a channel returned
+type channelResult struct {
+	posts []MessageInfo
+	next  string
+}
+
+// searchChannels runs search on every channel concurrently, at most maxConcurrentChannelSearches
+// at a time. A failing channel does not stop the others, its error is reported in its status.
+// Results and statuses are in the order of the channels.
+func searchChannels(ctx context.Context, channels []string, search func(ctx context.Context, channel string) (channelResult, error)) ([]channelResult, []ChannelStatus) {
+	results := make([]channelResult, len(channels))
+	statuses := make([]ChannelStatus, len(channels))
+
+	var group errgroup.Group
+	group.SetLimit(maxConcurrentChannelSearches)
+	for i, channel := range channels {
+		group.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				statuses[i] = channelStatus(channel, err)
+				return nil
+			}
+			result, err := search(ctx, channel)
+			if err != nil {
+				statuses[i] = channelStatus(channel, err)
+				return nil
+			}
+			results[i] = result
+			statuses[i] = ChannelStatus{Channel: channel, Status: ChannelOK, Post_Count: len(result.posts)}
+			return nil
+		})
+	}
+	// the searches report their errors in the statuses
+	_ = group.Wait()
+	return results, statuses
+}
+
+// channelStatus classifies the error of a channel search
+func channelStatus(channel string, err error) ChannelStatus {
+	status := ChannelStatus{Channel: channel, Status: ChannelError, Error: err.Error()}
+	var throttled *ThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		status.Status = ChannelRateLimited
+		status.Retry_After = int(throttled.RetryAfter.Round(time.Second).Seconds())
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		status.Status = ChannelTimedOut
+	}
+	return status
+}
+
+// mergeChannelPosts merges the posts of the channels, best score first. Posts with the same score
+// alternate between the channels in the order each channel ranked them. A post found in several
+// channels is returned once.
+func mergeChannelPosts(results []channelResult) []MessageInfo {
+	type rankedPost struct {
+		post    MessageInfo
+		rank    int
+		channel int
+	}
+
+	ranked := []rankedPost{}
+	seen := map[string]bool{}
+	for channel, result := range results {
+		for rank, post := range result.posts {
+			if post.Permalink != "" {
+				if seen[post.Permalink] {
+					continue
+				}
+				seen[post.Permalink] = true
+			}
+			ranked = append(ranked, rankedPost{post: post, rank: rank, channel: channel})
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].post.Score != ranked[j].post.Score {
+			return ranked[i].post.Score > ranked[j].post.Score
+		}
+		if ranked[i].rank != ranked[j].rank {
+			return ranked[i].rank < ranked[j].rank
+		}
+		return ranked[i].channel < ranked[j].channel
+	})
+
+	posts := []MessageInfo{}
+	for _, r := range ranked {
+		posts = append(posts, r.post)
+	}
+	return posts
+}
+
+// incompleteNotice describes the channels that could not be searched, empty when every search succeeded
+func incompleteNotice(statuses []ChannelStatus) string {
+	failed := []string{}
+	for _, status := range statuses {
+		switch status.Status {
+		case ChannelRateLimited:
+			failed = append(failed, fmt.Sprintf("%s (rate limited, retry in %ds)", status.Channel, status.Retry_After))
+		case ChannelTimedOut:
+			failed = append(failed, fmt.Sprintf("%s (timed out)", status.Channel))
+		case ChannelError:
+			failed = append(failed, fmt.Sprintf("%s (error: %s)", status.Channel, status.Error))
+		}
+	}
+	if len(failed) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Results are incomplete, %s could not be searched: %s. Call again with the cursor below to retry them.", plural(len(failed), "channel"), strings.Join(failed, ", "))
+}
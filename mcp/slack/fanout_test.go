@@ -0,0 +1,124 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_SearchChannels(t *testing.T) {
+	channels := []string{"a", "b", "c", "d", "e", "f"}
+	var running, peak atomic.Int32
+	results, statuses := searchChannels(context.Background(), channels, func(ctx context.Context, channel string) (channelResult, error) {
+		peak.Store(max(peak.Load(), running.Add(1)))
+		defer running.Add(-1)
+		time.Sleep(10 * time.Millisecond)
+
+		switch channel {
+		case "b":
+			return channelResult{}, fmt.Errorf("channel_not_found")
+		case "c":
+			return channelResult{}, fmt.Errorf("failed to search: %w", &ThrottledError{Method: "search.messages", RetryAfter: 30 * time.Second})
+		case "d":
+			return channelResult{}, context.DeadlineExceeded
+		}
+		return channelResult{posts: []MessageInfo{{Ts: channel}}, next: "next-" + channel}, nil
+	})
+
+	if peak.Load() > maxConcurrentChannelSearches {
+		t.Fatalf("expected at most %d concurrent searches, got %d", maxConcurrentChannelSearches, peak.Load())
+	}
+	expected := []ChannelStatus{
+		{Channel: "a", Status: ChannelOK, Post_Count: 1},
+		{Channel: "b", Status: ChannelError, Error: "channel_not_found"},
+		{Channel: "c", Status: ChannelRateLimited, Retry_After: 30},
+		{Channel: "d", Status: ChannelTimedOut},
+		{Channel: "e", Status: ChannelOK, Post_Count: 1},
+		{Channel: "f", Status: ChannelOK, Post_Count: 1},
+	}
+	for i, status := range statuses {
+		status.Error = strings.TrimPrefix(status.Error, "failed to search: ")
+		if status.Status != expected[i].Status || status.Channel != expected[i].Channel || status.Post_Count != expected[i].Post_Count || status.Retry_After != expected[i].Retry_After {
+			t.Errorf("expected %+v, got %+v", expected[i], status)
+		}
+	}
+	if statuses[1].Error != "channel_not_found" || results[4].next != "next-e" {
+		t.Fatalf("unexpected results %+v %+v", statuses[1], results[4])
+	}
+
+	notice := incompleteNotice(statuses)
+	for _, part := range []string{"3 channels could not be searched", "b (error: channel_not_found)", "c (rate limited, retry in 30s)", "d (timed out)"} {
+		if !strings.Contains(notice, part) {
+			t.Errorf("expected the notice to contain %q, got %q", part, notice)
+		}
+	}
+	if notice := incompleteNotice(statuses[:1]); notice != "" {
+		t.Fatalf("expected no notice when every channel was searched, got %q", notice)
+	}
+}
+
+func Test_SearchChannelsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, statuses := searchChannels(ctx, []string{"a", "b"}, func(ctx context.Context, channel string) (channelResult, error) {
+		return channelResult{}, errors.New("must not be called")
+	})
+	for _, status := range statuses {
+		if status.Status != ChannelTimedOut {
+			t.Fatalf("expected the channels to time out, got %+v", statuses)
+		}
+	}
+}
+
+func Test_MergeChannelPosts(t *testing.T) {
+	results := []channelResult{
+		{posts: []MessageInfo{{Ts: "a1", Permalink: "a1"}, {Ts: "a2", Permalink: "a2"}, {Ts: "a3", Permalink: "a3"}}},
+		{},
+		{posts: []MessageInfo{{Ts: "c1", Permalink: "c1", Score: 1.5}, {Ts: "c2", Permalink: "c2"}, {Ts: "a1", Permalink: "a1"}}},
+	}
+
+	posts := mergeChannelPosts(results)
+	order := []string{}
+	for _, post := range posts {
+		order = append(order, post.Ts)
+	}
+	// the scored post first, then the channels alternate, the duplicate is dropped
+	if strings.Join(order, ",") != "c1,a1,a2,c2,a3" {
+		t.Fatalf("unexpected order %v", order)
+	}
+}
+
+func Test_FindTechnologyPostToolChannelStatuses(t *testing.T) {
+	s, _ := newTestService(t, loadFixtures(t))
+	tool := NewFindTechnologyPost(s, newTestChannels(t, s))
+
+	result := tool.CallStructured(context.Background(), map[string]interface{}{"technology": "golang"})
+	output, ok := result.StructuredContent.(PostsOutput)
+	if !ok || len(output.Channels) != 2 || output.Notice != "" {
+		t.Fatalf("expected a status per channel, got %+v", result.StructuredContent)
+	}
+	if output.Channels[0] != (ChannelStatus{Channel: "concept-tech", Status: ChannelOK, Post_Count: 1}) ||
+		output.Channels[1] != (ChannelStatus{Channel: "today-i-learned", Status: ChannelOK, Post_Count: 2}) {
+		t.Fatalf("unexpected statuses %+v", output.Channels)
+	}
+
+	// a cancelled call is incomplete rather than failed, the cursor retries the channels
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result = tool.CallStructured(ctx, map[string]interface{}{"technology": "golang"})
+	if *result.IsError {
+		t.Fatalf("unexpected error result %+v", result)
+	}
+	output, ok = result.StructuredContent.(PostsOutput)
+	if !ok || len(output.Posts) != 0 || output.Channels[0].Status != ChannelTimedOut || output.Next_Cursor == "" {
+		t.Fatalf("expected timed out channels and a cursor, got %+v", result.StructuredContent)
+	}
+	if !strings.Contains(output.Notice, "2 channels could not be searched") {
+		t.Fatalf("expected an incomplete notice, got %q", output.Notice)
+	}
+}
@@ -0,0 +1,292 @@
+package slack
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// BM25 parameters, the usual defaults
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+const (
+	// snippetRadius is the number of characters kept around the first match of a snippet
+	snippetRadius = 80
+	// taggedPostBoost is added to the score of the posts tagged with the technology emoji,
+	// so a tagged post ranks above a post only mentioning the technology once
+	taggedPostBoost = 2.0
+)
+
+// stopWords are not indexed, they match too many posts to rank anything
+var stopWords = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`a about above after again against all am an and any are as at be because been
+		before being below between both but by can could did do does doing down during each few for from further had
+		has have having he her here hers herself him himself his how i if in into is it its itself just me more most
+		my myself no nor not of off on once only or other our ours ourselves out over own same she should so some such
+		than that the their theirs them themselves then there these they this those through to too under until up
+		very was we were what when where which while who whom why will with would you your yours yourself yourselves`) {
+		stopWords[word] = true
+	}
+}
+
+// token is a word of a message, with its position in the text for snippets
+type token struct {
+	term  string
+	start int
+	end   int
+}
+
+// tokenize splits a text in lower case stemmed terms, without stop words
+func tokenize(text string) []token {
+	tokens := []token{}
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		wordStart := start
+		start = -1
+		word := strings.ToLower(text[wordStart:end])
+		if stopWords[word] {
+			return
+		}
+		tokens = append(tokens, token{term: stem(word), start: wordStart, end: end})
+	}
+
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+	return tokens
+}
+
+// indexQuery is a parsed search: words matched anywhere and phrases matched in order
+type indexQuery struct {
+	terms   []string
+	phrases [][]string
+}
+
+// parseIndexQuery splits a query in words and "quoted phrases"
+func parseIndexQuery(query string) indexQuery {
+	parsed := indexQuery{}
+	parts := strings.Split(query, `"`)
+	for i, part := range parts {
+		terms := []string{}
+		for _, token := range tokenize(part) {
+			terms = append(terms, token.term)
+		}
+		// odd parts are between quotes, an unclosed quote is a phrase up to the end
+		if i%2 == 1 && len(terms) > 1 {
+			parsed.phrases = append(parsed.phrases, terms)
+		}
+		parsed.terms = append(parsed.terms, terms...)
+	}
+	return parsed
+}
+
+// posting lists the positions of a term in a document
+type posting struct {
+	doc       int
+	positions []int
+}
+
+// IndexHit is a message matching a search
+type IndexHit struct {
+	Message StoredMessage
+	Score   float64
+	Snippet string
+}
+
+// SearchIndex is an immutable inverted index over the stored messages, ranking with BM25
+type SearchIndex struct {
+	messages []StoredMessage
+	tokens   [][]token
+	postings map[string][]posting
+	avgLen   float64
+}
+
+// NewSearchIndex indexes the messages
+func NewSearchIndex(messages []StoredMessage) *SearchIndex {
+	index := &SearchIndex{
+		messages: messages,
+		tokens:   make([][]token, len(messages)),
+		postings: map[string][]posting{},
+	}
+
+	total := 0
+	for doc, message := range messages {
+		tokens := tokenize(message.Text)
+		index.tokens[doc] = tokens
+		total += len(tokens)
+
+		positions := map[string][]int{}
+		for position, token := range tokens {
+			positions[token.term] = append(positions[token.term], position)
+		}
+		for term, termPositions := range positions {
+			index.postings[term] = append(index.postings[term], posting{doc: doc, positions: termPositions})
+		}
+	}
+	if len(messages) > 0 {
+		index.avgLen = float64(total) / float64(len(messages))
+	}
+	return index
+}
+
+// Search returns the messages of the channels matching every word and phrase of the query,
+// best first. Every channel is searched when channelIDs is empty.
+func (i *SearchIndex) Search(query string, channelIDs []string) []IndexHit {
+	parsed := parseIndexQuery(query)
+	if len(parsed.terms) == 0 {
+		return []IndexHit{}
+	}
+
+	channels := map[string]bool{}
+	for _, id := range channelIDs {
+		channels[id] = true
+	}
+
+	// documents containing every term, with their term frequencies
+	scores := map[int]float64{}
+	for n, term := range uniqueTerms(parsed.terms) {
+		postings := i.postings[term]
+		matched := map[int]float64{}
+		idf := math.Log(1 + (float64(len(i.messages))-float64(len(postings))+0.5)/(float64(len(postings))+0.5))
+		for _, p := range postings {
+			if n > 0 {
+				if _, ok := scores[p.doc]; !ok {
+					continue
+				}
+			}
+			tf := float64(len(p.positions))
+			length := float64(len(i.tokens[p.doc]))
+			matched[p.doc] = scores[p.doc] + idf*tf*(bm25K1+1)/(tf+bm25K1*(1-bm25B+bm25B*length/i.avgLen))
+		}
+		scores = matched
+	}
+
+	hits := []IndexHit{}
+	for doc, score := range scores {
+		message := i.messages[doc]
+		if len(channels) > 0 && !channels[message.Channel_id] {
+			continue
+		}
+		if !i.matchPhrases(doc, parsed.phrases) {
+			continue
+		}
+		hits = append(hits, IndexHit{
+			Message: message,
+			Score:   math.Round(score*1000) / 1000,
+			Snippet: i.snippet(doc, parsed.terms),
+		})
+	}
+
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		return tsLess(hits[b].Message.Ts, hits[a].Message.Ts)
+	})
+	return hits
+}
+
+func (i *SearchIndex) snippet(doc int, terms []string) string {
+	return snippetAround(i.messages[doc].Text, i.tokens[doc], terms)
+}
+
+// matchPhrases tells if the document contains every phrase, as consecutive terms
+func (i *SearchIndex) matchPhrases(doc int, phrases [][]string) bool {
+	tokens := i.tokens[doc]
+	for _, phrase := range phrases {
+		found := false
+		for start := 0; start+len(phrase) <= len(tokens) && !found; start++ {
+			found = true
+			for n, term := range phrase {
+				if tokens[start+n].term != term {
+					found = false
+					break
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// messageSnippet returns the part of a text around the first word of the query it contains,
+// or its beginning
+func messageSnippet(text string, query string) string {
+	return snippetAround(text, tokenize(text), parseIndexQuery(query).terms)
+}
+
+// snippetAround cuts the text around the first token matching a term, on word boundaries
+func snippetAround(text string, tokens []token, terms []string) string {
+	wanted := map[string]bool{}
+	for _, term := range terms {
+		wanted[term] = true
+	}
+
+	center := 0
+	for _, token := range tokens {
+		if wanted[token.term] {
+			center = token.start
+			break
+		}
+	}
+
+	start := max(center-snippetRadius, 0)
+	end := min(center+snippetRadius, len(text))
+	// move to the closest word boundaries
+	for start > 0 && !isSpaceByte(text[start-1]) {
+		start--
+	}
+	for end < len(text) && !isSpaceByte(text[end]) {
+		end++
+	}
+
+	snippet := strings.Join(strings.Fields(text[start:end]), " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(text) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// isSpaceByte tells if a byte is an ASCII space, it is never part of a multi-byte rune
+func isSpaceByte(b byte) bool {
+	return b == ' ' || b == '\n' || b == '\t' || b == '\r'
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+	return unique
+}
+
+// String describes the index size, for logs
+func (i *SearchIndex) String() string {
+	return fmt.Sprintf("%d messages, %d terms", len(i.messages), len(i.postings))
+}
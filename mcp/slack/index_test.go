@@ -0,0 +1,126 @@
+package slack
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlexisZankowitch/concept-insight/mcp/slack/fakeslack"
+	"github.com/slack-go/slack"
+)
+
+func Test_Stem(t *testing.T) {
+	for word, expected := range map[string]string{
+		"caresses":       "caress",
+		"ponies":         "poni",
+		"relational":     "relat",
+		"generalization": "gener",
+		"running":        "run",
+		"filing":         "file",
+		"happy":          "happi",
+		"components":     "compon",
+		"adjustment":     "adjust",
+		"goroutines":     "goroutin",
+		"k8s":            "k8s",
+	} {
+		if stemmed := stem(word); stemmed != expected {
+			t.Errorf("expected %s to stem to %s, got %s", word, expected, stemmed)
+		}
+	}
+}
+
+func Test_Tokenize(t *testing.T) {
+	tokens := tokenize("The Goroutines are running, in Go!")
+	terms := []string{}
+	for _, token := range tokens {
+		terms = append(terms, token.term)
+	}
+	// stop words are dropped, go is not one
+	if len(terms) != 3 || terms[0] != "goroutin" || terms[1] != "run" || terms[2] != "go" {
+		t.Fatalf("unexpected terms %v", terms)
+	}
+	if tokens[0].start != 4 || tokens[0].end != 14 {
+		t.Fatalf("unexpected offsets %+v", tokens[0])
+	}
+}
+
+func Test_SearchIndex(t *testing.T) {
+	index := NewSearchIndex([]StoredMessage{
+		{Channel_id: "C1", Ts: "1.000001", Text: "We run golang services, golang everywhere"},
+		{Channel_id: "C1", Ts: "2.000001", Text: "Some golang in a long message about many other unrelated things like lunch and coffee"},
+		{Channel_id: "C1", Ts: "3.000001", Text: "Server components are great"},
+		{Channel_id: "C2", Ts: "4.000001", Text: "Rendering components on the server"},
+		{Channel_id: "C2", Ts: "5.000001", Text: "Nothing to see"},
+	})
+
+	hits := index.Search("golang", nil)
+	if len(hits) != 2 || hits[0].Message.Ts != "1.000001" || hits[0].Score <= hits[1].Score {
+		t.Fatalf("expected the post repeating golang first, got %+v", hits)
+	}
+
+	// every word must match, in any order, stemmed
+	hits = index.Search("component servers", nil)
+	if len(hits) != 2 {
+		t.Fatalf("expected 2 hits, got %+v", hits)
+	}
+	hits = index.Search(`"server components"`, nil)
+	if len(hits) != 1 || hits[0].Message.Ts != "3.000001" {
+		t.Fatalf("expected the phrase match only, got %+v", hits)
+	}
+	hits = index.Search("components", []string{"C2"})
+	if len(hits) != 1 || hits[0].Message.Channel_id != "C2" {
+		t.Fatalf("expected the C2 match only, got %+v", hits)
+	}
+	if hits := index.Search("the", nil); len(hits) != 0 {
+		t.Fatalf("expected no match for a stop word, got %+v", hits)
+	}
+}
+
+func Test_MessageSnippet(t *testing.T) {
+	text := "This is a long introduction that goes on and on before it finally mentions the actual subject, " +
+		"which is kubernetes operators, and then keeps going for a long while with many more details " +
+		"that nobody will ever read because the point was made much earlier."
+
+	snippet := messageSnippet(text, "kubernetes")
+	if snippet[:3] != "…" || snippet[len(snippet)-3:] != "…" {
+		t.Fatalf("expected a snippet cut on both sides, got %q", snippet)
+	}
+	if len(snippet) >= len(text) {
+		t.Fatalf("expected a shorter snippet, got %q", snippet)
+	}
+	if got := messageSnippet("short text", "missing"); got != "short text" {
+		t.Fatalf("expected the whole short text, got %q", got)
+	}
+}
+
+func Test_SlackGetTechonologyPostMergesIndex(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	// a post mentioning golang without the emoji tag
+	post := fakeslack.Message{}
+	post.Channel = slack.CtxChannel{ID: "C01CONCEPT", Name: "concept-tech"}
+	post.User = "U03LINUS01"
+	post.Timestamp = "1718600000.000700"
+	post.Text = "Anyone profiling golang services with pprof in production?"
+	server.AddMessage(post)
+
+	store := newTestStore(t)
+	if err := s.UseStore(store); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	syncer := NewSyncer(s, store, []Channel{conceptTech}, time.Minute, time.Hour)
+	if err := syncer.SyncAll(context.Background()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	r, _, err := s.GetTechonologyPost(context.Background(), "golang", "concept-tech", TimeRange{}, 20, "")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// the tagged post ranks first, then the one only mentioning golang
+	if len(r) != 2 || r[0].Ts != "1718000000.000100" || r[1].Ts != "1718600000.000700" {
+		t.Fatalf("unexpected posts %+v", r)
+	}
+	if r[1].Score <= 0 || r[1].Snippet == "" || r[0].Score < taggedPostBoost {
+		t.Fatalf("expected scores and snippets, got %+v", r)
+	}
+}
@@ -0,0 +1,205 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// mrkdwnToken matches the <...> tokens of Slack mrkdwn: mentions, channels, special mentions and links
+var mrkdwnToken = regexp.MustCompile(`<([^<>\n]+)>`)
+
+// mrkdwnEntities decodes the only entities Slack encodes, &amp; last so &amp;lt; stays &lt;
+var mrkdwnEntities = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+
+// mrkdwnRenderer converts Slack mrkdwn to Markdown, resolving the mentions with its lookups
+type mrkdwnRenderer struct {
+	// userName returns the handle of a user id
+	userName func(ctx context.Context, id string) (string, bool)
+	// channelName returns the name of a channel id
+	channelName func(ctx context.Context, id string) (string, bool)
+}
+
+// render converts a mrkdwn text to Markdown.
+// Code blocks and inline code keep their content, only entities and tokens are decoded in them.
+func (r *mrkdwnRenderer) render(ctx context.Context, text string) string {
+	var out strings.Builder
+	for i, block := range strings.Split(text, "```") {
+		// odd parts are inside a code block
+		if i%2 == 1 {
+			code := r.renderCode(ctx, block)
+			out.WriteString("```")
+			if !strings.HasPrefix(code, "\n") {
+				out.WriteString("\n")
+			}
+			out.WriteString(code)
+			if !strings.HasSuffix(code, "\n") {
+				out.WriteString("\n")
+			}
+			out.WriteString("```")
+			continue
+		}
+		for j, span := range strings.Split(block, "`") {
+			if j%2 == 1 {
+				out.WriteString("`" + r.renderCode(ctx, span) + "`")
+				continue
+			}
+			out.WriteString(r.renderProse(ctx, span))
+		}
+	}
+	return out.String()
+}
+
+// renderProse converts the formatting, tokens and entities of text outside code
+func (r *mrkdwnRenderer) renderProse(ctx context.Context, text string) string {
+	// tokens are set aside while the formatting is converted, so links are never altered
+	tokens := []string{}
+	text = mrkdwnToken.ReplaceAllStringFunc(text, func(token string) string {
+		tokens = append(tokens, r.renderToken(ctx, token[1:len(token)-1], true))
+		return fmt.Sprintf("\x00%d\x00", len(tokens)-1)
+	})
+
+	text = convertEmphasis(text, '*', "**")
+	text = convertEmphasis(text, '~', "~~")
+	text = mrkdwnEntities.Replace(text)
+
+	for i, token := range tokens {
+		text = strings.Replace(text, fmt.Sprintf("\x00%d\x00", i), token, 1)
+	}
+	return text
+}
+
+// renderCode decodes the tokens and entities of code, without Markdown links
+func (r *mrkdwnRenderer) renderCode(ctx context.Context, code string) string {
+	code = mrkdwnToken.ReplaceAllStringFunc(code, func(token string) string {
+		return r.renderToken(ctx, token[1:len(token)-1], false)
+	})
+	return mrkdwnEntities.Replace(code)
+}
+
+// renderToken converts the content of a <...> token, links become Markdown links when markdown is set
+func (r *mrkdwnRenderer) renderToken(ctx context.Context, token string, markdown bool) string {
+	target, label, _ := strings.Cut(token, "|")
+	label = mrkdwnEntities.Replace(label)
+
+	switch {
+	case strings.HasPrefix(target, "@"):
+		if name, ok := r.userName(ctx, target[1:]); ok {
+			return "@" + name
+		}
+		if label != "" {
+			return "@" + strings.TrimPrefix(label, "@")
+		}
+		return "@unknown-user"
+
+	case strings.HasPrefix(target, "#"):
+		if label != "" {
+			return "#" + label
+		}
+		if name, ok := r.channelName(ctx, target[1:]); ok {
+			return "#" + name
+		}
+		return "#unknown-channel"
+
+	case strings.HasPrefix(target, "!"):
+		// <!here>, <!channel>, <!subteam^S123|@team>, <!date^1392734382^{date}|fallback>
+		if label != "" {
+			return label
+		}
+		name, _, _ := strings.Cut(target[1:], "^")
+		return "@" + name
+	}
+
+	target = mrkdwnEntities.Replace(target)
+	if !markdown {
+		return target
+	}
+	if label == "" || label == target || "mailto:"+label == target {
+		if strings.HasPrefix(target, "mailto:") {
+			return strings.TrimPrefix(target, "mailto:")
+		}
+		return target
+	}
+	return fmt.Sprintf("[%s](%s)", label, target)
+}
+
+// convertEmphasis rewrites marker-delimited spans, such as *bold*, with the Markdown delimiter.
+// Like Slack, a span starts after a word boundary, ends before one and stays on one line.
+func convertEmphasis(text string, marker byte, delimiter string) string {
+	var out strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != marker || (i > 0 && !isEmphasisOpening(text[i-1])) || i+1 >= len(text) || isSpaceByte(text[i+1]) || text[i+1] == marker {
+			out.WriteByte(text[i])
+			continue
+		}
+
+		end := -1
+		for j := i + 1; j < len(text) && text[j] != '\n'; j++ {
+			if text[j] == marker && !isSpaceByte(text[j-1]) && (j+1 == len(text) || isEmphasisClosing(text[j+1])) {
+				end = j
+				break
+			}
+		}
+		if end < 0 {
+			out.WriteByte(text[i])
+			continue
+		}
+		out.WriteString(delimiter + text[i+1:end] + delimiter)
+		i = end
+	}
+	return out.String()
+}
+
+func isEmphasisOpening(b byte) bool {
+	return isSpaceByte(b) || strings.IndexByte("([{\"'>\x00", b) >= 0
+}
+
+func isEmphasisClosing(b byte) bool {
+	return isSpaceByte(b) || strings.IndexByte(")]}\"'.,;:!?\x00", b) >= 0
+}
+
+// renderMessages converts the mrkdwn of messages to Markdown, keeping the original in Raw_Message
+func (s *SlackService) renderMessages(ctx context.Context, messages []MessageInfo) {
+	renderer := &mrkdwnRenderer{
+		userName: func(ctx context.Context, id string) (string, bool) {
+			user, ok, err := s.users.Get(ctx, id)
+			if err != nil {
+				s.log(ctx).Warn("could not resolve mention", zap.String("slack_id", id), zap.Error(err))
+			}
+			return user.Slack_Name, ok
+		},
+		channelName: s.channelName,
+	}
+	for i := range messages {
+		if messages[i].Raw_Message == "" {
+			messages[i].Raw_Message = messages[i].Message
+		}
+		messages[i].Message = renderer.render(ctx, messages[i].Raw_Message)
+		if messages[i].Snippet != "" {
+			messages[i].Snippet = renderer.render(ctx, messages[i].Snippet)
+		}
+	}
+}
+
+// channelName returns the name of a channel id, channels are listed once and cached
+func (s *SlackService) channelName(ctx context.Context, id string) (string, bool) {
+	s.channelNamesMu.Lock()
+	defer s.channelNamesMu.Unlock()
+
+	if s.channelNames == nil {
+		channels, err := s.listChannels(ctx)
+		if err != nil {
+			s.log(ctx).Warn("could not resolve channel", zap.String("channel_id", id), zap.Error(err))
+			return "", false
+		}
+		s.channelNames = map[string]string{}
+		for _, channel := range channels {
+			s.channelNames[channel.ID] = channel.Name
+		}
+	}
+	name, ok := s.channelNames[id]
+	return name, ok
+}
@@ -0,0 +1,70 @@
+package slack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AlexisZankowitch/concept-insight/mcp/slack/fakeslack"
+	"github.com/slack-go/slack"
+)
+
+func Test_MrkdwnRender(t *testing.T) {
+	renderer := &mrkdwnRenderer{
+		userName: func(ctx context.Context, id string) (string, bool) {
+			if id == "U7D3Q7N8Y" {
+				return "alexis", true
+			}
+			return "", false
+		},
+		channelName: func(ctx context.Context, id string) (string, bool) {
+			if id == "C01CONCEPT" {
+				return "concept-tech", true
+			}
+			return "", false
+		},
+	}
+
+	for _, test := range []struct {
+		mrkdwn   string
+		expected string
+	}{
+		{"ask <@U7D3Q7N8Y> about it", "ask @alexis about it"},
+		{"ask <@U99UNKNOWN|bob> or <@U98UNKNOWN>", "ask @bob or @unknown-user"},
+		{"see <#C01CONCEPT> and <#C02TIL0001|today-i-learned>", "see #concept-tech and #today-i-learned"},
+		{"<!here> deploy done <!subteam^S123|@backend>", "@here deploy done @backend"},
+		{"read <https://go.dev/doc|the docs> or <https://go.dev>", "read [the docs](https://go.dev/doc) or https://go.dev"},
+		{"mail <mailto:a@concept.io|a@concept.io>", "mail a@concept.io"},
+		{"R&amp;D says 1 &lt; 2 &amp;&amp; 3 &gt; 2, not &amp;lt;", "R&D says 1 < 2 && 3 > 2, not &lt;"},
+		{"*bold*, _italic_ and ~gone~", "**bold**, _italic_ and ~~gone~~"},
+		{"a*b*c and snake_case_name stay", "a*b*c and snake_case_name stay"},
+		{"*<https://go.dev|Go>*", "**[Go](https://go.dev)**"},
+		{"run `a &amp;&amp; *b*`", "run `a && *b*`"},
+		{"```if a &lt; b {\n  *p = 1\n}```", "```\nif a < b {\n  *p = 1\n}\n```"},
+		{"&gt; quoted", "> quoted"},
+	} {
+		if got := renderer.render(context.Background(), test.mrkdwn); got != test.expected {
+			t.Errorf("render(%q) = %q, expected %q", test.mrkdwn, got, test.expected)
+		}
+	}
+}
+
+func Test_SlackMessagesRendered(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	post := fakeslack.Message{Reactions: []string{"golang"}}
+	post.Channel = slack.CtxChannel{ID: "C02TIL0001", Name: "today-i-learned"}
+	post.User = "U02MARIE01"
+	post.Timestamp = "1718600000.000700"
+	post.Text = "TIL from <@U7D3Q7N8Y> in <#C01CONCEPT>: *errgroup* &amp; context"
+	server.AddMessage(post)
+
+	r, _, err := s.GetPostByUser(context.Background(), "U02MARIE01", testChannels, TimeRange{}, 1, "")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(r) != 1 || r[0].Message != "TIL from @alexis in #concept-tech: **errgroup** & context" {
+		t.Fatalf("unexpected message %+v", r)
+	}
+	if r[0].Raw_Message != post.Text {
+		t.Fatalf("expected the raw text to be kept, got %q", r[0].Raw_Message)
+	}
+}
@@ -0,0 +1,288 @@
+package slack
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/strowk/foxy-contexts/pkg/mcp"
+)
+
+// Page is the paging state of a tool output
+type Page struct {
+	Next_Cursor string `json:"next_cursor,omitempty" description:"Cursor of the next page, absent on the last page"`
+	Notice      string `json:"notice,omitempty" description:"Why the results are incomplete, such as Slack rate limiting"`
+}
+
+// PostsOutput is the structured content of the tools returning posts
+type PostsOutput struct {
+	Posts []MessageInfo `json:"posts"`
+	// Channels is the status of each searched channel, for the tools searching channels one by one
+	Channels []ChannelStatus `json:"channels,omitempty" description:"Status of the search of each channel, anything but ok means the posts of the channel are missing"`
+	Page
+}
+
+// ResultCount implements mcptool.Counted
+func (o PostsOutput) ResultCount() int {
+	return len(o.Posts)
+}
+
+// UsersOutput is the structured content of the user details tool
+type UsersOutput struct {
+	Users []ConceptUser `json:"users"`
+	Page
+}
+
+// ResultCount implements mcptool.Counted
+func (o UsersOutput) ResultCount() int {
+	return len(o.Users)
+}
+
+// ThreadOutput is the structured content of the thread tool
+type ThreadOutput struct {
+	Thread
+	Page
+}
+
+// ResultCount implements mcptool.Counted
+func (o ThreadOutput) ResultCount() int {
+	return len(o.Replies)
+}
+
+// ExpertsOutput is the structured content of the experts tool
+type ExpertsOutput struct {
+	Experts []Expert `json:"experts"`
+	Page
+}
+
+// ResultCount implements mcptool.Counted
+func (o ExpertsOutput) ResultCount() int {
+	return len(o.Experts)
+}
+
+// textContent wraps a text in a content block
+func textContent(text string) mcp.TextContent {
+	return mcp.TextContent{
+		Type: "text",
+		Text: text,
+	}
+}
+
+// pageContent is the text rendering of a tool output followed by its notice and next cursor
+func pageContent(text string, page Page) []interface{} {
+	content := []interface{}{textContent(text)}
+	if page.Notice != "" {
+		content = append(content, textContent(page.Notice))
+	}
+	if page.Next_Cursor != "" {
+		content = append(content, nextCursorContent(page.Next_Cursor))
+	}
+	return content
+}
+
+// renderPosts renders posts as a Markdown list
+func renderPosts(posts []MessageInfo) string {
+	if len(posts) == 0 {
+		return "No posts found."
+	}
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s:\n", plural(len(posts), "post"))
+	for _, post := range posts {
+		out.WriteString("\n")
+		renderPost(&out, post)
+	}
+	return out.String()
+}
+
+// renderPost renders a post as a list item: its header line, then its indented text
+func renderPost(out *strings.Builder, post MessageInfo) {
+	fmt.Fprintf(out, "- **@%s** (%s) on %s, ts %s", post.Slack_Author_Name, post.Slack_id, post.Posted.Format(time.RFC3339), post.Ts)
+	if post.Reply_Count > 0 {
+		fmt.Fprintf(out, ", %s", plural(post.Reply_Count, "reply"))
+	}
+	if post.Reaction_Count > 0 {
+		fmt.Fprintf(out, ", %s", plural(post.Reaction_Count, "reaction"))
+	}
+	if post.Score > 0 {
+		fmt.Fprintf(out, ", score %.2f", post.Score)
+	}
+	out.WriteString("\n")
+	if post.Permalink != "" {
+		fmt.Fprintf(out, "  %s\n", post.Permalink)
+	}
+	for _, line := range strings.Split(post.Message, "\n") {
+		fmt.Fprintf(out, "  %s\n", line)
+	}
+}
+
+// renderUsers renders users as a Markdown list
+func renderUsers(users []ConceptUser) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s:\n\n", plural(len(users), "user"))
+	for _, user := range users {
+		fmt.Fprintf(&out, "- **%s** (@%s, %s)", user.Real_Name, user.Slack_Name, user.Slack_id)
+		if user.Display_Name != "" && user.Display_Name != user.Real_Name {
+			fmt.Fprintf(&out, ", displayed as %s", user.Display_Name)
+		}
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+// renderThread renders the parent post then its replies
+func renderThread(thread Thread) string {
+	var out strings.Builder
+	out.WriteString("Thread:\n\n")
+	renderPost(&out, thread.Parent)
+	if len(thread.Replies) == 0 {
+		out.WriteString("\nNo replies.\n")
+		return out.String()
+	}
+	fmt.Fprintf(&out, "\n%s:\n", plural(len(thread.Replies), "reply"))
+	for _, reply := range thread.Replies {
+		out.WriteString("\n")
+		renderPost(&out, reply)
+	}
+	return out.String()
+}
+
+// renderExperts renders experts as a numbered Markdown list
+func renderExperts(experts []Expert) string {
+	var out strings.Builder
+	for i, expert := range experts {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		fmt.Fprintf(&out, "%d. **%s** (@%s, %s), score %.2f\n", i+1, expert.User.Real_Name, expert.User.Slack_Name, expert.User.Slack_id, expert.Score)
+		fmt.Fprintf(&out, "   %s\n", expert.Reason)
+		for _, permalink := range expert.Top_Permalinks {
+			fmt.Fprintf(&out, "   - %s\n", permalink)
+		}
+	}
+	return out.String()
+}
+
+// TechnologiesOutput is the structured content of the technologies tool
+type TechnologiesOutput struct {
+	Technologies []Technology `json:"technologies"`
+}
+
+// ResultCount implements mcptool.Counted
+func (o TechnologiesOutput) ResultCount() int {
+	return len(o.Technologies)
+}
+
+// renderTechnologies renders the catalog as a Markdown list
+func renderTechnologies(technologies []Technology) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s:\n\n", plural(len(technologies), "technology"))
+	for _, tech := range technologies {
+		fmt.Fprintf(&out, "- **%s**", tech.Name)
+		if tech.Description != "" {
+			fmt.Fprintf(&out, ": %s", tech.Description)
+		}
+		if len(tech.Aliases) > 0 {
+			fmt.Fprintf(&out, ", also %s", strings.Join(tech.Aliases, ", "))
+		}
+		if len(tech.Emojis) > 0 {
+			fmt.Fprintf(&out, ", tagged :%s:", strings.Join(tech.Emojis, ": :"))
+		}
+		if len(tech.Parents) > 0 {
+			fmt.Fprintf(&out, ", under %s", strings.Join(tech.Parents, ", "))
+		}
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+// TrendsOutput is the structured content of the technology trends tool
+type TrendsOutput struct {
+	Trends []TechnologyTrend `json:"trends"`
+	Page
+}
+
+// ResultCount implements mcptool.Counted
+func (o TrendsOutput) ResultCount() int {
+	return len(o.Trends)
+}
+
+// renderTrends renders each trend as a sparkline followed by its buckets
+func renderTrends(trends []TechnologyTrend) string {
+	var out strings.Builder
+	for i, trend := range trends {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		fmt.Fprintf(&out, "**%s** %s %s, %s by %s", trend.Technology, trend.Sparkline, trend.Direction, plural(trend.Post_Count, "post"), plural(trend.Author_Count, "author"))
+		if trend.Truncated {
+			out.WriteString(", truncated")
+		}
+		out.WriteString("\n")
+		for _, bucket := range trend.Buckets {
+			fmt.Fprintf(&out, "- %s %s: %s, %s\n", trend.Interval, trendBucketLabel(bucket.Start, trend.Interval), plural(bucket.Post_Count, "post"), plural(bucket.Author_Count, "author"))
+		}
+	}
+	return out.String()
+}
+
+// trendBucketLabel names a bucket by its month, or by the Monday of its week
+func trendBucketLabel(start time.Time, interval string) string {
+	if interval == TrendMonth {
+		return start.Format("2006-01")
+	}
+	return "of " + start.Format(time.DateOnly)
+}
+
+// SkillsOutput is the structured content of the skills matrix tool
+type SkillsOutput struct {
+	SkillsMatrix
+	Page
+}
+
+// ResultCount implements mcptool.Counted
+func (o SkillsOutput) ResultCount() int {
+	return len(o.Rows)
+}
+
+// ProfileOutput is the structured content of the expertise profile tool
+type ProfileOutput struct {
+	ExpertiseProfile
+	Page
+}
+
+// ResultCount implements mcptool.Counted
+func (o ProfileOutput) ResultCount() int {
+	return len(o.Skills)
+}
+
+// renderProfile renders the inferred skills as a numbered Markdown list
+func renderProfile(profile ExpertiseProfile) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "Expertise of **%s** (@%s, %s), inferred from %s weighted against %s:\n", profile.User.Real_Name, profile.User.Slack_Name, profile.User.Slack_id, plural(profile.Post_Count, "post"), plural(profile.Corpus_Size, "post"))
+	if len(profile.Skills) == 0 {
+		out.WriteString("\nNo technology of the catalog is mentioned.\n")
+		return out.String()
+	}
+	for i, skill := range profile.Skills {
+		fmt.Fprintf(&out, "\n%d. **%s**, confidence %.2f, score %.2f, mentioned in %s\n", i+1, skill.Technology, skill.Confidence, skill.Score, plural(skill.Mention_Count, "post"))
+		for _, permalink := range skill.Examples {
+			fmt.Fprintf(&out, "   - %s\n", permalink)
+		}
+	}
+	return out.String()
+}
+
+// ChannelOutput is the content of the channel resources
+type ChannelOutput struct {
+	Channel_id   string        `json:"channel_id"`
+	Channel_Name string        `json:"channel_name"`
+	Posts        []MessageInfo `json:"posts"`
+}
+
+// renderChannel renders the channel then its recent posts
+func renderChannel(output ChannelOutput) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "Channel **#%s** (%s), latest posts:\n\n", output.Channel_Name, output.Channel_id)
+	out.WriteString(renderPosts(output.Posts))
+	return out.String()
+}
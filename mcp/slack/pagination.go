@@ -0,0 +1,368 @@
+package slack
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AlexisZankowitch/concept-insight/mcp/logging"
+	"github.com/slack-go/slack"
+	"github.com/strowk/foxy-contexts/pkg/mcp"
+	"go.uber.org/zap"
+)
+
+// searchPageSize is the number of matches requested per search.messages call.
+// It is the maximum allowed by Slack and must stay constant, cursors encode
+// positions relative to it.
+const searchPageSize = 100
+
+// maxLimit caps the number of results a single tool call can ask for
+const maxLimit = 1000
+
+// searchIterator walks the pages of a search.messages query, one match at a time
+type searchIterator struct {
+	client  SlackClient
+	limiter *RateLimiter
+	query   string
+	params slack.SearchParameters
+
+	matches  []slack.SearchMessage
+	offset   int
+	fetched  bool
+	lastPage bool
+
+	current slack.SearchMessage
+	err     error
+}
+
+// newSearchIterator creates an iterator over the query results, starting at cursor.
+// An empty cursor starts at the first result.
+func newSearchIterator(client SlackClient, limiter *RateLimiter, query string, params slack.SearchParameters, cursor string) (*searchIterator, error) {
+	page, offset, err := decodeSearchCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	params.Count = searchPageSize
+	params.Page = page
+
+	return &searchIterator{
+		client:  client,
+		limiter: limiter,
+		query:   query,
+		params:  params,
+		offset:  offset,
+	}, nil
+}
+
+// Next advances to the next match, fetching the next page when needed.
+// It returns false at the end of the results or on error, check Err.
+func (it *searchIterator) Next(ctx context.Context) bool {
+	for it.err == nil {
+		if it.fetched && it.offset < len(it.matches) {
+			it.current = it.matches[it.offset]
+			it.offset++
+			return true
+		}
+		if it.fetched {
+			if it.lastPage {
+				return false
+			}
+			it.params.Page++
+			it.offset = 0
+		}
+		it.fetch(ctx)
+	}
+	return false
+}
+
+func (it *searchIterator) fetch(ctx context.Context) {
+	logging.FromContext(ctx, it.limiter.logger).Debug("search", zap.String("query", it.query), zap.Int("page", it.params.Page))
+	var result *slack.SearchMessages
+	err := it.limiter.Do(ctx, "search.messages", func() error {
+		var err error
+		result, err = it.client.SearchMessagesContext(ctx, it.query, it.params)
+		return err
+	})
+	if err != nil {
+		it.err = err
+		return
+	}
+
+	it.matches = result.Matches
+	it.fetched = true
+	it.lastPage = len(result.Matches) == 0 || result.Paging.Page >= result.Paging.Pages
+}
+
+// Message returns the match the iterator is positioned on
+func (it *searchIterator) Message() slack.SearchMessage {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *searchIterator) Err() error {
+	return it.err
+}
+
+// Cursor returns a cursor pointing right after the last returned match,
+// or an empty string when there is nothing left to read.
+func (it *searchIterator) Cursor() string {
+	if !it.fetched {
+		return encodeSearchCursor(it.params.Page, it.offset)
+	}
+	if it.offset < len(it.matches) {
+		return encodeSearchCursor(it.params.Page, it.offset)
+	}
+	if it.lastPage {
+		return ""
+	}
+	return encodeSearchCursor(it.params.Page+1, 0)
+}
+
+// collectMessages reads up to limit matches posted in the time range from the iterator
+// and returns them together with the cursor to continue from.
+func collectMessages(ctx context.Context, it *searchIterator, window TimeRange, limit int) ([]MessageInfo, string, error) {
+	results := []MessageInfo{}
+	for len(results) < limit && it.Next(ctx) {
+		if message := toMessageInfo(it.Message()); window.Contains(message.Posted) {
+			results = append(results, message)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, "", err
+	}
+	return results, it.Cursor(), nil
+}
+
+// collectSearches reads up to limit matches posted in the time range from the searches, one search
+// after the other, and returns them together with the cursor to continue from. A match already returned by
+// an earlier search of the same call is skipped, it can come back on a later page.
+func collectSearches(ctx context.Context, client SlackClient, limiter *RateLimiter, queries []string, params slack.SearchParameters, window TimeRange, cursor string, limit int) ([]MessageInfo, string, error) {
+	index, searchCursor, err := decodeSearchesCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	if index > 0 && index >= len(queries) {
+		return nil, "", fmt.Errorf("invalid cursor %q", cursor)
+	}
+
+	results := []MessageInfo{}
+	seen := map[string]bool{}
+	for ; index < len(queries); index++ {
+		it, err := newSearchIterator(client, limiter, queries[index], params, searchCursor)
+		if err != nil {
+			return nil, "", err
+		}
+		searchCursor = ""
+		for len(results) < limit && it.Next(ctx) {
+			match := it.Message()
+			key := match.Channel.ID + "/" + match.Timestamp
+			if message := toMessageInfo(match); !seen[key] && window.Contains(message.Posted) {
+				seen[key] = true
+				results = append(results, message)
+			}
+		}
+		if err := it.Err(); err != nil {
+			return nil, "", err
+		}
+		if next := it.Cursor(); next != "" {
+			return results, encodeSearchesCursor(index, next), nil
+		}
+		if len(results) >= limit && index+1 < len(queries) {
+			return results, encodeSearchesCursor(index+1, ""), nil
+		}
+	}
+	return results, "", nil
+}
+
+// encodeSearchesCursor points at a position of the search at index,
+// the cursor of the first search is a plain search cursor
+func encodeSearchesCursor(index int, cursor string) string {
+	if index == 0 {
+		return cursor
+	}
+	raw := fmt.Sprintf("%d/%s", index, cursor)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeSearchesCursor(cursor string) (int, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid cursor %q", cursor)
+	}
+	rawIndex, searchCursor, ok := strings.Cut(string(raw), "/")
+	if !ok {
+		return 0, cursor, nil
+	}
+	index, err := strconv.Atoi(rawIndex)
+	if err != nil || index < 1 {
+		return 0, "", fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return index, searchCursor, nil
+}
+
+func encodeSearchCursor(page int, offset int) string {
+	raw := fmt.Sprintf("%d:%d", page, offset)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeSearchCursor(cursor string) (int, int, error) {
+	if cursor == "" {
+		return 1, 0, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	page, err := strconv.Atoi(parts[0])
+	if err != nil || page < 1 {
+		return 0, 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	offset, err := strconv.Atoi(parts[1])
+	if err != nil || offset < 0 || offset >= searchPageSize {
+		return 0, 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+
+	return page, offset, nil
+}
+
+// channelRestart is the cursor of a channel to search again from the start, such as a channel
+// that failed on its first page. It is not base64, so it is never the cursor of a page.
+const channelRestart = "-"
+
+// encodeChannelCursors packs the cursor of every channel that still has results
+// into one cursor for tools searching several channels
+func encodeChannelCursors(cursors map[string]string) string {
+	parts := []string{}
+	for channel, cursor := range cursors {
+		if cursor != "" {
+			parts = append(parts, channel+"="+cursor)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	sort.Strings(parts)
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(parts, ",")))
+}
+
+// decodeChannelCursors unpacks a cursor built by encodeChannelCursors.
+// Channels missing from the map have no results left, the restarted ones have an empty cursor.
+func decodeChannelCursors(cursor string) (map[string]string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor %q", cursor)
+	}
+
+	cursors := map[string]string{}
+	for _, part := range strings.Split(string(raw), ",") {
+		channel, channelCursor, ok := strings.Cut(part, "=")
+		if !ok || channel == "" {
+			return nil, fmt.Errorf("invalid cursor %q", cursor)
+		}
+		if channelCursor == channelRestart {
+			channelCursor = ""
+		}
+		cursors[channel] = channelCursor
+	}
+	return cursors, nil
+}
+
+// paginate returns the window of items selected by limit and an offset cursor,
+// for results that are already fully loaded in memory
+func paginate[T any](items []T, limit int, cursor string) ([]T, string, error) {
+	offset := 0
+	if cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid cursor %q", cursor)
+		}
+		offset, err = strconv.Atoi(string(raw))
+		if err != nil || offset < 0 {
+			return nil, "", fmt.Errorf("invalid cursor %q", cursor)
+		}
+	}
+
+	if offset >= len(items) {
+		return []T{}, "", nil
+	}
+	end := min(offset+limit, len(items))
+	next := ""
+	if end < len(items) {
+		next = base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(end)))
+	}
+	return items[offset:end], next, nil
+}
+
+// pageArgs extracts the optional limit and cursor tool arguments
+func pageArgs(args map[string]interface{}, defaultLimit int) (int, string, error) {
+	limit := defaultLimit
+	if value, ok := args["limit"]; ok && value != nil {
+		number, ok := value.(float64)
+		if !ok || number < 1 || number != float64(int(number)) {
+			return 0, "", fmt.Errorf("'limit' must be a positive integer")
+		}
+		limit = min(int(number), maxLimit)
+	}
+
+	cursor := ""
+	if value, ok := args["cursor"]; ok && value != nil {
+		cursor, ok = value.(string)
+		if !ok {
+			return 0, "", fmt.Errorf("'cursor' must be a string")
+		}
+	}
+
+	return limit, cursor, nil
+}
+
+// withPageProperties adds the limit and cursor arguments to a tool input schema
+func withPageProperties(properties map[string]map[string]interface{}, defaultLimit int) map[string]map[string]interface{} {
+	properties["limit"] = map[string]interface{}{
+		"type":        "integer",
+		"description": fmt.Sprintf("Maximum number of results to return (default %d, max %d)", defaultLimit, maxLimit),
+	}
+	properties["cursor"] = map[string]interface{}{
+		"type":        "string",
+		"description": "Cursor returned by a previous call, to fetch the next results",
+	}
+	return properties
+}
+
+// nextCursorContent tells the caller how to fetch the next results
+func nextCursorContent(cursor string) mcp.TextContent {
+	return mcp.TextContent{
+		Type: "text",
+		Text: fmt.Sprintf("More results are available, call again with cursor: %s", cursor),
+	}
+}
+
+// collectAllPages calls fetch with the cursor of the previous page until the last page, or until
+// maxItems items were read, in which case truncated is set
+func collectAllPages[T any](fetch func(cursor string) ([]T, string, error), maxItems int) ([]T, bool, error) {
+	items := []T{}
+	cursor := ""
+	for {
+		page, next, err := fetch(cursor)
+		if err != nil {
+			return nil, false, err
+		}
+		items = append(items, page...)
+		if next == "" {
+			return items, false, nil
+		}
+		if len(items) >= maxItems {
+			return items, true, nil
+		}
+		cursor = next
+	}
+}
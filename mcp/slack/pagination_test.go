@@ -0,0 +1,83 @@
+package slack
+
+import (
+	"testing"
+)
+
+func Test_SearchCursorRoundTrip(t *testing.T) {
+	page, offset, err := decodeSearchCursor(encodeSearchCursor(3, 42))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if page != 3 || offset != 42 {
+		t.Fatalf("expected page 3 offset 42, got page %d offset %d", page, offset)
+	}
+
+	page, offset, err = decodeSearchCursor("")
+	if err != nil || page != 1 || offset != 0 {
+		t.Fatalf("empty cursor must start at first page, got page %d offset %d err %v", page, offset, err)
+	}
+
+	if _, _, err := decodeSearchCursor("not a cursor"); err == nil {
+		t.Fatalf("expected an error for an invalid cursor")
+	}
+}
+
+func Test_ChannelCursors(t *testing.T) {
+	cursor := encodeChannelCursors(map[string]string{
+		"concept-tech":    encodeSearchCursor(2, 0),
+		"today-I-learned": "",
+	})
+
+	cursors, err := decodeChannelCursors(cursor)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(cursors) != 1 || cursors["concept-tech"] != encodeSearchCursor(2, 0) {
+		t.Fatalf("unexpected cursors %v", cursors)
+	}
+
+	if encodeChannelCursors(map[string]string{"concept-tech": ""}) != "" {
+		t.Fatalf("expected no cursor when every channel is exhausted")
+	}
+
+	cursors, err = decodeChannelCursors(encodeChannelCursors(map[string]string{"concept-tech": channelRestart}))
+	if err != nil || len(cursors) != 1 || cursors["concept-tech"] != "" {
+		t.Fatalf("expected concept-tech searched from the start, got %v, %v", cursors, err)
+	}
+}
+
+func Test_Paginate(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	page, next, err := paginate(items, 2, "")
+	if err != nil || len(page) != 2 || page[0] != 1 || next == "" {
+		t.Fatalf("unexpected first page %v next %q err %v", page, next, err)
+	}
+
+	page, next, err = paginate(items, 2, next)
+	if err != nil || len(page) != 2 || page[0] != 3 || next == "" {
+		t.Fatalf("unexpected second page %v next %q err %v", page, next, err)
+	}
+
+	page, next, err = paginate(items, 2, next)
+	if err != nil || len(page) != 1 || page[0] != 5 || next != "" {
+		t.Fatalf("unexpected last page %v next %q err %v", page, next, err)
+	}
+}
+
+func Test_PageArgs(t *testing.T) {
+	limit, cursor, err := pageArgs(map[string]interface{}{}, 20)
+	if err != nil || limit != 20 || cursor != "" {
+		t.Fatalf("unexpected defaults %d %q %v", limit, cursor, err)
+	}
+
+	limit, cursor, err = pageArgs(map[string]interface{}{"limit": float64(5000), "cursor": "abc"}, 20)
+	if err != nil || limit != maxLimit || cursor != "abc" {
+		t.Fatalf("unexpected values %d %q %v", limit, cursor, err)
+	}
+
+	if _, _, err := pageArgs(map[string]interface{}{"limit": float64(-1)}, 20); err == nil {
+		t.Fatalf("expected an error for a negative limit")
+	}
+}
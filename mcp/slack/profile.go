@@ -0,0 +1,326 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	// profileMaxPosts caps the posts of the user read from Slack search for a profile
+	profileMaxPosts = 2000
+	// profileCorpusPerChannel is the number of recent messages per channel sampled from Slack
+	// as the corpus of the channels that are not synced
+	profileCorpusPerChannel = 1000
+	// profileEvidenceScale is the number of mentions at which the evidence of a skill reaches 63%
+	profileEvidenceScale = 3.0
+	// profileExamples is the number of example permalinks returned per skill
+	profileExamples = 3
+)
+
+// InferredSkill is a technology a person writes about, inferred from the text of their posts
+type InferredSkill struct {
+	Technology string `json:"technology"`
+	// Score is the TF-IDF weight of the technology in the posts of the person
+	Score float64 `json:"score" description:"TF-IDF weight of the technology in the posts of the person, to rank the skills"`
+	// Confidence grows with the number of posts mentioning the technology and how specific it is to the person
+	Confidence    float64  `json:"confidence" description:"Between 0 and 1, grows with the number of mentions and how rare the technology is in the channels"`
+	Mention_Count int      `json:"mention_count" description:"Number of posts of the person mentioning the technology"`
+	Examples      []string `json:"examples" description:"Permalinks of posts mentioning the technology"`
+}
+
+// ExpertiseProfile ranks the technologies a person writes about
+type ExpertiseProfile struct {
+	User        ConceptUser     `json:"user"`
+	Post_Count  int             `json:"post_count" description:"Number of posts of the person analyzed"`
+	Corpus_Size int             `json:"corpus_size" description:"Number of posts of the channels the technologies are weighted against"`
+	Skills      []InferredSkill `json:"skills"`
+}
+
+// technologyTerms are the stemmed terms of the keywords of a technology. The aliases are names
+// to ask for the technology, such as go or ui, too common to be looked for in the text.
+type technologyTerms struct {
+	name    string
+	phrases [][]string
+}
+
+// ExpertiseProfile infers the technologies of the catalog the user writes about, from the keywords
+// in the text of their posts rather than from emoji tags. Each technology is weighted with TF-IDF: how many posts
+// of the user mention it, against how many posts of the channels mention it. Synced channels are
+// read from the store, the others are sampled from their recent history.
+func (s *SlackService) ExpertiseProfile(ctx context.Context, user ConceptUser, channels []Channel, window TimeRange) (ExpertiseProfile, error) {
+	corpus, err := s.profileCorpus(ctx, user.Slack_id, channels, window)
+	if err != nil {
+		return ExpertiseProfile{}, err
+	}
+	profile := inferExpertise(s.taxonomy, user.Slack_id, corpus)
+	profile.User = user
+	return profile, nil
+}
+
+// inferExpertise scores the technologies mentioned by the posts of the user in the corpus, best first
+func inferExpertise(taxonomy *Taxonomy, userId string, corpus []StoredMessage) ExpertiseProfile {
+	technologies := []technologyTerms{}
+	for _, tech := range taxonomy.Technologies() {
+		technologies = append(technologies, newTechnologyTerms(tech))
+	}
+
+	profile := ExpertiseProfile{Corpus_Size: len(corpus), Skills: []InferredSkill{}}
+	frequencies := map[string]int{}
+	mentions := map[string][]StoredMessage{}
+	for _, message := range corpus {
+		terms := tokenTerms(tokenize(message.Text))
+		own := strings.EqualFold(message.User, userId)
+		if own {
+			profile.Post_Count++
+		}
+		for _, tech := range technologies {
+			if !tech.mentionedIn(terms) {
+				continue
+			}
+			frequencies[tech.name]++
+			if own {
+				mentions[tech.name] = append(mentions[tech.name], message)
+			}
+		}
+	}
+
+	// the idf of a technology mentioned by a single post
+	maxIdf := math.Log(1 + float64(len(corpus)))
+	for name, posts := range mentions {
+		idf := math.Log(1 + float64(len(corpus))/float64(frequencies[name]))
+		score := (1 + math.Log(float64(len(posts)))) * idf
+		evidence := 1 - math.Exp(-float64(len(posts))/profileEvidenceScale)
+		confidence := evidence * (0.5 + 0.5*idf/maxIdf)
+
+		// the most discussed posts make the best examples
+		sort.SliceStable(posts, func(i, j int) bool {
+			return posts[i].Reply_Count+reactionCount(posts[i]) > posts[j].Reply_Count+reactionCount(posts[j])
+		})
+		examples := []string{}
+		for _, post := range posts[:min(len(posts), profileExamples)] {
+			if post.Permalink != "" {
+				examples = append(examples, post.Permalink)
+			}
+		}
+
+		profile.Skills = append(profile.Skills, InferredSkill{
+			Technology:    name,
+			Score:         math.Round(score*100) / 100,
+			Confidence:    math.Round(confidence*100) / 100,
+			Mention_Count: len(posts),
+			Examples:      examples,
+		})
+	}
+	sort.Slice(profile.Skills, func(i, j int) bool {
+		if profile.Skills[i].Score != profile.Skills[j].Score {
+			return profile.Skills[i].Score > profile.Skills[j].Score
+		}
+		return profile.Skills[i].Technology < profile.Skills[j].Technology
+	})
+	return profile
+}
+
+func newTechnologyTerms(tech Technology) technologyTerms {
+	terms := technologyTerms{name: tech.Name}
+	seen := map[string]bool{}
+	for _, text := range tech.Keywords {
+		phrase := tokenTerms(tokenize(text))
+		key := strings.Join(phrase, " ")
+		// a single letter, such as the c of c++, matches too many words
+		if len(phrase) == 0 || len(key) < 2 || seen[key] {
+			continue
+		}
+		seen[key] = true
+		terms.phrases = append(terms.phrases, phrase)
+	}
+	return terms
+}
+
+// mentionedIn tells if one of the phrases of the technology appears in the terms of a message
+func (t technologyTerms) mentionedIn(terms []string) bool {
+	for _, phrase := range t.phrases {
+		for start := 0; start+len(phrase) <= len(terms); start++ {
+			found := true
+			for n, term := range phrase {
+				if terms[start+n] != term {
+					found = false
+					break
+				}
+			}
+			if found {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func tokenTerms(tokens []token) []string {
+	terms := make([]string, len(tokens))
+	for i, token := range tokens {
+		terms[i] = token.term
+	}
+	return terms
+}
+
+func reactionCount(message StoredMessage) int {
+	count := 0
+	for _, n := range message.Reactions {
+		count += n
+	}
+	return count
+}
+
+// profileCorpus returns the messages of the channels posted in the time range, with every post of
+// the user found by Slack search in the channels that are not synced
+func (s *SlackService) profileCorpus(ctx context.Context, userId string, channels []Channel, window TimeRange) ([]StoredMessage, error) {
+	corpus := []StoredMessage{}
+	live := []Channel{}
+	for _, channel := range channels {
+		messages, ok := s.storedMessages([]string{channel.Name})
+		if !ok {
+			live = append(live, channel)
+			continue
+		}
+		for _, message := range messages {
+			if window.Contains(tsTime(message.Ts)) {
+				corpus = append(corpus, message)
+			}
+		}
+	}
+	if len(live) == 0 {
+		return corpus, nil
+	}
+
+	seen := map[string]bool{}
+	for _, channel := range live {
+		messages, err := s.recentHistory(ctx, channel, window, profileCorpusPerChannel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the history of %s: %w", channel.Name, err)
+		}
+		for _, message := range messages {
+			seen[message.User+"/"+message.Ts] = true
+		}
+		corpus = append(corpus, messages...)
+	}
+
+	// the sample may miss older posts of the user, search them
+	posts, err := s.searchUserPosts(ctx, userId, channelNames(live), window)
+	if err != nil {
+		return nil, err
+	}
+	for _, post := range posts {
+		if !seen[post.Slack_id+"/"+post.Ts] {
+			corpus = append(corpus, StoredMessage{Ts: post.Ts, User: post.Slack_id, Text: post.Message, Permalink: post.Permalink})
+		}
+	}
+	return corpus, nil
+}
+
+// recentHistory returns up to limit of the latest top-level messages of a channel posted in the time range
+func (s *SlackService) recentHistory(ctx context.Context, channel Channel, window TimeRange, limit int) ([]StoredMessage, error) {
+	workspaceURL, err := s.workspaceURL(ctx)
+	if err != nil {
+		return nil, err
+	}
+	params := &slack.GetConversationHistoryParameters{
+		ChannelID: channel.ID,
+		Inclusive: true,
+		Limit:     historyPageSize,
+	}
+	if !window.After.IsZero() {
+		params.Oldest = fmt.Sprintf("%d.000000", window.After.Unix())
+	}
+	if !window.Before.IsZero() {
+		params.Latest = fmt.Sprintf("%d.000000", window.Before.Unix())
+	}
+
+	messages := []StoredMessage{}
+	for len(messages) < limit {
+		var history *slack.GetConversationHistoryResponse
+		err := s.limiter.Do(ctx, "conversations.history", func() error {
+			var err error
+			history, err = s.client.GetConversationHistoryContext(ctx, params)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range history.Messages {
+			if isUserMessage(msg) && window.Contains(tsTime(msg.Timestamp)) {
+				messages = append(messages, toStoredMessage(channel, workspaceURL, msg))
+			}
+		}
+		if !history.HasMore || history.ResponseMetaData.NextCursor == "" {
+			break
+		}
+		params.Cursor = history.ResponseMetaData.NextCursor
+	}
+	return messages, nil
+}
+
+// searchUserPosts returns the posts of the user in the channels found by Slack search, as posted
+func (s *SlackService) searchUserPosts(ctx context.Context, userId string, channels []string, window TimeRange) ([]MessageInfo, error) {
+	params := slack.SearchParameters{
+		Sort:          "timestamp",
+		SortDirection: "desc",
+		Highlight:     false,
+	}
+	query := NewSearchQuery().From(userId)
+	for _, channel := range channels {
+		query.In(channel)
+	}
+	search, err := window.addTo(query).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	posts, _, err := collectAllPages(func(cursor string) ([]MessageInfo, string, error) {
+		it, err := newSearchIterator(s.client, s.limiter, search, params, cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		return collectMessages(ctx, it, window, maxLimit)
+	}, profileMaxPosts)
+	return posts, err
+}
+
+// resolveUser finds the user of a Slack id, handle or name. A name must match a single user,
+// or one of them exactly.
+func (s *SlackService) resolveUser(ctx context.Context, search string) (ConceptUser, error) {
+	if user, ok, err := s.users.Get(ctx, search); err != nil || ok {
+		return user, err
+	}
+	matches, err := s.users.Search(ctx, search)
+	if err != nil {
+		return ConceptUser{}, err
+	}
+	if len(matches) == 0 {
+		return ConceptUser{}, fmt.Errorf("no user matches '%s'", search)
+	}
+	exact := []ConceptUser{}
+	for _, user := range matches {
+		for _, name := range []string{user.Slack_Name, user.Real_Name, user.Display_Name} {
+			if strings.EqualFold(strings.TrimSpace(search), name) {
+				exact = append(exact, user)
+				break
+			}
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	if len(exact) == 1 {
+		return exact[0], nil
+	}
+	names := []string{}
+	for _, user := range matches[:min(len(matches), 5)] {
+		names = append(names, fmt.Sprintf("%s (%s)", user.Real_Name, user.Slack_id))
+	}
+	return ConceptUser{}, fmt.Errorf("'%s' matches %s: %s, give a Slack id", search, plural(len(matches), "user"), strings.Join(names, ", "))
+}
@@ -0,0 +1,140 @@
+package slack
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/strowk/foxy-contexts/pkg/mcp"
+)
+
+func Test_TechnologyTermsMentionedIn(t *testing.T) {
+	terms := newTechnologyTerms(Technology{Name: "nextjs", Aliases: []string{"next"}, Keywords: []string{"nextjs", "next.js", "c"}})
+	if len(terms.phrases) != 2 {
+		t.Fatalf("expected the alias and the single letter keyword to be ignored, got %v", terms.phrases)
+	}
+
+	nextjs := newTechnologyTerms(Technology{Name: "nextjs", Keywords: []string{"nextjs", "next.js"}})
+	for text, expected := range map[string]bool{
+		"We moved to Next.js 14":           true,
+		"nextjs middlewares are confusing": true,
+		"see you next week":                false,
+		"the js of next sprint":            false,
+	} {
+		if mentioned := nextjs.mentionedIn(tokenTerms(tokenize(text))); mentioned != expected {
+			t.Errorf("%q: expected %v, got %v", text, expected, mentioned)
+		}
+	}
+}
+
+func Test_InferExpertise(t *testing.T) {
+	taxonomy := newTestTaxonomy(t)
+	corpus := []StoredMessage{
+		// go is an alias and not a keyword, the reaction is not an evidence either
+		{User: "U1", Text: "Our Go services share a generic repository", Permalink: "p1", Reactions: map[string]int{"gopher": 1}},
+		{User: "U1", Text: "Our goroutines leak, golang traces helped", Permalink: "p10"},
+		{User: "U1", Text: "golang 1.22 fixed the loop variable", Permalink: "p2", Reply_Count: 4},
+		{User: "U1", Text: "Deployed the golang worker", Permalink: "p3"},
+		{User: "U1", Text: "Tried Next.js for the docs site", Permalink: "p4"},
+		{User: "U1", Text: "Lunch anyone?", Permalink: "p5"},
+		{User: "U2", Text: "React server components are great", Permalink: "p6"},
+		{User: "U2", Text: "Next.js app router migration done", Permalink: "p7"},
+		{User: "U3", Text: "Next.js caching bit us again", Permalink: "p8"},
+		{User: "U3", Text: "Anyone using React Query?", Permalink: "p9"},
+	}
+
+	profile := inferExpertise(taxonomy, "U1", corpus)
+	if profile.Post_Count != 6 || profile.Corpus_Size != 10 {
+		t.Fatalf("unexpected counts %+v", profile)
+	}
+	if len(profile.Skills) != 2 {
+		t.Fatalf("expected golang and nextjs, got %+v", profile.Skills)
+	}
+	golang, nextjs := profile.Skills[0], profile.Skills[1]
+	if golang.Technology != "golang" || golang.Mention_Count != 3 || nextjs.Technology != "nextjs" || nextjs.Mention_Count != 1 {
+		t.Fatalf("expected golang before nextjs, got %+v", profile.Skills)
+	}
+	// nextjs is mentioned by everyone, golang only by U1
+	if golang.Score <= nextjs.Score || golang.Confidence <= nextjs.Confidence || golang.Confidence > 1 || nextjs.Confidence <= 0 {
+		t.Fatalf("unexpected scores %+v", profile.Skills)
+	}
+	if strings.Join(golang.Examples, ",") != "p2,p10,p3" {
+		t.Fatalf("expected the discussed post first, got %v", golang.Examples)
+	}
+
+	if profile := inferExpertise(taxonomy, "U9", corpus); profile.Post_Count != 0 || len(profile.Skills) != 0 {
+		t.Fatalf("expected an empty profile, got %+v", profile)
+	}
+}
+
+func Test_InferExpertiseIgnoresCommonWords(t *testing.T) {
+	taxonomy, err := LoadTaxonomy("")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	corpus := []StoredMessage{
+		{User: "U1", Text: "I'm going back to the office, let's go grab lunch"},
+		{User: "U1", Text: "The front desk has the data of the ops team, ping me on the new ui"},
+	}
+
+	// go, back, front, data, ops and ui are aliases of the catalog, not keywords
+	if profile := inferExpertise(taxonomy, "U1", corpus); len(profile.Skills) != 0 {
+		t.Fatalf("expected no skills, got %+v", profile.Skills)
+	}
+}
+
+func Test_SlackResolveUser(t *testing.T) {
+	s, _ := newTestService(t, loadFixtures(t))
+
+	for search, expected := range map[string]string{
+		"U02MARIE01":   "U02MARIE01",
+		"linus":        "U03LINUS01",
+		"Marie Curie":  "U02MARIE01",
+		"zankowitch":   "U7D3Q7N8Y",
+		" u03linus01 ": "U03LINUS01",
+	} {
+		user, err := s.resolveUser(context.Background(), search)
+		if err != nil || user.Slack_id != expected {
+			t.Errorf("%q: expected %s, got %+v, %v", search, expected, user, err)
+		}
+	}
+	for search, expected := range map[string]string{
+		"nobody": "no user matches",
+		"i":      "give a Slack id",
+	} {
+		if _, err := s.resolveUser(context.Background(), search); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected an error containing %q, got %v", search, expected, err)
+		}
+	}
+}
+
+func Test_GetUserExpertiseProfileTool(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	s.UseTaxonomy(newTestTaxonomy(t))
+	tool := NewGetUserExpertiseProfile(s, newTestChannels(t, s))
+
+	result := tool.CallStructured(context.Background(), map[string]interface{}{"user": "alexis"})
+	if *result.IsError {
+		t.Fatalf("unexpected error result %+v", result)
+	}
+	output, ok := result.StructuredContent.(ProfileOutput)
+	if !ok || output.User.Slack_id != "U7D3Q7N8Y" {
+		t.Fatalf("expected the profile of alexis, got %+v", result.StructuredContent)
+	}
+	// the post in #random is not part of the channels
+	if output.Post_Count != 2 || len(output.Skills) != 1 || output.Skills[0].Technology != "golang" || output.Skills[0].Mention_Count != 2 {
+		t.Fatalf("expected golang mentioned in 2 posts, got %+v", output.ExpertiseProfile)
+	}
+	if calls := server.Calls("conversations.history"); calls != 2 {
+		t.Fatalf("expected the history of each channel to be sampled, got %d calls", calls)
+	}
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok || !strings.Contains(text.Text, "1. **golang**") {
+		t.Fatalf("expected the profile rendered as text, got %+v", result.Content[0])
+	}
+
+	result = tool.CallStructured(context.Background(), map[string]interface{}{"user": "nobody"})
+	if !*result.IsError {
+		t.Fatalf("expected an unknown user error, got %+v", result)
+	}
+}
@@ -0,0 +1,119 @@
+package slack
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// emojiPattern matches Slack emoji names, such as golang or +1
+	emojiPattern = regexp.MustCompile(`^[a-z0-9_+'-]{1,100}$`)
+	// channelPattern matches Slack channel names
+	channelPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,79}$`)
+	// userPattern matches Slack user ids
+	userPattern = regexp.MustCompile(`^[UW][A-Z0-9]{2,20}$`)
+	// modifierPattern matches the search modifiers of free text, such as in: or -from:
+	modifierPattern = regexp.MustCompile(`^-?[A-Za-z_]+:`)
+)
+
+// SearchQuery builds a Slack search query from typed clauses, so that tool arguments can only
+// add what their clause allows. The first invalid clause fails the query, see Build.
+type SearchQuery struct {
+	clauses []string
+	err     error
+}
+
+// NewSearchQuery creates an empty query
+func NewSearchQuery() *SearchQuery {
+	return &SearchQuery{}
+}
+
+// Has matches the messages with a reaction or an emoji, the name is given with or without colons
+func (q *SearchQuery) Has(emoji string) *SearchQuery {
+	emoji = strings.Trim(emoji, ":")
+	if !emojiPattern.MatchString(emoji) {
+		return q.fail(fmt.Errorf("invalid emoji name %q", emoji))
+	}
+	return q.add(fmt.Sprintf("has::%s:", emoji))
+}
+
+// In matches the messages of a channel, several In match any of the channels
+func (q *SearchQuery) In(channel string) *SearchQuery {
+	channel = strings.TrimPrefix(channel, "#")
+	if !channelPattern.MatchString(channel) {
+		return q.fail(fmt.Errorf("invalid channel name %q", channel))
+	}
+	return q.add("in:#" + channel)
+}
+
+// From matches the messages posted by a user id
+func (q *SearchQuery) From(userId string) *SearchQuery {
+	if !userPattern.MatchString(userId) {
+		return q.fail(fmt.Errorf("invalid slack user id %q", userId))
+	}
+	return q.add(fmt.Sprintf("from:<@%s>", userId))
+}
+
+// After matches the messages posted after the day of t, that day excluded
+func (q *SearchQuery) After(t time.Time) *SearchQuery {
+	return q.add("after:" + t.Format(time.DateOnly))
+}
+
+// Before matches the messages posted before the day of t, that day excluded
+func (q *SearchQuery) Before(t time.Time) *SearchQuery {
+	return q.add("before:" + t.Format(time.DateOnly))
+}
+
+// Text matches the messages containing the text, such as a keyword of a technology. A text of
+// several words is matched as a phrase. Modifiers, mentions, quotes and exclusions are stripped
+// from the text, so it can not change the scope of the search.
+func (q *SearchQuery) Text(text string) *SearchQuery {
+	words := []string{}
+	for _, word := range strings.Fields(text) {
+		// <@U123> mentions and <#C123> channels are Slack tokens, not words
+		if modifierPattern.MatchString(word) || strings.ContainsAny(word, "<>") {
+			continue
+		}
+		word = strings.TrimLeft(strings.Map(func(r rune) rune {
+			if strings.ContainsRune(`":*`, r) {
+				return -1
+			}
+			return r
+		}, word), "-")
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	switch len(words) {
+	case 0:
+		return q
+	case 1:
+		return q.add(words[0])
+	}
+	return q.add(`"` + strings.Join(words, " ") + `"`)
+}
+
+// Build returns the query, or the error of its first invalid clause
+func (q *SearchQuery) Build() (string, error) {
+	if q.err != nil {
+		return "", q.err
+	}
+	if len(q.clauses) == 0 {
+		return "", fmt.Errorf("empty search query")
+	}
+	return strings.Join(q.clauses, " "), nil
+}
+
+func (q *SearchQuery) add(clause string) *SearchQuery {
+	q.clauses = append(q.clauses, clause)
+	return q
+}
+
+func (q *SearchQuery) fail(err error) *SearchQuery {
+	if q.err == nil {
+		q.err = err
+	}
+	return q
+}
@@ -0,0 +1,56 @@
+package slack
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_SearchQuery(t *testing.T) {
+	day := time.Date(2024, 6, 10, 15, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		query    *SearchQuery
+		expected string
+		err      string
+	}{
+		{NewSearchQuery().Has(":golang:").In("#concept-tech"), "has::golang: in:#concept-tech", ""},
+		{NewSearchQuery().From("U7D3Q7N8Y").In("concept-tech").In("today-i-learned"), "from:<@U7D3Q7N8Y> in:#concept-tech in:#today-i-learned", ""},
+		{NewSearchQuery().Has("+1").After(day).Before(day.AddDate(0, 1, 0)), "has::+1: after:2024-06-10 before:2024-07-10", ""},
+		{NewSearchQuery().Text(`generics in:#hr-private -from:@ceo "go" <@U1> -wip`).In("concept-tech"), `"generics go wip" in:#concept-tech`, ""},
+		{NewSearchQuery().Text("react native").In("concept-tech"), `"react native" in:#concept-tech`, ""},
+		{NewSearchQuery().Text("next.js").In("concept-tech"), "next.js in:#concept-tech", ""},
+		{NewSearchQuery().Has("go: in:#hr-private from:@ceo"), "", "invalid emoji name"},
+		{NewSearchQuery().In("concept-tech in:#hr-private"), "", "invalid channel name"},
+		{NewSearchQuery().From("U7D3Q7N8Y in:#hr-private"), "", "invalid slack user id"},
+		{NewSearchQuery().From("alexis"), "", "invalid slack user id"},
+		// the first invalid clause fails the query
+		{NewSearchQuery().Has("bad emoji").In("bad channel"), "", "invalid emoji name"},
+		{NewSearchQuery().Text("in:#hr-private"), "", "empty search query"},
+	} {
+		query, err := test.query.Build()
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("expected an error containing %q, got %q, %v", test.err, query, err)
+			}
+			continue
+		}
+		if err != nil || query != test.expected {
+			t.Errorf("expected %q, got %q, %v", test.expected, query, err)
+		}
+	}
+}
+
+func Test_SlackSearchRejectsModifiers(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+
+	if _, _, err := s.GetTechonologyPost(context.Background(), "go: in:#random from:@alexis", "concept-tech", TimeRange{}, 20, ""); err == nil {
+		t.Fatalf("expected the technology to be rejected")
+	}
+	if _, _, err := s.GetPostByUser(context.Background(), "U7D3Q7N8Y in:#random", testChannels, TimeRange{}, 20, ""); err == nil {
+		t.Fatalf("expected the user id to be rejected")
+	}
+	if calls := server.Calls("search.messages"); calls != 0 {
+		t.Fatalf("expected no search, got %d", calls)
+	}
+}
@@ -0,0 +1,173 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/AlexisZankowitch/concept-insight/mcp/logging"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// Slack Web API rate limit tiers, see https://api.slack.com/apis/rate-limits
+var (
+	tier2 = rate.Every(time.Minute / 20)
+	tier3 = rate.Every(time.Minute / 50)
+)
+
+// methodTiers maps the Slack methods we call to their rate limit tier.
+// Methods missing from the map use tier 3.
+var methodTiers = map[string]rate.Limit{
+	"search.messages": tier2,
+	"users.list":      tier2,
+}
+
+const (
+	// rateLimitBurst is the number of calls to a method allowed back to back
+	rateLimitBurst = 3
+	// maxRateLimitRetries is the number of retries after Slack answered ratelimited
+	maxRateLimitRetries = 3
+	// defaultBaseBackoff is the first retry delay when Slack gives no Retry-After
+	defaultBaseBackoff = time.Second
+)
+
+// ThrottledError is returned when a Slack call could not be made within the
+// caller's deadline or the retry budget because of rate limiting
+type ThrottledError struct {
+	Method     string
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("slack method %s is rate limited, retry after %s", e.Method, e.RetryAfter.Round(time.Second))
+}
+
+// RateLimiter shares a request budget per Slack method between every caller
+// of the service, and retries the calls Slack rejected as rate limited
+type RateLimiter struct {
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+
+	tiers       map[string]rate.Limit
+	defaultTier rate.Limit
+	burst       int
+	maxRetries  int
+	baseBackoff time.Duration
+	// logger logs the calls made outside of a request
+	logger *zap.Logger
+}
+
+// NewRateLimiter creates a rate limiter using the Slack tiers of each method
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		limiters:    map[string]*rate.Limiter{},
+		tiers:       methodTiers,
+		defaultTier: tier3,
+		burst:       rateLimitBurst,
+		maxRetries:  maxRateLimitRetries,
+		baseBackoff: defaultBaseBackoff,
+		logger:      zap.NewNop(),
+	}
+}
+
+func (l *RateLimiter) limiter(method string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limiter, ok := l.limiters[method]
+	if !ok {
+		tier, ok := l.tiers[method]
+		if !ok {
+			tier = l.defaultTier
+		}
+		limiter = rate.NewLimiter(tier, l.burst)
+		l.limiters[method] = limiter
+	}
+	return limiter
+}
+
+// Do runs call once the method budget allows it, and retries it with a
+// jittered backoff while Slack answers ratelimited.
+// It gives up with a ThrottledError when the wait would exceed the context deadline.
+func (l *RateLimiter) Do(ctx context.Context, method string, call func() error) error {
+	limiter := l.limiter(method)
+
+	for attempt := 0; ; attempt++ {
+		reservation := limiter.Reserve()
+		delay := reservation.Delay()
+		if err := l.wait(ctx, delay); err != nil {
+			reservation.Cancel()
+			return l.throttled(err, method, delay)
+		}
+
+		start := time.Now()
+		err := call()
+		logging.FromContext(ctx, l.logger).Debug("slack call", zap.String("slack_method", method), zap.Duration("duration", time.Since(start)), zap.Error(err))
+		var rateLimited *slack.RateLimitedError
+		if !errors.As(err, &rateLimited) {
+			return err
+		}
+		if attempt >= l.maxRetries {
+			return &ThrottledError{Method: method, RetryAfter: rateLimited.RetryAfter}
+		}
+
+		backoff := l.backoff(attempt, rateLimited.RetryAfter)
+		logging.FromContext(ctx, l.logger).Warn("slack method rate limited", zap.String("slack_method", method), zap.Duration("backoff", backoff), zap.Int("attempt", attempt+1))
+		if err := l.wait(ctx, backoff); err != nil {
+			return l.throttled(err, method, backoff)
+		}
+	}
+}
+
+// Budget returns how many calls to the method the rate limit allows before the context deadline.
+// It is false when the calls are not bounded: without deadline or without limit.
+func (l *RateLimiter) Budget(ctx context.Context, method string) (int, bool) {
+	deadline, ok := ctx.Deadline()
+	limiter := l.limiter(method)
+	if !ok || limiter.Limit() == rate.Inf {
+		return 0, false
+	}
+	now := time.Now()
+	tokens := limiter.TokensAt(now) + float64(limiter.Limit())*max(deadline.Sub(now), 0).Seconds()
+	return max(int(tokens), 0), true
+}
+
+// backoff returns the delay before the next attempt: exponential from the base
+// backoff, never shorter than what Slack asked for, plus up to 50% jitter
+func (l *RateLimiter) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	backoff := max(l.baseBackoff<<attempt, retryAfter)
+	return backoff + rand.N(backoff/2+1)
+}
+
+// wait sleeps for delay, unless the context is done or its deadline is too close
+func (l *RateLimiter) wait(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return context.DeadlineExceeded
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// throttled turns a wait interrupted by the deadline into a ThrottledError,
+// cancellations are returned as they are
+func (l *RateLimiter) throttled(err error, method string, delay time.Duration) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &ThrottledError{Method: method, RetryAfter: delay}
+	}
+	return err
+}
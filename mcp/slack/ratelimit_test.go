@@ -0,0 +1,84 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/strowk/foxy-contexts/pkg/mcp"
+	"golang.org/x/time/rate"
+)
+
+func Test_RateLimitRetry(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	server.RateLimit("search.messages", 2)
+
+	r, _, err := s.GetTechonologyPost(context.Background(), "golang", "concept-tech", TimeRange{}, 20, "")
+	if err != nil {
+		t.Fatalf("expected the call to succeed after retries, got %v", err)
+	}
+	if len(r) != 1 {
+		t.Fatalf("expected 1 post, got %d", len(r))
+	}
+	// the emoji search then the keyword search
+	if calls := server.Calls("search.messages"); calls != 4 {
+		t.Fatalf("expected 2 rate limited calls and 2 successful calls, got %d calls", calls)
+	}
+}
+
+func Test_RateLimitGiveUp(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	server.RateLimit("search.messages", maxRateLimitRetries+1)
+
+	_, _, err := s.GetPostByUser(context.Background(), "U7D3Q7N8Y", testChannels, TimeRange{}, 200, "")
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("expected a throttled error, got %v", err)
+	}
+	if throttled.Method != "search.messages" {
+		t.Fatalf("unexpected method %s", throttled.Method)
+	}
+}
+
+func Test_RateLimitDeadline(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	s.limiter.tiers = map[string]rate.Limit{"search.messages": tier2}
+	// the emoji and keyword searches of a call
+	s.limiter.burst = 2
+
+	if _, _, err := s.GetTechonologyPost(context.Background(), "golang", "concept-tech", TimeRange{}, 20, ""); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// the budget is spent, the next token comes after the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, _, err := s.GetTechonologyPost(ctx, "golang", "concept-tech", TimeRange{}, 20, "")
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("expected a throttled error, got %v", err)
+	}
+	if calls := server.Calls("search.messages"); calls != 2 {
+		t.Fatalf("expected the throttled call not to reach Slack, got %d calls", calls)
+	}
+}
+
+func Test_RateLimitReportedInTool(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	tool := NewGetLastestPostsByUserId(s, newTestChannels(t, s))
+	server.RateLimit("search.messages", maxRateLimitRetries+1)
+
+	result := tool.CallStructured(context.Background(), map[string]interface{}{"slack_user_id": "U7D3Q7N8Y"})
+	if *result.IsError {
+		t.Fatalf("throttling must not be reported as an error, got %+v", result)
+	}
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok || !strings.Contains(text.Text, "rate limiting") {
+		t.Fatalf("expected a throttling notice, got %+v", result.Content)
+	}
+	if output, ok := result.StructuredContent.(PostsOutput); !ok || output.Notice != text.Text || output.Posts == nil {
+		t.Fatalf("expected the notice in the structured content, got %+v", result.StructuredContent)
+	}
+}
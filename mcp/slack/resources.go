@@ -0,0 +1,185 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/AlexisZankowitch/concept-insight/mcp/mcpresource"
+	"github.com/AlexisZankowitch/concept-insight/toolid"
+	"github.com/AlexisZankowitch/concept-insight/utils"
+	"github.com/strowk/foxy-contexts/pkg/mcp"
+)
+
+// URI templates of the Slack resources
+const (
+	UserResourceTemplate    = "slack://users/{id}"
+	ChannelResourceTemplate = "slack://channels/{name}"
+	MessageResourceTemplate = "slack://messages/{channel}/{ts}"
+)
+
+// defaultChannelPostsLimit is the number of recent posts of a channel resource
+const defaultChannelPostsLimit = 20
+
+// NewUserResource serves the profile of a user with the technologies inferred from their posts
+// in the channels of the expertise profile tool. Every active user is listed.
+func NewUserResource(slack *SlackService, channels *ChannelSet) mcpresource.Template {
+	return mcpresource.NewTemplate(
+		mcp.ResourceTemplate{
+			Name:        "slack-user",
+			UriTemplate: UserResourceTemplate,
+			Description: utils.Ptr("A Slack user by id, with the technologies inferred from their posts as in get-user-expertise-profile"),
+			MimeType:    utils.Ptr("text/markdown"),
+		},
+		func(ctx context.Context) ([]mcp.Resource, error) {
+			users, err := slack.Users().Users(ctx)
+			if err != nil {
+				return nil, err
+			}
+			resources := []mcp.Resource{}
+			for _, user := range users {
+				resources = append(resources, mcp.Resource{
+					Name:        user.Real_Name,
+					Uri:         "slack://users/" + url.PathEscape(user.Slack_id),
+					Description: utils.Ptr(fmt.Sprintf("Profile and expertise of @%s", user.Slack_Name)),
+					MimeType:    utils.Ptr("text/markdown"),
+				})
+			}
+			return resources, nil
+		},
+		func(ctx context.Context, uri string, vars map[string]string) (*mcp.ReadResourceResult, error) {
+			user, ok, err := slack.Users().Get(ctx, vars["id"])
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, fmt.Errorf("%w: no user with id %s", mcpresource.ErrNotFound, vars["id"])
+			}
+			profile, err := slack.ExpertiseProfile(ctx, user, channels.ForTool(toolid.ExpertiseProfile), TimeRange{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to build the expertise profile: %w", err)
+			}
+			if len(profile.Skills) > defaultProfileSkillsLimit {
+				profile.Skills = profile.Skills[:defaultProfileSkillsLimit]
+			}
+			return resourceContents(uri, renderProfile(profile), profile)
+		},
+	)
+}
+
+// NewChannelResource serves an allowed channel with its latest posts. Every allowed channel is listed.
+func NewChannelResource(slack *SlackService, channels *ChannelSet) mcpresource.Template {
+	return mcpresource.NewTemplate(
+		mcp.ResourceTemplate{
+			Name:        "slack-channel",
+			UriTemplate: ChannelResourceTemplate,
+			Description: utils.Ptr(fmt.Sprintf("An allowed Slack channel by name or id, with its %d latest posts", defaultChannelPostsLimit)),
+			MimeType:    utils.Ptr("text/markdown"),
+		},
+		func(ctx context.Context) ([]mcp.Resource, error) {
+			resources := []mcp.Resource{}
+			for _, channel := range channels.All() {
+				resources = append(resources, mcp.Resource{
+					Name:        "#" + channel.Name,
+					Uri:         "slack://channels/" + url.PathEscape(channel.Name),
+					Description: utils.Ptr(fmt.Sprintf("Latest posts of #%s", channel.Name)),
+					MimeType:    utils.Ptr("text/markdown"),
+				})
+			}
+			return resources, nil
+		},
+		func(ctx context.Context, uri string, vars map[string]string) (*mcp.ReadResourceResult, error) {
+			channel, err := channels.Lookup(vars["name"])
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", mcpresource.ErrNotFound, err)
+			}
+			posts, err := slack.RecentPosts(ctx, channel, defaultChannelPostsLimit)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the history of %s: %w", channel.Name, err)
+			}
+			output := ChannelOutput{Channel_id: channel.ID, Channel_Name: channel.Name, Posts: posts}
+			return resourceContents(uri, renderChannel(output), output)
+		},
+	)
+}
+
+// NewMessageResource serves a post of an allowed channel with the replies of its thread.
+// The messages are not listed.
+func NewMessageResource(slack *SlackService, channels *ChannelSet) mcpresource.Template {
+	return mcpresource.NewTemplate(
+		mcp.ResourceTemplate{
+			Name:        "slack-message",
+			UriTemplate: MessageResourceTemplate,
+			Description: utils.Ptr("A post by channel name or id and timestamp, with the replies of its thread as in get-thread"),
+			MimeType:    utils.Ptr("text/markdown"),
+		},
+		nil,
+		func(ctx context.Context, uri string, vars map[string]string) (*mcp.ReadResourceResult, error) {
+			channel, err := channels.Lookup(vars["channel"])
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", mcpresource.ErrNotFound, err)
+			}
+			thread, err := slack.GetThread(ctx, channel.ID, vars["ts"])
+			if errors.Is(err, ErrMessageNotFound) {
+				return nil, fmt.Errorf("%w: %v", mcpresource.ErrNotFound, err)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch the thread: %w", err)
+			}
+			return resourceContents(uri, renderThread(thread), thread)
+		},
+	)
+}
+
+// resourceContents returns a resource rendered as Markdown, then as JSON like the structured content of the tools
+func resourceContents(uri string, text string, structured interface{}) (*mcp.ReadResourceResult, error) {
+	encoded, err := json.Marshal(structured)
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []interface{}{
+			mcp.TextResourceContents{
+				Uri:      uri,
+				MimeType: utils.Ptr("text/markdown"),
+				Text:     text,
+			},
+			mcp.TextResourceContents{
+				Uri:      uri,
+				MimeType: utils.Ptr("application/json"),
+				Text:     string(encoded),
+			},
+		},
+	}, nil
+}
+
+// RecentPosts returns the latest top-level posts of a channel, newest first, from the store once synced
+func (s *SlackService) RecentPosts(ctx context.Context, channel Channel, limit int) ([]MessageInfo, error) {
+	messages := []StoredMessage{}
+	if stored, ok := s.storedMessages([]string{channel.Name}); ok {
+		for _, message := range stored {
+			if message.Thread_ts == "" || message.Thread_ts == message.Ts {
+				messages = append(messages, message)
+			}
+		}
+	} else {
+		var err error
+		messages, err = s.recentHistory(ctx, channel, TimeRange{}, limit)
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return tsLess(messages[j].Ts, messages[i].Ts)
+	})
+	if len(messages) > limit {
+		messages = messages[:limit]
+	}
+
+	posts := s.storedMessageInfos(ctx, messages)
+	s.renderMessages(ctx, posts)
+	return posts, nil
+}
@@ -0,0 +1,114 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AlexisZankowitch/concept-insight/mcp/mcpresource"
+	"github.com/strowk/foxy-contexts/pkg/fxctx"
+	"github.com/strowk/foxy-contexts/pkg/mcp"
+)
+
+// newTestResourceMux returns a mux of the Slack resources of the fixtures
+func newTestResourceMux(t *testing.T) *mcpresource.ResourceMux {
+	t.Helper()
+	s, _ := newTestService(t, loadFixtures(t))
+	s.UseTaxonomy(newTestTaxonomy(t))
+	channels := newTestChannels(t, s)
+	mux, err := mcpresource.NewResourceMux(fxctx.NewResourceMux(nil, nil), []mcpresource.Template{
+		NewUserResource(s, channels),
+		NewChannelResource(s, channels),
+		NewMessageResource(s, channels),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return mux
+}
+
+// readResource reads a resource, returning its Markdown and decoding its JSON into v
+func readResource(t *testing.T, mux *mcpresource.ResourceMux, uri string, v interface{}) string {
+	t.Helper()
+	result, err := mux.ReadResource(context.Background(), uri)
+	if err != nil {
+		t.Fatalf("unexpected error reading %s: %v", uri, err)
+	}
+	if len(result.Contents) != 2 {
+		t.Fatalf("expected Markdown and JSON contents, got %+v", result.Contents)
+	}
+	text := result.Contents[0].(mcp.TextResourceContents)
+	encoded := result.Contents[1].(mcp.TextResourceContents)
+	if text.Uri != uri || *text.MimeType != "text/markdown" || *encoded.MimeType != "application/json" {
+		t.Fatalf("unexpected contents %+v", result.Contents)
+	}
+	if err := json.Unmarshal([]byte(encoded.Text), v); err != nil {
+		t.Fatalf("invalid JSON content %v", err)
+	}
+	return text.Text
+}
+
+func Test_SlackResources(t *testing.T) {
+	mux := newTestResourceMux(t)
+
+	var profile ExpertiseProfile
+	text := readResource(t, mux, "slack://users/U7D3Q7N8Y", &profile)
+	if profile.User.Slack_Name != "alexis" || len(profile.Skills) != 1 || profile.Skills[0].Technology != "golang" || !strings.Contains(text, "1. **golang**") {
+		t.Fatalf("expected the expertise of alexis, got %+v %s", profile, text)
+	}
+
+	var channel ChannelOutput
+	text = readResource(t, mux, "slack://channels/%23concept-tech", &channel)
+	if channel.Channel_id != "C01CONCEPT" || len(channel.Posts) == 0 || !strings.Contains(text, "Channel **#concept-tech**") {
+		t.Fatalf("expected the posts of concept-tech, got %+v %s", channel, text)
+	}
+	for i, post := range channel.Posts {
+		if post.Thread_ts != "" && post.Thread_ts != post.Ts || i > 0 && tsLess(channel.Posts[i-1].Ts, post.Ts) {
+			t.Fatalf("expected the top-level posts newest first, got %+v", channel.Posts)
+		}
+	}
+
+	var thread Thread
+	text = readResource(t, mux, "slack://messages/C01CONCEPT/1718000000.000100", &thread)
+	if thread.Parent.Slack_Author_Name != "alexis" || len(thread.Replies) != 2 || !strings.HasPrefix(text, "Thread:") {
+		t.Fatalf("expected the thread of the post, got %+v %s", thread, text)
+	}
+}
+
+func Test_SlackResourcesNotFound(t *testing.T) {
+	mux := newTestResourceMux(t)
+
+	for _, uri := range []string{
+		"slack://users/U00NOBODY",
+		// random is not one of the channels
+		"slack://channels/random",
+		"slack://messages/random/1718400000.000500",
+		"slack://messages/concept-tech/1.000000",
+	} {
+		if _, err := mux.ReadResource(context.Background(), uri); !errors.Is(err, mcpresource.ErrNotFound) {
+			t.Errorf("expected %s not found, got %v", uri, err)
+		}
+	}
+}
+
+func Test_SlackResourcesList(t *testing.T) {
+	mux := newTestResourceMux(t)
+
+	resources, err := mux.GetResources(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	uris := map[string]bool{}
+	for _, resource := range resources {
+		uris[resource.Uri] = true
+	}
+	// the channels then the active users, the messages are not listed
+	if len(resources) != 5 || !uris["slack://channels/concept-tech"] || !uris["slack://users/U02MARIE01"] || uris["slack://users/U04GONE001"] {
+		t.Fatalf("unexpected resources %+v", resources)
+	}
+	if templates := mux.Templates(); len(templates) != 3 || templates[0].UriTemplate != ChannelResourceTemplate {
+		t.Fatalf("unexpected templates %+v", templates)
+	}
+}
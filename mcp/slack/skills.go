@@ -0,0 +1,292 @@
+package slack
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Skills matrix export formats
+const (
+	SkillsFormatCSV      = "csv"
+	SkillsFormatJSON     = "json"
+	SkillsFormatMarkdown = "markdown"
+)
+
+// SkillsFormats lists the export formats of a skills matrix
+var SkillsFormats = []string{SkillsFormatCSV, SkillsFormatJSON, SkillsFormatMarkdown}
+
+// skillsMaxPosts caps the posts read per search while building a skills matrix
+const skillsMaxPosts = 5000
+
+// SkillCell is the evidence that a person knows a technology
+type SkillCell struct {
+	Post_Count  int       `json:"post_count"`
+	Last_Posted time.Time `json:"last_posted"`
+	// Best_Permalink is the post with the most reactions and replies, the best match when none has any
+	Best_Permalink string `json:"best_permalink" description:"Permalink of the most discussed post, an example of the person's knowledge"`
+}
+
+// SkillRow holds the skills of a person, by technology name. Technologies without posts are absent.
+type SkillRow struct {
+	User   ConceptUser          `json:"user"`
+	Skills map[string]SkillCell `json:"skills"`
+}
+
+// SkillsMatrix crosses the people with the technologies they posted about
+type SkillsMatrix struct {
+	Technologies []string   `json:"technologies" description:"Columns of the matrix"`
+	Rows         []SkillRow `json:"rows" description:"People with at least one post, most posts first"`
+	Truncated    bool       `json:"truncated,omitempty" description:"Set when there were too many posts to count them all"`
+}
+
+// SkillsMatrix counts the posts of every person tagged with each technology in the channels.
+// The synced channels are read from the store. In the others each emoji and keyword is searched
+// once per channel, its posts counting for every technology it belongs to, such as a category
+// and the technologies under it. A matrix needing more searches than the rate limit allows
+// before the deadline is refused.
+func (s *SlackService) SkillsMatrix(ctx context.Context, technologies []string, channels []string, window TimeRange) (SkillsMatrix, error) {
+	matrix := SkillsMatrix{Technologies: []string{}, Rows: []SkillRow{}}
+	queries := []TechnologyQuery{}
+	columns := map[string]bool{}
+	for _, tech := range technologies {
+		query := s.taxonomy.Expand(tech)
+		if columns[query.Name] {
+			// an alias of a technology already in the matrix
+			continue
+		}
+		columns[query.Name] = true
+		matrix.Technologies = append(matrix.Technologies, query.Name)
+		queries = append(queries, query)
+	}
+
+	searches := map[string]bool{}
+	for _, channel := range channels {
+		if s.channelSynced(channel) {
+			continue
+		}
+		for _, query := range queries {
+			channelSearches, err := technologySearches(query, channel, window)
+			if err != nil {
+				return SkillsMatrix{}, err
+			}
+			for _, search := range channelSearches {
+				searches[search] = true
+			}
+		}
+	}
+	if budget, ok := s.limiter.Budget(ctx, "search.messages"); ok && len(searches) > budget {
+		return SkillsMatrix{}, fmt.Errorf("the matrix needs %d Slack searches in the channels that are not synced, the rate limit allows %d before the timeout: give fewer technologies or channels", len(searches), budget)
+	}
+
+	searched := map[string][]MessageInfo{}
+	rows := map[string]*SkillRow{}
+	bests := map[string]MessageInfo{}
+	for _, query := range queries {
+		posts := []MessageInfo{}
+		for _, channel := range channels {
+			channelPosts, truncated, err := s.skillPosts(ctx, query, channel, window, searched)
+			if err != nil {
+				return SkillsMatrix{}, fmt.Errorf("failed to search %s in %s: %w", query.Name, channel, err)
+			}
+			posts = append(posts, channelPosts...)
+			matrix.Truncated = matrix.Truncated || truncated
+		}
+
+		for _, post := range posts {
+			if post.Slack_id == "" {
+				continue
+			}
+			row, ok := rows[post.Slack_id]
+			if !ok {
+				row = &SkillRow{
+					User:   ConceptUser{Slack_id: post.Slack_id, Slack_Name: post.Slack_Author_Name},
+					Skills: map[string]SkillCell{},
+				}
+				rows[post.Slack_id] = row
+			}
+			cell := row.Skills[query.Name]
+			cell.Post_Count++
+			if post.Posted.After(cell.Last_Posted) {
+				cell.Last_Posted = post.Posted
+			}
+			key := post.Slack_id + "/" + query.Name
+			if best, ok := bests[key]; !ok || betterExample(post, best) {
+				bests[key] = post
+				cell.Best_Permalink = post.Permalink
+			}
+			row.Skills[query.Name] = cell
+		}
+	}
+
+	for id, row := range rows {
+		user, ok, err := s.users.Get(ctx, id)
+		if err != nil {
+			// keep the row when the directory is unavailable, with the name found in the posts
+			s.log(ctx).Warn("could not resolve user", zap.String("slack_id", id), zap.Error(err))
+			matrix.Rows = append(matrix.Rows, *row)
+			continue
+		}
+		if !ok {
+			// people who left the workspace are not staffed
+			continue
+		}
+		row.User = user
+		matrix.Rows = append(matrix.Rows, *row)
+	}
+	sort.Slice(matrix.Rows, func(i, j int) bool {
+		a, b := matrix.Rows[i].postCount(), matrix.Rows[j].postCount()
+		if a != b {
+			return a > b
+		}
+		return matrix.Rows[i].User.Slack_id < matrix.Rows[j].User.Slack_id
+	})
+	return matrix, nil
+}
+
+// skillPosts returns the posts of a technology in a channel. The store answers for the synced
+// channels, the other channels are searched once per emoji and once per searched keyword, the results
+// being kept in searched.
+func (s *SlackService) skillPosts(ctx context.Context, query TechnologyQuery, channel string, window TimeRange, searched map[string][]MessageInfo) ([]MessageInfo, bool, error) {
+	searches, err := technologySearches(query, channel, window)
+	if err != nil {
+		return nil, false, err
+	}
+	if posts, ok := s.storedTechnologyPosts(ctx, query, channel, window); ok {
+		return posts, false, nil
+	}
+
+	posts := []MessageInfo{}
+	truncated := false
+	for _, search := range searches {
+		results, ok := searched[search]
+		if !ok {
+			var searchTruncated bool
+			results, searchTruncated, err = collectAllPages(func(cursor string) ([]MessageInfo, string, error) {
+				return s.searchPosts(ctx, []string{search}, window, maxLimit, cursor)
+			}, skillsMaxPosts)
+			if err != nil {
+				return nil, false, err
+			}
+			searched[search] = results
+			truncated = truncated || searchTruncated
+		}
+		posts = append(posts, results...)
+	}
+	// a post tagged with several emojis of the technology is counted once
+	return uniquePosts(posts), truncated, nil
+}
+
+// betterExample reports whether post is a better example than best: more reactions and replies,
+// then a better match
+func betterExample(post MessageInfo, best MessageInfo) bool {
+	if a, b := post.Reaction_Count+post.Reply_Count, best.Reaction_Count+best.Reply_Count; a != b {
+		return a > b
+	}
+	return post.Score > best.Score
+}
+
+func (r SkillRow) postCount() int {
+	count := 0
+	for _, cell := range r.Skills {
+		count += cell.Post_Count
+	}
+	return count
+}
+
+// Write exports the matrix in one of the SkillsFormats
+func (m SkillsMatrix) Write(w io.Writer, format string) error {
+	switch format {
+	case SkillsFormatCSV:
+		return m.WriteCSV(w)
+	case SkillsFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(m)
+	case SkillsFormatMarkdown:
+		_, err := io.WriteString(w, m.Markdown())
+		return err
+	}
+	return fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(SkillsFormats, ", "))
+}
+
+// WriteCSV exports the matrix with one line per person and three columns per technology:
+// the post count, the last post date and the best example
+func (m SkillsMatrix) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	header := []string{"slack_id", "slack_name", "real_name"}
+	for _, tech := range m.Technologies {
+		header = append(header, tech+"_posts", tech+"_last_posted", tech+"_example")
+	}
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	for _, row := range m.Rows {
+		record := []string{row.User.Slack_id, row.User.Slack_Name, row.User.Real_Name}
+		for _, tech := range m.Technologies {
+			cell, ok := row.Skills[tech]
+			if !ok {
+				record = append(record, "0", "", "")
+				continue
+			}
+			record = append(record, strconv.Itoa(cell.Post_Count), cell.Last_Posted.Format(time.DateOnly), cell.Best_Permalink)
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// Markdown renders the matrix as a table, each cell links its post count to the best example
+func (m SkillsMatrix) Markdown() string {
+	if len(m.Rows) == 0 {
+		return "No posts found."
+	}
+	var out strings.Builder
+	out.WriteString("| Person |")
+	for _, tech := range m.Technologies {
+		fmt.Fprintf(&out, " %s |", tech)
+	}
+	out.WriteString("\n|---|")
+	out.WriteString(strings.Repeat("---|", len(m.Technologies)))
+	out.WriteString("\n")
+	for _, row := range m.Rows {
+		name := row.User.Real_Name
+		if name == "" {
+			name = row.User.Slack_Name
+		}
+		fmt.Fprintf(&out, "| %s (@%s) |", markdownCell(name), markdownCell(row.User.Slack_Name))
+		for _, tech := range m.Technologies {
+			cell, ok := row.Skills[tech]
+			if !ok {
+				out.WriteString(" |")
+				continue
+			}
+			if cell.Best_Permalink != "" {
+				fmt.Fprintf(&out, " [%d](%s), %s |", cell.Post_Count, cell.Best_Permalink, cell.Last_Posted.Format(time.DateOnly))
+			} else {
+				fmt.Fprintf(&out, " %d, %s |", cell.Post_Count, cell.Last_Posted.Format(time.DateOnly))
+			}
+		}
+		out.WriteString("\n")
+	}
+	if m.Truncated {
+		out.WriteString("\nThere were too many posts to count them all, narrow the period or the technologies.\n")
+	}
+	return out.String()
+}
+
+// markdownCell escapes the pipes that would split a table cell
+func markdownCell(text string) string {
+	return strings.ReplaceAll(text, "|", `\|`)
+}
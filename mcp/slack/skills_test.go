@@ -0,0 +1,173 @@
+package slack
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/strowk/foxy-contexts/pkg/mcp"
+	"golang.org/x/time/rate"
+)
+
+func Test_SlackSkillsMatrix(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	s.UseTaxonomy(newTestTaxonomy(t))
+
+	matrix, err := s.SkillsMatrix(context.Background(), []string{"backend", "golang", "go", "frontend", "react"}, testChannels, TimeRange{})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// go is an alias of golang
+	if strings.Join(matrix.Technologies, ",") != "backend,golang,frontend,react" {
+		t.Fatalf("unexpected columns %v", matrix.Technologies)
+	}
+	// backend and golang share the searches of the golang emojis, frontend and react the ones of react,
+	// each technology searches its keywords
+	if calls := server.Calls("search.messages"); calls != 16 {
+		t.Fatalf("expected each search to run once, got %d", calls)
+	}
+
+	if len(matrix.Rows) != 2 {
+		t.Fatalf("expected 2 people, got %+v", matrix.Rows)
+	}
+	marie, alexis := matrix.Rows[0], matrix.Rows[1]
+	if marie.User.Real_Name != "Marie Curie" || len(marie.Skills) != 4 || marie.Skills["react"].Post_Count != 1 {
+		t.Fatalf("unexpected row %+v", marie)
+	}
+	golang := alexis.Skills["golang"]
+	if alexis.User.Real_Name != "Alexis Zankowitch" || len(alexis.Skills) != 2 || golang.Post_Count != 2 {
+		t.Fatalf("unexpected row %+v", alexis)
+	}
+	if !golang.Last_Posted.Equal(tsTime("1718500000.000600")) || golang.Best_Permalink == "" {
+		t.Fatalf("unexpected cell %+v", golang)
+	}
+}
+
+func Test_SkillsMatrixExport(t *testing.T) {
+	matrix := SkillsMatrix{
+		Technologies: []string{"golang", "react"},
+		Rows: []SkillRow{{
+			User: ConceptUser{Slack_id: "U1", Slack_Name: "marie.curie", Real_Name: "Marie | Curie"},
+			Skills: map[string]SkillCell{
+				"golang": {Post_Count: 3, Last_Posted: time.Date(2024, 6, 16, 9, 0, 0, 0, time.UTC), Best_Permalink: "https://concept.slack.com/archives/C1/p1"},
+			},
+		}},
+	}
+
+	var csv strings.Builder
+	if err := matrix.Write(&csv, SkillsFormatCSV); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := "slack_id,slack_name,real_name,golang_posts,golang_last_posted,golang_example,react_posts,react_last_posted,react_example\n" +
+		"U1,marie.curie,Marie | Curie,3,2024-06-16,https://concept.slack.com/archives/C1/p1,0,,\n"
+	if csv.String() != expected {
+		t.Fatalf("unexpected CSV\n%s", csv.String())
+	}
+
+	markdown := matrix.Markdown()
+	if !strings.Contains(markdown, "| Person | golang | react |\n|---|---|---|\n") ||
+		!strings.Contains(markdown, `| Marie \| Curie (@marie.curie) | [3](https://concept.slack.com/archives/C1/p1), 2024-06-16 | |`) {
+		t.Fatalf("unexpected Markdown\n%s", markdown)
+	}
+
+	var json strings.Builder
+	if err := matrix.Write(&json, SkillsFormatJSON); err != nil || !strings.Contains(json.String(), `"post_count": 3`) {
+		t.Fatalf("unexpected JSON %s, %v", json.String(), err)
+	}
+	if err := matrix.Write(&json, "xlsx"); err == nil {
+		t.Fatalf("expected an unknown format error")
+	}
+}
+
+func Test_SkillsMatrixTool(t *testing.T) {
+	s, _ := newTestService(t, loadFixtures(t))
+	s.UseTaxonomy(newTestTaxonomy(t))
+	tool := NewSkillsMatrix(s, newTestChannels(t, s))
+
+	result := tool.CallStructured(context.Background(), map[string]interface{}{
+		"technologies": []interface{}{"golang"},
+		"format":       "csv",
+		"since":        "1y",
+	})
+	if *result.IsError {
+		t.Fatalf("unexpected error result %+v", result)
+	}
+	// the fixtures are older than a year
+	output, ok := result.StructuredContent.(SkillsOutput)
+	if !ok || len(output.Rows) != 0 {
+		t.Fatalf("expected an empty matrix, got %+v", result.StructuredContent)
+	}
+
+	result = tool.CallStructured(context.Background(), map[string]interface{}{})
+	if *result.IsError {
+		t.Fatalf("unexpected error result %+v", result)
+	}
+	output, ok = result.StructuredContent.(SkillsOutput)
+	if !ok || len(output.Technologies) != 5 || len(output.Rows) != 2 {
+		t.Fatalf("expected the whole catalog, got %+v", result.StructuredContent)
+	}
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok || !strings.HasPrefix(text.Text, "| Person | backend | frontend | golang | nextjs | react |") {
+		t.Fatalf("expected a Markdown table, got %+v", result.Content[0])
+	}
+
+	result = tool.CallStructured(context.Background(), map[string]interface{}{"format": "xlsx"})
+	if !*result.IsError {
+		t.Fatalf("expected an unknown format error, got %+v", result)
+	}
+}
+
+func Test_SlackSkillsMatrixCatalogSearches(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	taxonomy, err := LoadTaxonomy("")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	s.UseTaxonomy(taxonomy)
+	technologies := []string{}
+	emojis, keywords := map[string]bool{}, map[string]bool{}
+	for _, tech := range taxonomy.Technologies() {
+		technologies = append(technologies, tech.Name)
+		query := taxonomy.Expand(tech.Name)
+		for _, emoji := range query.Emojis {
+			emojis[emoji] = true
+		}
+		for _, keyword := range query.SearchedKeywords {
+			keywords[keyword] = true
+		}
+	}
+
+	if _, err := s.SkillsMatrix(context.Background(), technologies, []string{"concept-tech"}, TimeRange{}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// each emoji and keyword of the catalog is searched once, whatever the technologies it belongs to
+	if calls := server.Calls("search.messages"); calls != len(emojis)+len(keywords) {
+		t.Fatalf("expected %d searches for %d technologies, got %d", len(emojis)+len(keywords), len(technologies), calls)
+	}
+}
+
+func Test_SlackSkillsMatrixOverBudget(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	s.limiter.tiers = map[string]rate.Limit{"search.messages": tier2}
+	taxonomy, err := LoadTaxonomy("")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	s.UseTaxonomy(taxonomy)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	_, err = s.SkillsMatrix(ctx, []string{"devops", "frontend", "backend", "data", "ai"}, testChannels, TimeRange{})
+	if err == nil || !strings.Contains(err.Error(), "give fewer technologies or channels") {
+		t.Fatalf("expected the matrix refused, got %v", err)
+	}
+	if calls := server.Calls("search.messages"); calls != 0 {
+		t.Fatalf("expected no search, got %d", calls)
+	}
+
+	// the searches of a technology in a channel fit
+	if _, err := s.SkillsMatrix(ctx, []string{"golang"}, []string{"concept-tech"}, TimeRange{}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -1,86 +1,268 @@
 package slack
 
 import (
-	"fmt"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/AlexisZankowitch/concept-insight/config"
+	"github.com/AlexisZankowitch/concept-insight/mcp/logging"
 	"github.com/slack-go/slack"
+	"go.uber.org/zap"
 )
 
+// SlackClient is the subset of the Slack Web API used by the service, called with the context
+// of the request so a cancelled or timed out tool call aborts its Slack requests.
+// It is satisfied by *slack.Client.
+type SlackClient interface {
+	SearchMessagesContext(ctx context.Context, query string, params slack.SearchParameters) (*slack.SearchMessages, error)
+	GetUsersContext(ctx context.Context, options ...slack.GetUsersOption) ([]slack.User, error)
+	GetConversationsContext(ctx context.Context, params *slack.GetConversationsParameters) ([]slack.Channel, string, error)
+	GetConversationRepliesContext(ctx context.Context, params *slack.GetConversationRepliesParameters) ([]slack.Message, bool, string, error)
+	GetConversationHistoryContext(ctx context.Context, params *slack.GetConversationHistoryParameters) (*slack.GetConversationHistoryResponse, error)
+	AuthTestContext(ctx context.Context) (*slack.AuthTestResponse, error)
+}
 
 type SlackService struct {
-	client *slack.Client
+	client  SlackClient
+	limiter *RateLimiter
+	users   *UserDirectory
+	// store answers the searches of synced channels, nil when no store is configured
+	store *Store
+	// index is the full-text index of the store, rebuilt after each sync
+	index atomic.Pointer[SearchIndex]
+	// taxonomy expands the technology searches, nil searches technologies as written
+	taxonomy *Taxonomy
+	// logger logs outside of the requests, the requests log with the logger of their context
+	logger *zap.Logger
+
+	workspaceMu  sync.Mutex
+	workspaceUrl string
+
+	channelNamesMu sync.Mutex
+	channelNames   map[string]string
 }
 
 type MessageInfo struct {
-	Message string
-	Slack_Author_Name string
-	Slack_id string
-	Posted string
-	Permalink string
+	// Message is the text converted to Markdown, with mentions resolved
+	Message string `json:"message"`
+	// Raw_Message is the text as posted, in Slack mrkdwn
+	Raw_Message string `json:"raw_message"`
+	Slack_Author_Name string `json:"slack_author_name"`
+	Slack_id string `json:"slack_id"`
+	// Ts is the Slack timestamp identifying the message in its channel
+	Ts string `json:"ts"`
+	Posted time.Time `json:"posted"`
+	Permalink string `json:"permalink"`
+	// Thread_ts is the timestamp of the thread parent, empty when the message has no thread
+	Thread_ts string `json:"thread_ts,omitempty"`
+	Reply_Count int `json:"reply_count"`
+	Reaction_Count int `json:"reaction_count"`
+	// Score and Snippet are set by searches of the local index
+	Score float64 `json:"score,omitempty"`
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// NewSlackService creates a new Slack service authenticated with the token
+func NewSlackService(token string, options ...slack.Option) *SlackService {
+	return NewSlackServiceWithClient(slack.New(token, options...))
+}
+
+// NewSlackServiceWithClient creates a new Slack service using the given client
+func NewSlackServiceWithClient(client SlackClient) *SlackService {
+	s := &SlackService{
+		client:  client,
+		limiter: NewRateLimiter(),
+		logger:  zap.NewNop(),
+	}
+	s.users = NewUserDirectory(s.ListUsers, defaultDirectoryTTL)
+	return s
+}
+
+// UseStore makes the service answer from the store for the channels already synced,
+// searching Slack for the others
+func (s *SlackService) UseStore(store *Store) error {
+	s.store = store
+	return s.RefreshIndex()
+}
+
+// UseLogger makes the service and its rate limiter log with the logger
+func (s *SlackService) UseLogger(logger *zap.Logger) {
+	s.logger = logger
+	s.limiter.logger = logger
+}
+
+// log returns the logger of the request, tagged with its correlation id
+func (s *SlackService) log(ctx context.Context) *zap.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+// UseTaxonomy expands the technology searches with the catalog
+func (s *SlackService) UseTaxonomy(taxonomy *Taxonomy) {
+	s.taxonomy = taxonomy
 }
 
-// NewSlackService creates a new Slack service
-func NewSlackService() *SlackService {
-	api := slack.New(config.AppConfig.SlackToken)
-	return &SlackService{
-		client: api,
+// Taxonomy returns the technology catalog, nil when none is used
+func (s *SlackService) Taxonomy() *Taxonomy {
+	return s.taxonomy
+}
+
+// RefreshIndex rebuilds the full-text index from the messages of the store
+func (s *SlackService) RefreshIndex() error {
+	if s.store == nil {
+		return nil
+	}
+	states, err := s.store.SyncStates()
+	if err != nil {
+		return err
+	}
+	messages := []StoredMessage{}
+	for _, state := range states {
+		channelMessages, err := s.store.Messages(state.Channel.ID)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, channelMessages...)
+	}
+
+	index := NewSearchIndex(messages)
+	s.index.Store(index)
+	s.logger.Info("search index rebuilt", zap.Stringer("index", index))
+	return nil
+}
+
+// workspaceURL returns the workspace URL, e.g. https://concept.slack.com/
+func (s *SlackService) workspaceURL(ctx context.Context) (string, error) {
+	s.workspaceMu.Lock()
+	defer s.workspaceMu.Unlock()
+
+	if s.workspaceUrl != "" {
+		return s.workspaceUrl, nil
+	}
+	var auth *slack.AuthTestResponse
+	err := s.limiter.Do(ctx, "auth.test", func() error {
+		var err error
+		auth, err = s.client.AuthTestContext(ctx)
+		return err
+	})
+	if err != nil {
+		return "", err
 	}
+	s.workspaceUrl = auth.URL
+	return s.workspaceUrl, nil
 }
 
-func (s *SlackService) GetTechonologyPost(tech string, channel string) ([]MessageInfo, error) {
+// Users returns the cached directory of the workspace users
+func (s *SlackService) Users() *UserDirectory {
+	return s.users
+}
+
+// GetTechonologyPost searches the posts tagged with the emojis of the technology in a channel,
+// posted in the time range. The technology is expanded with its aliases and the technologies under it.
+// It returns at most limit posts starting at cursor, and the cursor of the next posts.
+func (s *SlackService) GetTechonologyPost(ctx context.Context, tech string, channel string, window TimeRange, limit int, cursor string) ([]MessageInfo, string, error) {
+	results, next, live, err := s.technologyPosts(ctx, tech, channel, window, limit, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	if live {
+		s.addHistoryInfo(ctx, results)
+	}
+	s.renderMessages(ctx, results)
+	return results, next, nil
+}
+
+// technologyPosts finds the posts of GetTechonologyPost without their thread info and Markdown text,
+// live is set when they come from a Slack search rather than from the store
+func (s *SlackService) technologyPosts(ctx context.Context, tech string, channel string, window TimeRange, limit int, cursor string) ([]MessageInfo, string, bool, error) {
+	query := s.taxonomy.Expand(tech)
+	searches, err := technologySearches(query, channel, window)
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	if posts, ok := s.storedTechnologyPosts(ctx, query, channel, window); ok {
+		page, next, err := paginate(posts, limit, cursor)
+		if err != nil {
+			return nil, "", false, err
+		}
+		return page, next, false, nil
+	}
+
+	results, next, err := s.searchPosts(ctx, searches, window, limit, cursor)
+	if err != nil {
+		return []MessageInfo{}, "", false, err
+	}
+	return results, next, true, nil
+}
+
+// technologySearches builds the Slack searches of a technology in a channel: one search per emoji,
+// then one per searched keyword for the posts naming the technology without its emoji
+func technologySearches(query TechnologyQuery, channel string, window TimeRange) ([]string, error) {
+	searches := []string{}
+	for _, emoji := range query.Emojis {
+		search, err := window.addTo(NewSearchQuery().Has(emoji).In(channel)).Build()
+		if err != nil {
+			return nil, err
+		}
+		searches = append(searches, search)
+	}
+	for _, keyword := range query.SearchedKeywords {
+		search, err := window.addTo(NewSearchQuery().Text(keyword).In(channel)).Build()
+		if err != nil {
+			return nil, err
+		}
+		searches = append(searches, search)
+	}
+	return searches, nil
+}
+
+// searchPosts runs the searches one after the other, best matches first
+func (s *SlackService) searchPosts(ctx context.Context, searches []string, window TimeRange, limit int, cursor string) ([]MessageInfo, string, error) {
 	params := slack.SearchParameters{
 		Sort:          "score",
 		SortDirection: "desc",
 		Highlight:     false,
-		Count:         20,
-		Page:          1,
 	}
-	searchTechno := fmt.Sprintf("has::%s: in:%s", tech, channel)
-	fmt.Printf("Search: %s", searchTechno)
-	searchResult, err := s.client.SearchMessages(searchTechno, params)
+	results, next, err := collectSearches(ctx, s.client, s.limiter, searches, params, window, cursor, limit)
 	if err != nil {
-		fmt.Printf("Error: %s\n", err)
-		return []MessageInfo{}, err
-	}
-
-	fmt.Printf("Found %d\n", len(searchResult.Matches))
-	results := []MessageInfo{}
-	// Loop through each message in the Matches slice.
-	for _, match := range searchResult.Matches {
-		// Access the individual message fields.
-		// For example, the message's text, the user who sent it, and the timestamp.
-		// fmt.Printf("Message Text: %s\n", match.Text)
-		// fmt.Printf("Sent by User ID: %s\n", match.User)
-		// fmt.Printf("Timestamp: %s\n", match.Timestamp)
-		// fmt.Printf("Username: %s\n", match.Username)
-		// fmt.Println("--------------------")
-		// fmt.Println("")
-
-		results = append(results, MessageInfo{
-			Message: match.Text,
-			Slack_Author_Name: match.Username,
-			Slack_id: match.User,
-			Posted: match.Timestamp,
-			Permalink: match.Permalink,
-		})
+		return nil, "", err
 	}
 
-	return results, nil
+	s.log(ctx).Debug("searched posts", zap.Int("searches", len(searches)), zap.Int("result_count", len(results)))
+	return results, next, nil
+}
+
+func toMessageInfo(match slack.SearchMessage) MessageInfo {
+	return MessageInfo{
+		Message: match.Text,
+		Slack_Author_Name: match.Username,
+		Slack_id: match.User,
+		Ts: match.Timestamp,
+		Posted: tsTime(match.Timestamp),
+		Permalink: match.Permalink,
+	}
 }
 
 type ConceptUser struct {
-	Slack_id string
-	Slack_Name string
-	Real_Name string
+	Slack_id string `json:"slack_id"`
+	Slack_Name string `json:"slack_name"`
+	Real_Name string `json:"real_name"`
+	Display_Name string `json:"display_name"`
 	// Email is not present in profile actually 
 	// Email string
 }
 
-func (s *SlackService) ListUsers() ([]ConceptUser, error){
-	users, err := s.client.GetUsers()
+// ListUsers loads the active users of the workspace from Slack.
+// Prefer the cached Users directory for lookups.
+func (s *SlackService) ListUsers(ctx context.Context) ([]ConceptUser, error){
+	var users []slack.User
+	err := s.limiter.Do(ctx, "users.list", func() error {
+		var err error
+		users, err = s.client.GetUsersContext(ctx)
+		return err
+	})
 	if err != nil {
-		fmt.Printf("error %v \n", err)
 		return nil, err
 	}
 
@@ -91,6 +273,7 @@ func (s *SlackService) ListUsers() ([]ConceptUser, error){
 				Slack_id: user.ID,
 				Slack_Name: user.Name,
 				Real_Name: user.Profile.RealName,
+				Display_Name: user.Profile.DisplayName,
 				// Email: user.Profile.Email,
 			})
 		} 
@@ -99,32 +282,44 @@ func (s *SlackService) ListUsers() ([]ConceptUser, error){
 	return results, nil
 }
 
-func (s *SlackService) GetPostByUser(userId string) ([]MessageInfo, error) {
-	results := []MessageInfo{}
-
+// GetPostByUser returns the posts of a user in the channels posted in the time range, newest first.
+// It returns at most limit posts starting at cursor, and the cursor of the next posts.
+func (s *SlackService) GetPostByUser(ctx context.Context, userId string, channels []string, window TimeRange, limit int, cursor string) ([]MessageInfo, string, error) {
 	params := slack.SearchParameters{
 		Sort:          "timestamp",
 		SortDirection: "desc",
 		Highlight:     false,
-		Count:         100,
-		Page:          1,
 	}
-	search := fmt.Sprintf("from:%s in:#concept-tech in:#today-i-learned", userId)
-	searchResults, err := s.client.SearchMessages(search, params)
+	query := NewSearchQuery().From(userId)
+	for _, channel := range channels {
+		query.In(channel)
+	}
+	search, err := window.addTo(query).Build()
 	if err != nil {
-		fmt.Printf("Error %v", err)
-		return nil, err
+		return nil, "", err
 	}
 
-	for _, matche := range searchResults.Matches {
-		results = append(results, MessageInfo{
-			Message: matche.Text,
-			Slack_Author_Name: matche.Username,
-			Slack_id: matche.User,
-			Posted: matche.Timestamp,
-		})
+	if posts, ok := s.storedPostsByUser(ctx, userId, channels, window); ok {
+		page, next, err := paginate(posts, limit, cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		s.renderMessages(ctx, page)
+		return page, next, nil
 	}
 
-	fmt.Printf("Results get post by user %v", results)
-	return results, nil
+	it, err := newSearchIterator(s.client, s.limiter, search, params, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	results, next, err := collectMessages(ctx, it, window, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.renderMessages(ctx, results)
+
+	s.log(ctx).Debug("searched posts by user", zap.Int("result_count", len(results)))
+	return results, next, nil
 }
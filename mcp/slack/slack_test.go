@@ -1,43 +1,187 @@
 package slack
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/AlexisZankowitch/concept-insight/mcp/logging"
+	"github.com/AlexisZankowitch/concept-insight/mcp/slack/fakeslack"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"golang.org/x/time/rate"
 )
 
+// newTestService returns a service talking to a fake Slack serving the fixtures
+func newTestService(t *testing.T, fixtures fakeslack.Fixtures) (*SlackService, *fakeslack.Server) {
+	t.Helper()
+	server := fakeslack.NewServer(fixtures)
+	t.Cleanup(server.Close)
+	service := NewSlackServiceWithClient(server.Client())
+	service.limiter = newTestRateLimiter()
+	return service, server
+}
+
+// newTestChannels resolves the default channels of the fixtures
+func newTestChannels(t *testing.T, s *SlackService) *ChannelSet {
+	t.Helper()
+	channels, err := s.ResolveChannels(context.Background(), ChannelConfig{
+		Default: testChannels,
+	})
+	if err != nil {
+		t.Fatalf("failed to resolve channels: %v", err)
+	}
+	return channels
+}
+
+// newTestRateLimiter returns a rate limiter without budget and with short backoffs
+func newTestRateLimiter() *RateLimiter {
+	limiter := NewRateLimiter()
+	limiter.defaultTier = rate.Inf
+	limiter.tiers = map[string]rate.Limit{}
+	limiter.baseBackoff = time.Millisecond
+	return limiter
+}
+
+// testChannels are the channels searched by default in the fixtures
+var testChannels = []string{"concept-tech", "today-i-learned"}
+
+func loadFixtures(t *testing.T) fakeslack.Fixtures {
+	t.Helper()
+	fixtures, err := fakeslack.LoadFixtures("testdata/slack_fixtures.json")
+	if err != nil {
+		t.Fatalf("failed to load fixtures: %v", err)
+	}
+	return fixtures
+}
+
 func Test_Slack(t *testing.T) {
-	s := NewSlackService()
-	r, err :=	s.GetTechonologyPost("golang", "concept-tech")
+	s, _ := newTestService(t, loadFixtures(t))
+
+	r, next, err := s.GetTechonologyPost(context.Background(), "golang", "concept-tech", TimeRange{}, 20, "")
 	if err != nil {
-		fmt.Printf("Err %v", err)
-		return
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(r) != 1 || next != "" {
+		t.Fatalf("expected 1 post and no cursor, got %d posts and cursor %q", len(r), next)
+	}
+	if r[0].Slack_id != "U7D3Q7N8Y" || r[0].Permalink == "" {
+		t.Fatalf("unexpected post %+v", r[0])
 	}
-	fmt.Printf("Results: %d", len(r))
 }
 
 func Test_SlackUser(t *testing.T) {
-	s := NewSlackService()
+	s, _ := newTestService(t, loadFixtures(t))
 
-	r, err := s.ListUsers()
+	r, err := s.ListUsers(context.Background())
 	if err != nil {
-		fmt.Printf("err %v", err)
-		return
+		t.Fatalf("unexpected error %v", err)
 	}
 
-	fmt.Printf("Results size %v \n", len(r))
-	fmt.Printf("Results %v", r)
+	// deleted users are filtered out
+	if len(r) != 3 {
+		t.Fatalf("expected 3 users, got %d: %v", len(r), r)
+	}
+	if r[0].Slack_id != "U7D3Q7N8Y" || r[0].Real_Name != "Alexis Zankowitch" {
+		t.Fatalf("unexpected user %+v", r[0])
+	}
 }
 
 func Test_SlackGetPostByUser(t *testing.T) {
-	s := NewSlackService()
+	s, _ := newTestService(t, loadFixtures(t))
+
+	r, next, err := s.GetPostByUser(context.Background(), "U7D3Q7N8Y", testChannels, TimeRange{}, 200, "")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// the post in #random is not part of the searched channels
+	if len(r) != 2 || next != "" {
+		t.Fatalf("expected 2 posts and no cursor, got %d posts and cursor %q", len(r), next)
+	}
+	if r[0].Ts != "1718500000.000600" {
+		t.Fatalf("expected newest post first, got %+v", r[0])
+	}
+}
+
+func Test_SlackLogging(t *testing.T) {
+	s, _ := newTestService(t, loadFixtures(t))
+	core, logs := observer.New(zapcore.DebugLevel)
+	s.UseLogger(zap.New(core))
+
+	ctx, _ := logging.WithCorrelationID(context.Background(), s.logger)
+	posts, _, err := s.GetPostByUser(ctx, "U7D3Q7N8Y", testChannels, TimeRange{}, 200, "")
+	if err != nil || len(posts) == 0 {
+		t.Fatalf("unexpected result %v, %v", posts, err)
+	}
 
-	r, err := s.GetPostByUser("U7D3Q7N8Y")
+	calls := logs.FilterMessage("slack call").All()
+	if len(calls) == 0 || calls[0].ContextMap()["slack_method"] != "search.messages" || calls[0].ContextMap()["correlation_id"] == nil {
+		t.Fatalf("expected the Slack calls logged with the correlation id, got %+v", calls)
+	}
+	for _, entry := range logs.All() {
+		for _, value := range entry.ContextMap() {
+			if text, ok := value.(string); ok && strings.Contains(text, posts[0].Raw_Message) {
+				t.Fatalf("expected no message text in the logs, got %+v", entry)
+			}
+		}
+	}
+}
+
+func Test_SlackAbortsHungCalls(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	server.Hang("search.messages")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, err := s.GetPostByUser(ctx, "U7D3Q7N8Y", testChannels, TimeRange{}, 200, "")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the search to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second || server.Calls("search.messages") != 1 {
+		t.Fatalf("expected a single aborted search, took %s for %d calls", elapsed, server.Calls("search.messages"))
+	}
+}
+
+func Test_SlackGetPostByUserPagination(t *testing.T) {
+	fixtures := fakeslack.Fixtures{}
+	for i := 0; i < 250; i++ {
+		message := fakeslack.Message{}
+		message.Channel = slack.CtxChannel{ID: "C01CONCEPT", Name: "concept-tech"}
+		message.User = "U7D3Q7N8Y"
+		message.Timestamp = fmt.Sprintf("%d.000000", 1700000000+i)
+		message.Text = fmt.Sprintf("post %d", i)
+		fixtures.Messages = append(fixtures.Messages, message)
+	}
+	s, server := newTestService(t, fixtures)
+
+	first, next, err := s.GetPostByUser(context.Background(), "U7D3Q7N8Y", testChannels, TimeRange{}, 200, "")
 	if err != nil {
-		fmt.Printf("err %v", err)
-		return
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(first) != 200 || next == "" {
+		t.Fatalf("expected 200 posts and a cursor, got %d posts and cursor %q", len(first), next)
 	}
 
-	fmt.Printf("Results len: %v", len(r))
-	fmt.Printf("Results: %v", r)
+	second, next, err := s.GetPostByUser(context.Background(), "U7D3Q7N8Y", testChannels, TimeRange{}, 200, next)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(second) != 50 || next != "" {
+		t.Fatalf("expected 50 posts and no cursor, got %d posts and cursor %q", len(second), next)
+	}
+	if second[0].Message != "post 49" {
+		t.Fatalf("expected to continue after the first page, got %q", second[0].Message)
+	}
 
+	// 2 pages for the first call, the third page for the second one
+	if calls := server.Calls("search.messages"); calls != 3 {
+		t.Fatalf("expected 3 search calls, got %d", calls)
+	}
 }
@@ -0,0 +1,225 @@
+package slack
+
+import "strings"
+
+// stem reduces an english word to its stem with the Porter algorithm,
+// see https://tartarus.org/martin/PorterStemmer/def.txt
+// Words are expected in lower case, short words are returned as they are.
+func stem(word string) string {
+	if len(word) <= 2 || !isASCIILetters(word) {
+		return word
+	}
+	w := []byte(word)
+	w = porterStep1a(w)
+	w = porterStep1b(w)
+	w = porterStep1c(w)
+	w = porterStep2(w)
+	w = porterStep3(w)
+	w = porterStep4(w)
+	w = porterStep5(w)
+	return string(w)
+}
+
+func isASCIILetters(word string) bool {
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// isConsonant tells if the letter at i is a consonant, y is a consonant after a vowel
+func isConsonant(w []byte, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !isConsonant(w, i-1)
+	}
+	return true
+}
+
+// measure counts the vowel-consonant sequences of w, the m of the algorithm
+func measure(w []byte) int {
+	m := 0
+	i := 0
+	for i < len(w) && isConsonant(w, i) {
+		i++
+	}
+	for i < len(w) {
+		for i < len(w) && !isConsonant(w, i) {
+			i++
+		}
+		if i >= len(w) {
+			break
+		}
+		for i < len(w) && isConsonant(w, i) {
+			i++
+		}
+		m++
+	}
+	return m
+}
+
+func hasVowel(w []byte) bool {
+	for i := range w {
+		if !isConsonant(w, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func endsWithDoubleConsonant(w []byte) bool {
+	n := len(w)
+	return n >= 2 && w[n-1] == w[n-2] && isConsonant(w, n-1)
+}
+
+// endsCVC tells if w ends with consonant-vowel-consonant, the last one not being w, x or y
+func endsCVC(w []byte) bool {
+	n := len(w)
+	if n < 3 || !isConsonant(w, n-3) || isConsonant(w, n-2) || !isConsonant(w, n-1) {
+		return false
+	}
+	return w[n-1] != 'w' && w[n-1] != 'x' && w[n-1] != 'y'
+}
+
+func hasSuffix(w []byte, suffix string) bool {
+	return strings.HasSuffix(string(w), suffix)
+}
+
+// replaceSuffix replaces suffix by replacement when the stem before it has a measure above minMeasure.
+// It returns whether w ended with suffix, whatever the measure.
+func replaceSuffix(w []byte, suffix string, replacement string, minMeasure int) ([]byte, bool) {
+	if !hasSuffix(w, suffix) {
+		return w, false
+	}
+	base := w[:len(w)-len(suffix)]
+	if measure(base) > minMeasure {
+		return append(base[:len(base):len(base)], replacement...), true
+	}
+	return w, true
+}
+
+func porterStep1a(w []byte) []byte {
+	switch {
+	case hasSuffix(w, "sses"):
+		return w[:len(w)-2]
+	case hasSuffix(w, "ies"):
+		return w[:len(w)-2]
+	case hasSuffix(w, "ss"):
+		return w
+	case hasSuffix(w, "s"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+func porterStep1b(w []byte) []byte {
+	if hasSuffix(w, "eed") {
+		w, _ = replaceSuffix(w, "eed", "ee", 0)
+		return w
+	}
+
+	var base []byte
+	switch {
+	case hasSuffix(w, "ed") && hasVowel(w[:len(w)-2]):
+		base = w[:len(w)-2]
+	case hasSuffix(w, "ing") && hasVowel(w[:len(w)-3]):
+		base = w[:len(w)-3]
+	default:
+		return w
+	}
+
+	switch {
+	case hasSuffix(base, "at"), hasSuffix(base, "bl"), hasSuffix(base, "iz"):
+		return append(base[:len(base):len(base)], 'e')
+	case endsWithDoubleConsonant(base):
+		last := base[len(base)-1]
+		if last != 'l' && last != 's' && last != 'z' {
+			return base[:len(base)-1]
+		}
+	case measure(base) == 1 && endsCVC(base):
+		return append(base[:len(base):len(base)], 'e')
+	}
+	return base
+}
+
+func porterStep1c(w []byte) []byte {
+	if hasSuffix(w, "y") && hasVowel(w[:len(w)-1]) {
+		return append(w[:len(w)-1:len(w)-1], 'i')
+	}
+	return w
+}
+
+// porterSuffixes are tried in order, the first suffix found is the only one considered
+func porterSuffixes(w []byte, rules [][2]string, minMeasure int) []byte {
+	for _, rule := range rules {
+		if result, found := replaceSuffix(w, rule[0], rule[1], minMeasure); found {
+			return result
+		}
+	}
+	return w
+}
+
+var porterStep2Rules = [][2]string{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"},
+	{"izer", "ize"}, {"abli", "able"}, {"alli", "al"}, {"entli", "ent"},
+	{"eli", "e"}, {"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"},
+	{"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"},
+	{"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+}
+
+func porterStep2(w []byte) []byte {
+	return porterSuffixes(w, porterStep2Rules, 0)
+}
+
+var porterStep3Rules = [][2]string{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+	{"ical", "ic"}, {"ful", ""}, {"ness", ""},
+}
+
+func porterStep3(w []byte) []byte {
+	return porterSuffixes(w, porterStep3Rules, 0)
+}
+
+var porterStep4Suffixes = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment",
+	"ent", "ion", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+func porterStep4(w []byte) []byte {
+	// the longest matching suffix wins, ement before ment before ent
+	suffix := ""
+	for _, s := range porterStep4Suffixes {
+		if hasSuffix(w, s) && len(s) > len(suffix) {
+			suffix = s
+		}
+	}
+	if suffix == "" {
+		return w
+	}
+	base := w[:len(w)-len(suffix)]
+	if measure(base) <= 1 {
+		return w
+	}
+	if suffix == "ion" && !hasSuffix(base, "s") && !hasSuffix(base, "t") {
+		return w
+	}
+	return base
+}
+
+func porterStep5(w []byte) []byte {
+	if hasSuffix(w, "e") {
+		base := w[:len(w)-1]
+		m := measure(base)
+		if m > 1 || (m == 1 && !endsCVC(base)) {
+			w = base
+		}
+	}
+	if measure(w) > 1 && endsWithDoubleConsonant(w) && hasSuffix(w, "l") {
+		w = w[:len(w)-1]
+	}
+	return w
+}
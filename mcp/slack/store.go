@@ -0,0 +1,367 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+var (
+	// messagesBucket holds a bucket of messages per channel id, keyed by timestamp
+	messagesBucket = []byte("messages")
+	// syncBucket holds the sync state of each channel, keyed by channel id
+	syncBucket = []byte("sync")
+)
+
+// StoredMessage is a message of a synced channel, top-level or thread reply
+type StoredMessage struct {
+	Channel_id   string
+	Channel_Name string
+	Ts           string
+	// Thread_ts is the timestamp of the thread parent, empty when the message has no thread
+	Thread_ts    string
+	User         string
+	Username     string
+	Text         string
+	Permalink    string
+	Edited       string
+	Reply_Count  int
+	Latest_Reply string
+	// Reactions counts the reactions per emoji name
+	Reactions map[string]int
+}
+
+// SyncState records how far a channel was synced
+type SyncState struct {
+	Channel Channel
+	// High_Water is the timestamp of the newest message synced
+	High_Water string
+	Last_Sync  time.Time
+}
+
+// Store keeps a local copy of the synced channels, so the tools do not depend
+// on Slack search being reachable or on its rate limit
+type Store struct {
+	db *bolt.DB
+}
+
+// OpenStore opens the store at path, creating it when missing
+func OpenStore(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{messagesBucket, syncBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize store %s: %w", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the store
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// PutMessages saves the messages of a channel and returns how many were new or changed
+func (s *Store) PutMessages(channelID string, messages []StoredMessage) (int, error) {
+	written := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(messagesBucket).CreateBucketIfNotExists([]byte(channelID))
+		if err != nil {
+			return err
+		}
+		for _, message := range messages {
+			value, err := json.Marshal(message)
+			if err != nil {
+				return err
+			}
+			if bytes.Equal(bucket.Get([]byte(message.Ts)), value) {
+				continue
+			}
+			if err := bucket.Put([]byte(message.Ts), value); err != nil {
+				return err
+			}
+			written++
+		}
+		return nil
+	})
+	return written, err
+}
+
+// Message returns the message of a channel posted at ts
+func (s *Store) Message(channelID string, ts string) (StoredMessage, bool, error) {
+	var message StoredMessage
+	found := false
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(messagesBucket).Bucket([]byte(channelID))
+		if bucket == nil {
+			return nil
+		}
+		value := bucket.Get([]byte(ts))
+		if value == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(value, &message)
+	})
+	return message, found, err
+}
+
+// Messages returns the messages of a channel, oldest first
+func (s *Store) Messages(channelID string) ([]StoredMessage, error) {
+	messages := []StoredMessage{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(messagesBucket).Bucket([]byte(channelID))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(_, value []byte) error {
+			var message StoredMessage
+			if err := json.Unmarshal(value, &message); err != nil {
+				return err
+			}
+			messages = append(messages, message)
+			return nil
+		})
+	})
+	return messages, err
+}
+
+// SyncState returns the sync state of a channel, false when it was never synced
+func (s *Store) SyncState(channelID string) (SyncState, bool, error) {
+	var state SyncState
+	found := false
+	err := s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(syncBucket).Get([]byte(channelID))
+		if value == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(value, &state)
+	})
+	return state, found, err
+}
+
+// SetSyncState saves the sync state of a channel
+func (s *Store) SetSyncState(state SyncState) error {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(syncBucket).Put([]byte(state.Channel.ID), value)
+	})
+}
+
+// SyncStates returns the sync state of every synced channel
+func (s *Store) SyncStates() ([]SyncState, error) {
+	states := []SyncState{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(syncBucket).ForEach(func(_, value []byte) error {
+			var state SyncState
+			if err := json.Unmarshal(value, &state); err != nil {
+				return err
+			}
+			states = append(states, state)
+			return nil
+		})
+	})
+	return states, err
+}
+
+// SyncedChannel returns the synced channel with the given name
+func (s *Store) SyncedChannel(name string) (Channel, bool, error) {
+	states, err := s.SyncStates()
+	if err != nil {
+		return Channel{}, false, err
+	}
+	for _, state := range states {
+		if normalizeChannelName(state.Channel.Name) == normalizeChannelName(name) {
+			return state.Channel, true, nil
+		}
+	}
+	return Channel{}, false, nil
+}
+
+// toMessageInfo converts a stored message, the author is its Slack handle when known
+func (m StoredMessage) toMessageInfo(author string) MessageInfo {
+	info := MessageInfo{
+		Message:           m.Text,
+		Slack_Author_Name: author,
+		Slack_id:          m.User,
+		Ts:                m.Ts,
+		Posted:            tsTime(m.Ts),
+		Permalink:         m.Permalink,
+		Thread_ts:         m.Thread_ts,
+		Reply_Count:       m.Reply_Count,
+	}
+	for _, count := range m.Reactions {
+		info.Reaction_Count += count
+	}
+	return info
+}
+
+// hasReaction tells if the message was reacted with the emoji, which is how posts are tagged
+func (m StoredMessage) hasReaction(emoji string) bool {
+	for name := range m.Reactions {
+		if strings.EqualFold(name, emoji) {
+			return true
+		}
+	}
+	return false
+}
+
+// storedTechnologyPosts returns the posts of a synced channel tagged with one of the technology emojis
+// or mentioning one of its keywords, posted in the time range, best first. Tagged posts get a boost
+// over the posts only mentioning it. It returns false when the search must go to Slack.
+func (s *SlackService) storedTechnologyPosts(ctx context.Context, query TechnologyQuery, channel string, window TimeRange) ([]MessageInfo, bool) {
+	messages, ok := s.storedMessages([]string{channel})
+	if !ok {
+		return nil, false
+	}
+
+	type rankedPost struct {
+		message StoredMessage
+		score   float64
+		snippet string
+	}
+	keywords := strings.Join(query.Keywords, " ")
+	ranked := map[string]*rankedPost{}
+	for _, message := range messages {
+		if !window.Contains(tsTime(message.Ts)) {
+			continue
+		}
+		for _, emoji := range query.Emojis {
+			if message.hasReaction(emoji) {
+				ranked[message.Ts] = &rankedPost{
+					message: message,
+					score:   taggedPostBoost,
+					snippet: messageSnippet(message.Text, keywords),
+				}
+				break
+			}
+		}
+	}
+	if index := s.index.Load(); index != nil && len(messages) > 0 {
+		// a post matching several keywords keeps its best score
+		best := map[string]IndexHit{}
+		for _, keyword := range query.Keywords {
+			for _, hit := range index.Search(`"`+keyword+`"`, []string{messages[0].Channel_id}) {
+				if !window.Contains(tsTime(hit.Message.Ts)) {
+					continue
+				}
+				if previous, ok := best[hit.Message.Ts]; !ok || hit.Score > previous.Score {
+					best[hit.Message.Ts] = hit
+				}
+			}
+		}
+		for _, hit := range best {
+			post, ok := ranked[hit.Message.Ts]
+			if !ok {
+				post = &rankedPost{message: hit.Message}
+				ranked[hit.Message.Ts] = post
+			}
+			post.score += hit.Score
+			post.snippet = hit.Snippet
+		}
+	}
+
+	posts := []*rankedPost{}
+	for _, post := range ranked {
+		posts = append(posts, post)
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		if posts[i].score != posts[j].score {
+			return posts[i].score > posts[j].score
+		}
+		return tsLess(posts[j].message.Ts, posts[i].message.Ts)
+	})
+
+	infos := []MessageInfo{}
+	for _, post := range posts {
+		info := post.message.toMessageInfo(s.authorName(ctx, post.message.User, post.message.Username))
+		info.Score = post.score
+		info.Snippet = post.snippet
+		infos = append(infos, info)
+	}
+	return infos, true
+}
+
+// storedPostsByUser returns the posts of a user in synced channels posted in the time range, newest first.
+// It returns false when one of the channels is not synced yet.
+func (s *SlackService) storedPostsByUser(ctx context.Context, userId string, channels []string, window TimeRange) ([]MessageInfo, bool) {
+	messages, ok := s.storedMessages(channels)
+	if !ok {
+		return nil, false
+	}
+
+	posts := []StoredMessage{}
+	for _, message := range messages {
+		if strings.EqualFold(message.User, userId) && window.Contains(tsTime(message.Ts)) {
+			posts = append(posts, message)
+		}
+	}
+	sort.SliceStable(posts, func(i, j int) bool {
+		return tsLess(posts[j].Ts, posts[i].Ts)
+	})
+	return s.storedMessageInfos(ctx, posts), true
+}
+
+// storedMessages returns the messages of the channels, false when the store is
+// not configured, one of the channels is not synced or the store fails
+// channelSynced tells if the store answers for the channel
+func (s *SlackService) channelSynced(name string) bool {
+	if s.store == nil {
+		return false
+	}
+	_, synced, err := s.store.SyncedChannel(name)
+	return err == nil && synced
+}
+
+func (s *SlackService) storedMessages(channels []string) ([]StoredMessage, bool) {
+	if s.store == nil {
+		return nil, false
+	}
+
+	messages := []StoredMessage{}
+	for _, name := range channels {
+		channel, synced, err := s.store.SyncedChannel(name)
+		if err == nil && synced {
+			var channelMessages []StoredMessage
+			channelMessages, err = s.store.Messages(channel.ID)
+			messages = append(messages, channelMessages...)
+		}
+		if err != nil {
+			s.logger.Warn("could not read the store, searching Slack instead", zap.String("channel", name), zap.Error(err))
+			return nil, false
+		}
+		if !synced {
+			return nil, false
+		}
+	}
+	return messages, true
+}
+
+func (s *SlackService) storedMessageInfos(ctx context.Context, messages []StoredMessage) []MessageInfo {
+	infos := []MessageInfo{}
+	for _, message := range messages {
+		infos = append(infos, message.toMessageInfo(s.authorName(ctx, message.User, message.Username)))
+	}
+	return infos
+}
@@ -0,0 +1,63 @@
+package slack
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// newTestStore opens an empty store removed at the end of the test
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := OpenStore(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func Test_StorePutMessages(t *testing.T) {
+	store := newTestStore(t)
+	messages := []StoredMessage{
+		{Channel_id: "C01CONCEPT", Ts: "1718000000.000100", Text: "first"},
+		{Channel_id: "C01CONCEPT", Ts: "1718000001.000100", Text: "second"},
+	}
+
+	written, err := store.PutMessages("C01CONCEPT", messages)
+	if err != nil || written != 2 {
+		t.Fatalf("expected 2 messages written, got %d, %v", written, err)
+	}
+
+	// only the edited message is written again
+	messages[1].Text = "second, edited"
+	messages[1].Edited = "1718000100.000000"
+	written, err = store.PutMessages("C01CONCEPT", messages)
+	if err != nil || written != 1 {
+		t.Fatalf("expected 1 message written, got %d, %v", written, err)
+	}
+
+	stored, err := store.Messages("C01CONCEPT")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(stored) != 2 || stored[0].Text != "first" || stored[1].Text != "second, edited" {
+		t.Fatalf("unexpected messages %+v", stored)
+	}
+}
+
+func Test_StoreSyncState(t *testing.T) {
+	store := newTestStore(t)
+
+	if _, found, err := store.SyncedChannel("concept-tech"); err != nil || found {
+		t.Fatalf("expected no synced channel, got %v, %v", found, err)
+	}
+
+	err := store.SetSyncState(SyncState{Channel: Channel{ID: "C01CONCEPT", Name: "concept-tech"}, High_Water: "1718000000.000100"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	channel, found, err := store.SyncedChannel("#Concept-Tech")
+	if err != nil || !found || channel.ID != "C01CONCEPT" {
+		t.Fatalf("expected the synced channel, got %+v, %v, %v", channel, found, err)
+	}
+}
@@ -0,0 +1,228 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AlexisZankowitch/concept-insight/mcp/logging"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+// historyPageSize is the number of messages asked per conversations.history call
+const historyPageSize = 200
+
+// SyncStats counts what a channel sync did
+type SyncStats struct {
+	// Fetched is the number of messages read from Slack
+	Fetched int
+	// Written is the number of new or changed messages saved
+	Written int
+	// Threads is the number of threads whose replies were fetched
+	Threads int
+}
+
+// Syncer copies the history of the tracked channels to the store.
+// The first sync of a channel pulls its whole history, later ones only fetch
+// the messages posted after the high-water mark, plus a lookback window to
+// catch recent edits, reactions and replies.
+type Syncer struct {
+	service  *SlackService
+	store    *Store
+	channels []Channel
+	interval time.Duration
+	lookback time.Duration
+}
+
+// NewSyncer creates a syncer of the channels running every interval
+func NewSyncer(service *SlackService, store *Store, channels []Channel, interval time.Duration, lookback time.Duration) *Syncer {
+	return &Syncer{
+		service:  service,
+		store:    store,
+		channels: channels,
+		interval: interval,
+		lookback: lookback,
+	}
+}
+
+// Run syncs the channels right away and then every interval, until the context is done
+func (s *Syncer) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		syncCtx, logger := logging.WithCorrelationID(ctx, s.service.logger)
+		if err := s.SyncAll(syncCtx); err != nil {
+			logger.Error("sync failed", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncAll syncs every channel, a failing channel does not stop the others
+func (s *Syncer) SyncAll(ctx context.Context) error {
+	var errs []error
+	for _, channel := range s.channels {
+		stats, err := s.SyncChannel(ctx, channel)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to sync %s: %w", channel.Name, err))
+			continue
+		}
+		s.service.log(ctx).Info("synced channel", zap.String("channel", channel.Name), zap.Int("fetched", stats.Fetched), zap.Int("written", stats.Written), zap.Int("threads", stats.Threads))
+	}
+	if err := s.service.RefreshIndex(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to rebuild the search index: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// SyncChannel fetches the new and recently changed messages of a channel and their replies
+func (s *Syncer) SyncChannel(ctx context.Context, channel Channel) (SyncStats, error) {
+	stats := SyncStats{}
+	state, synced, err := s.store.SyncState(channel.ID)
+	if err != nil {
+		return stats, err
+	}
+
+	oldest := ""
+	if synced && state.High_Water != "" {
+		since := tsTime(state.High_Water).Add(-s.lookback)
+		oldest = fmt.Sprintf("%d.000000", since.Unix())
+	}
+	workspaceURL, err := s.service.workspaceURL(ctx)
+	if err != nil {
+		return stats, err
+	}
+
+	highWater := state.High_Water
+	params := &slack.GetConversationHistoryParameters{
+		ChannelID: channel.ID,
+		Oldest:    oldest,
+		Inclusive: true,
+		Limit:     historyPageSize,
+	}
+	for {
+		var history *slack.GetConversationHistoryResponse
+		err := s.service.limiter.Do(ctx, "conversations.history", func() error {
+			var err error
+			history, err = s.service.client.GetConversationHistoryContext(ctx, params)
+			return err
+		})
+		if err != nil {
+			return stats, err
+		}
+
+		messages := []StoredMessage{}
+		for _, msg := range history.Messages {
+			if !isUserMessage(msg) {
+				continue
+			}
+			message := toStoredMessage(channel, workspaceURL, msg)
+			messages = append(messages, message)
+			if highWater == "" || tsLess(highWater, msg.Timestamp) {
+				highWater = msg.Timestamp
+			}
+
+			replies, err := s.syncReplies(ctx, channel, workspaceURL, message)
+			if err != nil {
+				return stats, err
+			}
+			if replies != nil {
+				stats.Threads++
+				messages = append(messages, replies...)
+			}
+		}
+
+		written, err := s.store.PutMessages(channel.ID, messages)
+		if err != nil {
+			return stats, err
+		}
+		stats.Fetched += len(messages)
+		stats.Written += written
+
+		if !history.HasMore || history.ResponseMetaData.NextCursor == "" {
+			break
+		}
+		params.Cursor = history.ResponseMetaData.NextCursor
+	}
+
+	err = s.store.SetSyncState(SyncState{
+		Channel:    channel,
+		High_Water: highWater,
+		Last_Sync:  time.Now(),
+	})
+	return stats, err
+}
+
+// syncReplies returns the replies of a thread when it got new replies since
+// the last sync, and nil when it has none or is up to date
+func (s *Syncer) syncReplies(ctx context.Context, channel Channel, workspaceURL string, message StoredMessage) ([]StoredMessage, error) {
+	if message.Reply_Count == 0 {
+		return nil, nil
+	}
+	stored, found, err := s.store.Message(channel.ID, message.Ts)
+	if err != nil {
+		return nil, err
+	}
+	if found && stored.Latest_Reply == message.Latest_Reply && stored.Reply_Count == message.Reply_Count {
+		return nil, nil
+	}
+
+	thread, err := s.service.fetchReplies(ctx, channel.ID, message.Ts)
+	if err != nil {
+		return nil, err
+	}
+	replies := []StoredMessage{}
+	for _, msg := range thread {
+		if msg.Timestamp == message.Ts || !isUserMessage(msg) {
+			continue
+		}
+		replies = append(replies, toStoredMessage(channel, workspaceURL, msg))
+	}
+	return replies, nil
+}
+
+// isUserMessage filters out the join, leave and other channel events of a history
+func isUserMessage(msg slack.Message) bool {
+	return msg.SubType == "" || msg.SubType == "thread_broadcast" || msg.SubType == "bot_message"
+}
+
+func toStoredMessage(channel Channel, workspaceURL string, msg slack.Message) StoredMessage {
+	message := StoredMessage{
+		Channel_id:   channel.ID,
+		Channel_Name: channel.Name,
+		Ts:           msg.Timestamp,
+		Thread_ts:    msg.ThreadTimestamp,
+		User:         msg.User,
+		Username:     msg.Username,
+		Text:         msg.Text,
+		Permalink:    buildPermalink(workspaceURL, channel.ID, msg.Timestamp, msg.ThreadTimestamp),
+		Reply_Count:  msg.ReplyCount,
+		Latest_Reply: msg.LatestReply,
+		Reactions:    map[string]int{},
+	}
+	if msg.Edited != nil {
+		message.Edited = msg.Edited.Timestamp
+	}
+	for _, reaction := range msg.Reactions {
+		message.Reactions[reaction.Name] = reaction.Count
+	}
+	return message
+}
+
+// buildPermalink builds the permalink of a message the way Slack does,
+// to avoid a chat.getPermalink call per message
+func buildPermalink(workspaceURL string, channelID string, ts string, threadTs string) string {
+	permalink := fmt.Sprintf("%sarchives/%s/p%s", workspaceURL, channelID, strings.Replace(ts, ".", "", 1))
+	if threadTs != "" && threadTs != ts {
+		permalink += fmt.Sprintf("?thread_ts=%s&cid=%s", threadTs, channelID)
+	}
+	return permalink
+}
@@ -0,0 +1,100 @@
+package slack
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlexisZankowitch/concept-insight/mcp/slack/fakeslack"
+	"github.com/slack-go/slack"
+)
+
+var conceptTech = Channel{ID: "C01CONCEPT", Name: "concept-tech"}
+
+func Test_SyncChannel(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	store := newTestStore(t)
+	syncer := NewSyncer(s, store, []Channel{conceptTech}, time.Minute, time.Hour)
+
+	stats, err := syncer.SyncChannel(context.Background(), conceptTech)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// 3 posts and the 2 replies of the golang thread
+	if stats.Fetched != 5 || stats.Written != 5 || stats.Threads != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	parent, found, err := store.Message("C01CONCEPT", "1718000000.000100")
+	if err != nil || !found {
+		t.Fatalf("expected the golang post in the store, got %v, %v", found, err)
+	}
+	if parent.Reply_Count != 2 || parent.Reactions["golang"] != 1 || parent.Permalink != "https://concept.slack.com/archives/C01CONCEPT/p1718000000000100" {
+		t.Fatalf("unexpected stored post %+v", parent)
+	}
+
+	// nothing changed, the thread is not fetched again
+	stats, err = syncer.SyncChannel(context.Background(), conceptTech)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if stats.Written != 0 || stats.Threads != 0 {
+		t.Fatalf("expected nothing to write, got %+v", stats)
+	}
+	if calls := server.Calls("conversations.replies"); calls != 1 {
+		t.Fatalf("expected the thread to be fetched once, got %d", calls)
+	}
+
+	// a new post is fetched from the high-water mark
+	post := fakeslack.Message{Reactions: []string{"golang"}}
+	post.Channel = slack.CtxChannel{ID: "C01CONCEPT", Name: "concept-tech"}
+	post.User = "U03LINUS01"
+	post.Timestamp = "1718600000.000700"
+	post.Text = "Go 1.23 iterators are in"
+	server.AddMessage(post)
+
+	stats, err = syncer.SyncChannel(context.Background(), conceptTech)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// only the rust post is within the lookback of the previous high-water mark
+	if stats.Fetched != 2 || stats.Written != 1 {
+		t.Fatalf("expected the new post only, got %+v", stats)
+	}
+	state, _, _ := store.SyncState("C01CONCEPT")
+	if state.High_Water != "1718600000.000700" {
+		t.Fatalf("unexpected high-water mark %q", state.High_Water)
+	}
+}
+
+func Test_SlackGetTechonologyPostFromStore(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	store := newTestStore(t)
+	if err := s.UseStore(store); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	syncer := NewSyncer(s, store, []Channel{conceptTech}, time.Minute, time.Hour)
+	if err := syncer.SyncAll(context.Background()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	r, next, err := s.GetTechonologyPost(context.Background(), "golang", "concept-tech", TimeRange{}, 20, "")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(r) != 1 || next != "" || r[0].Reply_Count != 2 || r[0].Slack_Author_Name != "alexis" {
+		t.Fatalf("unexpected posts %+v", r)
+	}
+	if calls := server.Calls("search.messages"); calls != 0 {
+		t.Fatalf("expected no search for a synced channel, got %d", calls)
+	}
+
+	// today-i-learned is not synced, Slack is searched
+	r, _, err = s.GetTechonologyPost(context.Background(), "golang", "today-i-learned", TimeRange{}, 20, "")
+	if err != nil || len(r) != 2 {
+		t.Fatalf("expected 2 posts, got %+v, %v", r, err)
+	}
+	// the emoji search and the keyword search
+	if calls := server.Calls("search.messages"); calls != 2 {
+		t.Fatalf("expected the searches of a channel not synced, got %d", calls)
+	}
+}
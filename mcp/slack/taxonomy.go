@@ -0,0 +1,232 @@
+package slack
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultTaxonomy is the catalog used when no technologies file is configured
+//
+//go:embed technologies.yaml
+var defaultTaxonomy []byte
+
+// Technology is an entry of the technology catalog, a technology or a category of technologies
+type Technology struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description,omitempty"`
+	// Aliases are the other names the technology is asked for, e.g. k8s for kubernetes
+	Aliases []string `yaml:"aliases" json:"aliases"`
+	// Emojis are the names of the Slack emojis tagging posts about the technology
+	Emojis []string `yaml:"emojis" json:"emojis"`
+	// Keywords are searched in the text of the posts, the name when empty
+	Keywords []string `yaml:"keywords" json:"keywords"`
+	// Parents are the categories the technology belongs to, e.g. frontend for react
+	Parents []string `yaml:"parents" json:"parents"`
+}
+
+// TechnologyQuery is a technology search expanded with the catalog
+type TechnologyQuery struct {
+	// Name is the canonical name of the technology, or the normalized search when it is unknown
+	Name string
+	// Technologies are the technology and all the technologies under it
+	Technologies []string
+	Emojis       []string
+	Keywords     []string
+	// SearchedKeywords are the keywords of the Slack searches: the first keywords of the technology
+	// itself, the technologies under it being found by their emojis
+	SearchedKeywords []string
+}
+
+// maxSearchedKeywords caps the Slack searches of the keywords of a technology
+const maxSearchedKeywords = 3
+
+// Taxonomy is the technology catalog, it resolves aliases and categories
+type Taxonomy struct {
+	technologies []Technology
+	// byName indexes the technologies by normalized name and alias
+	byName   map[string]int
+	children map[string][]string
+}
+
+// LoadTaxonomy reads the technology catalog from a YAML file, or the default catalog when path is empty
+func LoadTaxonomy(path string) (*Taxonomy, error) {
+	if path == "" {
+		return ParseTaxonomy(defaultTaxonomy)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read technologies: %w", err)
+	}
+	taxonomy, err := ParseTaxonomy(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid technologies file %s: %w", path, err)
+	}
+	return taxonomy, nil
+}
+
+// ParseTaxonomy parses and validates a YAML technology catalog
+func ParseTaxonomy(data []byte) (*Taxonomy, error) {
+	var file struct {
+		Technologies []Technology `yaml:"technologies"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	t := &Taxonomy{byName: map[string]int{}, children: map[string][]string{}}
+	for i, tech := range file.Technologies {
+		tech.Name = strings.ToLower(strings.TrimSpace(tech.Name))
+		if tech.Name == "" {
+			return nil, fmt.Errorf("technology %d has no name", i+1)
+		}
+		tech.Aliases = cleanNames(tech.Aliases)
+		tech.Emojis = cleanNames(tech.Emojis)
+		tech.Keywords = cleanNames(tech.Keywords)
+		tech.Parents = cleanNames(tech.Parents)
+		if len(tech.Keywords) == 0 {
+			tech.Keywords = []string{tech.Name}
+		}
+
+		for _, name := range append([]string{tech.Name}, tech.Aliases...) {
+			key := normalizeTechnology(name)
+			if other, ok := t.byName[key]; ok && other != i {
+				return nil, fmt.Errorf("%s of %s is already a name of %s", name, tech.Name, file.Technologies[other].Name)
+			}
+			t.byName[key] = i
+		}
+		file.Technologies[i] = tech
+	}
+	t.technologies = file.Technologies
+
+	for _, tech := range t.technologies {
+		for _, parent := range tech.Parents {
+			i, ok := t.byName[normalizeTechnology(parent)]
+			if !ok {
+				return nil, fmt.Errorf("unknown parent %s of %s", parent, tech.Name)
+			}
+			parentName := t.technologies[i].Name
+			t.children[parentName] = append(t.children[parentName], tech.Name)
+		}
+	}
+	for _, tech := range t.technologies {
+		if t.isDescendant(tech.Name, tech.Name, map[string]bool{}) {
+			return nil, fmt.Errorf("%s is its own parent", tech.Name)
+		}
+	}
+	return t, nil
+}
+
+// isDescendant reports whether name is under root in the hierarchy
+func (t *Taxonomy) isDescendant(root string, name string, visited map[string]bool) bool {
+	for _, child := range t.children[root] {
+		if child == name {
+			return true
+		}
+		if visited[child] {
+			continue
+		}
+		visited[child] = true
+		if t.isDescendant(child, name, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup returns the technology named or aliased by name, ignoring case, separators and colons
+func (t *Taxonomy) Lookup(name string) (Technology, bool) {
+	if t == nil {
+		return Technology{}, false
+	}
+	i, ok := t.byName[normalizeTechnology(name)]
+	if !ok {
+		return Technology{}, false
+	}
+	return t.technologies[i], true
+}
+
+// Technologies returns the catalog sorted by name
+func (t *Taxonomy) Technologies() []Technology {
+	technologies := []Technology{}
+	if t == nil {
+		return technologies
+	}
+	technologies = append(technologies, t.technologies...)
+	sort.Slice(technologies, func(i, j int) bool {
+		return technologies[i].Name < technologies[j].Name
+	})
+	return technologies
+}
+
+// Descendants returns the technology and every technology under it, closest first
+func (t *Taxonomy) Descendants(tech Technology) []Technology {
+	technologies := []Technology{tech}
+	seen := map[string]bool{tech.Name: true}
+	for i := 0; i < len(technologies); i++ {
+		for _, child := range t.children[technologies[i].Name] {
+			if !seen[child] {
+				seen[child] = true
+				technologies = append(technologies, t.technologies[t.byName[normalizeTechnology(child)]])
+			}
+		}
+	}
+	return technologies
+}
+
+// Expand resolves a technology search to the emojis and keywords of the technology and
+// of all the technologies under it. An unknown technology is searched as it is written.
+func (t *Taxonomy) Expand(search string) TechnologyQuery {
+	tech, ok := t.Lookup(search)
+	if !ok {
+		name := strings.ToLower(strings.Trim(strings.TrimSpace(search), ":"))
+		return TechnologyQuery{
+			Name:             name,
+			Emojis:           []string{name},
+			Keywords:         []string{name},
+			SearchedKeywords: []string{name},
+		}
+	}
+
+	query := TechnologyQuery{Name: tech.Name, SearchedKeywords: tech.Keywords[:min(len(tech.Keywords), maxSearchedKeywords)]}
+	emojis := map[string]bool{}
+	keywords := map[string]bool{}
+	for _, technology := range t.Descendants(tech) {
+		query.Technologies = append(query.Technologies, technology.Name)
+		for _, emoji := range technology.Emojis {
+			if !emojis[emoji] {
+				emojis[emoji] = true
+				query.Emojis = append(query.Emojis, emoji)
+			}
+		}
+		for _, keyword := range technology.Keywords {
+			if !keywords[keyword] {
+				keywords[keyword] = true
+				query.Keywords = append(query.Keywords, keyword)
+			}
+		}
+	}
+	return query
+}
+
+// normalizeTechnology folds the spellings of a technology name: Go-Lang, golang and :golang: are the same
+func normalizeTechnology(name string) string {
+	name = strings.ToLower(strings.Trim(strings.TrimSpace(name), ":"))
+	return strings.NewReplacer(" ", "", "-", "", "_", "", ".", "").Replace(name)
+}
+
+// cleanNames lowercases names and drops the blank ones and the colons around emoji names
+func cleanNames(names []string) []string {
+	cleaned := []string{}
+	for _, name := range names {
+		name = strings.ToLower(strings.Trim(strings.TrimSpace(name), ":"))
+		if name != "" {
+			cleaned = append(cleaned, name)
+		}
+	}
+	return cleaned
+}
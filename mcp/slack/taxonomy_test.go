@@ -0,0 +1,186 @@
+package slack
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/AlexisZankowitch/concept-insight/mcp/slack/fakeslack"
+	"github.com/slack-go/slack"
+)
+
+const testTaxonomy = `
+technologies:
+  - name: frontend
+    aliases: [front-end]
+  - name: backend
+  - name: golang
+    aliases: [go, go-lang]
+    emojis: [golang, ":gopher:"]
+    parents: [backend]
+  - name: react
+    aliases: [reactjs]
+    emojis: [react]
+    parents: [frontend]
+  - name: nextjs
+    emojis: [nextjs]
+    keywords: [nextjs, next.js]
+    parents: [react]
+`
+
+func newTestTaxonomy(t *testing.T) *Taxonomy {
+	t.Helper()
+	taxonomy, err := ParseTaxonomy([]byte(testTaxonomy))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return taxonomy
+}
+
+func Test_DefaultTaxonomy(t *testing.T) {
+	taxonomy, err := LoadTaxonomy("")
+	if err != nil {
+		t.Fatalf("invalid default catalog: %v", err)
+	}
+	for search, expected := range map[string]string{
+		"Go":           "golang",
+		"go-lang":      "golang",
+		":golang:":     "golang",
+		"k8s":          "kubernetes",
+		"React.js":     "react",
+		"Front End":    "frontend",
+		"react native": "react-native",
+	} {
+		if tech, ok := taxonomy.Lookup(search); !ok || tech.Name != expected {
+			t.Errorf("expected %s to be %s, got %+v", search, expected, tech)
+		}
+	}
+}
+
+func Test_TaxonomyExpand(t *testing.T) {
+	taxonomy := newTestTaxonomy(t)
+
+	query := taxonomy.Expand("Front-End")
+	if query.Name != "frontend" || !reflect.DeepEqual(query.Technologies, []string{"frontend", "react", "nextjs"}) {
+		t.Fatalf("unexpected technologies %+v", query)
+	}
+	if !reflect.DeepEqual(query.Emojis, []string{"react", "nextjs"}) {
+		t.Fatalf("unexpected emojis %+v", query.Emojis)
+	}
+	// keywords default to the name
+	if !reflect.DeepEqual(query.Keywords, []string{"frontend", "react", "nextjs", "next.js"}) {
+		t.Fatalf("unexpected keywords %+v", query.Keywords)
+	}
+	// Slack searches the keywords of the category only
+	if !reflect.DeepEqual(query.SearchedKeywords, []string{"frontend"}) {
+		t.Fatalf("unexpected searched keywords %+v", query.SearchedKeywords)
+	}
+
+	if query := taxonomy.Expand("go"); !reflect.DeepEqual(query.Emojis, []string{"golang", "gopher"}) {
+		t.Fatalf("expected the emojis of golang, got %+v", query)
+	}
+
+	// unknown technologies are searched as written
+	query = taxonomy.Expand(" :Zig: ")
+	if query.Name != "zig" || !reflect.DeepEqual(query.Emojis, []string{"zig"}) || query.Technologies != nil {
+		t.Fatalf("unexpected query %+v", query)
+	}
+	var none *Taxonomy
+	if query := none.Expand("golang"); query.Name != "golang" || !reflect.DeepEqual(query.Emojis, []string{"golang"}) {
+		t.Fatalf("unexpected query without catalog %+v", query)
+	}
+}
+
+func Test_ParseTaxonomyErrors(t *testing.T) {
+	for expected, catalog := range map[string]string{
+		"has no name":          "technologies:\n  - aliases: [a]\n",
+		"already a name of go": "technologies:\n  - name: go\n  - name: golang\n    aliases: [Go]\n",
+		"unknown parent web":   "technologies:\n  - name: react\n    parents: [web]\n",
+		"is its own parent":    "technologies:\n  - name: a\n    parents: [b]\n  - name: b\n    parents: [a]\n",
+		"cannot unmarshal":     "technologies: 3\n",
+	} {
+		if _, err := ParseTaxonomy([]byte(catalog)); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected an error containing %q, got %v", expected, err)
+		}
+	}
+}
+
+func Test_SlackGetTechonologyPostExpandsAliases(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	s.UseTaxonomy(newTestTaxonomy(t))
+	for ts, reactions := range map[string][]string{
+		"1718600000.000700": {"gopher"},
+		"1718700000.000800": {"golang", "gopher"},
+	} {
+		post := fakeslack.Message{Reactions: reactions}
+		post.Channel = slack.CtxChannel{ID: "C02TIL0001", Name: "today-i-learned"}
+		post.User = "U02MARIE01"
+		post.Timestamp = ts
+		post.Text = "gophers " + ts
+		server.AddMessage(post)
+	}
+
+	r, next, err := s.GetTechonologyPost(context.Background(), "Go", "today-i-learned", TimeRange{}, 10, "")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// one search per emoji and one of the keywords, the post tagged with both is returned once
+	if len(r) != 4 || next != "" {
+		t.Fatalf("expected the 4 posts of both emojis, got %+v, next %q", r, next)
+	}
+	if calls := server.Calls("search.messages"); calls != 3 {
+		t.Fatalf("expected one search per emoji and one of the keywords, got %d", calls)
+	}
+
+	// the cursor walks one search after the other
+	found := map[string]bool{}
+	cursor := ""
+	for page := 0; page == 0 || cursor != ""; page++ {
+		if page > 10 {
+			t.Fatalf("cursor does not end")
+		}
+		r, cursor, err = s.GetTechonologyPost(context.Background(), "golang", "today-i-learned", TimeRange{}, 1, cursor)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		for _, post := range r {
+			found[post.Ts] = true
+		}
+	}
+	if len(found) != 4 {
+		t.Fatalf("expected to page through the 4 posts, got %v", found)
+	}
+}
+
+func Test_SlackGetTechonologyPostSearchesKeywords(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	s.UseTaxonomy(newTestTaxonomy(t))
+	post := fakeslack.Message{}
+	post.Channel = slack.CtxChannel{ID: "C01CONCEPT", Name: "concept-tech"}
+	post.User = "U7D3Q7N8Y"
+	post.Timestamp = "1718600000.000700"
+	post.Text = "We moved the website to Next.js 14"
+	server.AddMessage(post)
+
+	r, _, err := s.GetTechonologyPost(context.Background(), "nextjs", "concept-tech", TimeRange{}, 10, "")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(r) != 1 || r[0].Ts != "1718600000.000700" {
+		t.Fatalf("expected the post naming next.js without its emoji, got %+v", r)
+	}
+	// one search per emoji and one per keyword
+	if calls := server.Calls("search.messages"); calls != 3 {
+		t.Fatalf("expected the searches of nextjs, next.js and the emoji, got %d", calls)
+	}
+
+	// a category searches its own keywords, the technologies under it are found by their emojis
+	r, _, err = s.GetTechonologyPost(context.Background(), "react", "concept-tech", TimeRange{}, 10, "")
+	if err != nil || len(r) != 1 || r[0].Ts != "1718100000.000200" {
+		t.Fatalf("expected the post tagged react, got %+v, %v", r, err)
+	}
+	if calls := server.Calls("search.messages") - 3; calls != 3 {
+		t.Fatalf("expected the searches of the react and nextjs emojis and of react, got %d", calls)
+	}
+}
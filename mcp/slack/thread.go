@@ -0,0 +1,270 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+// repliesPageSize is the number of messages asked per conversations.replies call
+const repliesPageSize = 200
+
+// Thread is a message with the replies posted under it, oldest first
+type Thread struct {
+	Parent  MessageInfo   `json:"parent"`
+	Replies []MessageInfo `json:"replies"`
+}
+
+// ErrMessageNotFound is returned for a channel without message at the timestamp
+var ErrMessageNotFound = errors.New("message not found")
+
+// GetThread returns the message posted at ts in the channel and all of its replies.
+// Authors are resolved from the user directory.
+func (s *SlackService) GetThread(ctx context.Context, channelID string, ts string) (Thread, error) {
+	messages, err := s.fetchReplies(ctx, channelID, ts)
+	var slackErr slack.SlackErrorResponse
+	if errors.As(err, &slackErr) && slackErr.Err == "thread_not_found" {
+		return Thread{}, fmt.Errorf("%w: %s in channel %s", ErrMessageNotFound, ts, channelID)
+	}
+	if err != nil {
+		return Thread{}, err
+	}
+
+	// messages are in order, the parent first
+	infos := []MessageInfo{}
+	for _, msg := range messages {
+		infos = append(infos, s.threadMessageInfo(ctx, msg))
+	}
+	if len(infos) == 0 || infos[0].Ts != ts {
+		return Thread{}, fmt.Errorf("%w: %s in channel %s", ErrMessageNotFound, ts, channelID)
+	}
+	s.renderMessages(ctx, infos)
+
+	return Thread{
+		Parent:  infos[0],
+		Replies: infos[1:],
+	}, nil
+}
+
+// fetchReplies returns the parent message of a thread followed by its replies, oldest first
+func (s *SlackService) fetchReplies(ctx context.Context, channelID string, ts string) ([]slack.Message, error) {
+	params := &slack.GetConversationRepliesParameters{
+		ChannelID: channelID,
+		Timestamp: ts,
+		Limit:     repliesPageSize,
+	}
+
+	messages := []slack.Message{}
+	parentSeen := false
+	for {
+		var page []slack.Message
+		var hasMore bool
+		var next string
+		err := s.limiter.Do(ctx, "conversations.replies", func() error {
+			var err error
+			page, hasMore, next, err = s.client.GetConversationRepliesContext(ctx, params)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, msg := range page {
+			// Slack repeats the parent message on every page
+			if msg.Timestamp == ts {
+				if parentSeen {
+					continue
+				}
+				parentSeen = true
+			}
+			messages = append(messages, msg)
+		}
+		if !hasMore || next == "" {
+			break
+		}
+		params.Cursor = next
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		return tsLess(messages[i].Timestamp, messages[j].Timestamp)
+	})
+	return messages, nil
+}
+
+// threadMessageInfo converts a conversations API message, resolving its author handle
+func (s *SlackService) threadMessageInfo(ctx context.Context, msg slack.Message) MessageInfo {
+	return MessageInfo{
+		Message:           msg.Text,
+		Slack_Author_Name: s.authorName(ctx, msg.User, msg.Username),
+		Slack_id:          msg.User,
+		Ts:                msg.Timestamp,
+		Posted:            tsTime(msg.Timestamp),
+		Permalink:         msg.Permalink,
+		Thread_ts:         msg.ThreadTimestamp,
+		Reply_Count:       msg.ReplyCount,
+	}
+}
+
+// authorName returns the Slack handle of a user, or the fallback when the user is unknown
+func (s *SlackService) authorName(ctx context.Context, userId string, fallback string) string {
+	user, ok, err := s.users.Get(ctx, userId)
+	if err != nil {
+		s.log(ctx).Warn("could not resolve author", zap.String("slack_id", userId), zap.Error(err))
+	}
+	if ok {
+		return user.Slack_Name
+	}
+	return fallback
+}
+
+// addHistoryInfo fills the reply count, thread timestamp and reaction count of
+// search results, which search.messages does not return. The messages of the store
+// are described from it, the others with the conversations.history of each channel
+// between their timestamps, paged until the oldest is covered. It is best effort:
+// messages Slack fails to describe are left as they are.
+func (s *SlackService) addHistoryInfo(ctx context.Context, messages []MessageInfo) {
+	// the messages left to describe, by channel in the order of the results
+	channelIDs := []string{}
+	missing := map[string][]int{}
+	for i := range messages {
+		channelID, ts, threadTs, err := parsePermalink(messages[i].Permalink)
+		if err != nil {
+			continue
+		}
+		if threadTs != "" {
+			// a reply found by the search
+			messages[i].Thread_ts = threadTs
+			continue
+		}
+		if s.store != nil {
+			stored, ok, err := s.store.Message(channelID, ts)
+			if err == nil && ok {
+				messages[i].Thread_ts = stored.Thread_ts
+				messages[i].Reply_Count = stored.Reply_Count
+				messages[i].Reaction_Count = reactionCount(stored)
+				continue
+			}
+		}
+		if _, ok := missing[channelID]; !ok {
+			channelIDs = append(channelIDs, channelID)
+		}
+		missing[channelID] = append(missing[channelID], i)
+	}
+
+	for _, channelID := range channelIDs {
+		s.addChannelHistoryInfo(ctx, channelID, messages, missing[channelID])
+	}
+}
+
+// addChannelHistoryInfo describes the messages at the indexes, all posted in the channel,
+// with the history between the oldest and the latest of them, one call per page of history
+func (s *SlackService) addChannelHistoryInfo(ctx context.Context, channelID string, messages []MessageInfo, indexes []int) {
+	oldest, latest := messages[indexes[0]].Ts, messages[indexes[0]].Ts
+	for _, i := range indexes[1:] {
+		if tsLess(messages[i].Ts, oldest) {
+			oldest = messages[i].Ts
+		}
+		if tsLess(latest, messages[i].Ts) {
+			latest = messages[i].Ts
+		}
+	}
+
+	// the pages come newest first, until the oldest result is covered
+	params := &slack.GetConversationHistoryParameters{
+		ChannelID: channelID,
+		Oldest:    oldest,
+		Latest:    latest,
+		Inclusive: true,
+		Limit:     historyPageSize,
+	}
+	wanted := map[string]bool{}
+	for _, i := range indexes {
+		wanted[messages[i].Ts] = true
+	}
+	byTs := map[string]slack.Message{}
+	for len(byTs) < len(wanted) {
+		var history *slack.GetConversationHistoryResponse
+		err := s.limiter.Do(ctx, "conversations.history", func() error {
+			var err error
+			history, err = s.client.GetConversationHistoryContext(ctx, params)
+			return err
+		})
+		if err != nil {
+			s.log(ctx).Warn("could not get the threads of the posts", zap.String("channel_id", channelID), zap.Int("post_count", len(indexes)), zap.Error(err))
+			break
+		}
+		for _, msg := range history.Messages {
+			if wanted[msg.Timestamp] {
+				byTs[msg.Timestamp] = msg
+			}
+		}
+		if !history.HasMore || history.ResponseMetaData.NextCursor == "" {
+			break
+		}
+		params.Cursor = history.ResponseMetaData.NextCursor
+	}
+
+	for _, i := range indexes {
+		msg, ok := byTs[messages[i].Ts]
+		if !ok {
+			continue
+		}
+		messages[i].Thread_ts = msg.ThreadTimestamp
+		messages[i].Reply_Count = msg.ReplyCount
+		for _, reaction := range msg.Reactions {
+			messages[i].Reaction_Count += reaction.Count
+		}
+	}
+}
+
+// permalinkPath matches the channel and the message timestamp of a permalink,
+// e.g. /archives/C01CONCEPT/p1718000000000100
+var permalinkPath = regexp.MustCompile(`/archives/([A-Z0-9]+)/p(\d{7,})$`)
+
+// parsePermalink returns the channel id, the message timestamp and, for replies,
+// the parent timestamp of a Slack message permalink
+func parsePermalink(permalink string) (string, string, string, error) {
+	u, err := url.Parse(permalink)
+	if err != nil {
+		return "", "", "", fmt.Errorf("invalid permalink %q", permalink)
+	}
+	match := permalinkPath.FindStringSubmatch(u.Path)
+	if match == nil {
+		return "", "", "", fmt.Errorf("invalid permalink %q", permalink)
+	}
+
+	// the timestamp is written without its dot, which precedes the last 6 digits
+	digits := match[2]
+	ts := digits[:len(digits)-6] + "." + digits[len(digits)-6:]
+	threadTs := u.Query().Get("thread_ts")
+	if threadTs == ts {
+		threadTs = ""
+	}
+	return match[1], ts, threadTs, nil
+}
+
+// tsLess compares two Slack timestamps. They are compared as seconds and
+// sequence numbers, since a float64 cannot hold all of their digits.
+func tsLess(a string, b string) bool {
+	aSeconds, aSequence := splitTs(a)
+	bSeconds, bSequence := splitTs(b)
+	if aSeconds != bSeconds {
+		return aSeconds < bSeconds
+	}
+	return aSequence < bSequence
+}
+
+func splitTs(ts string) (int64, int64) {
+	seconds, sequence, _ := strings.Cut(ts, ".")
+	s, _ := strconv.ParseInt(seconds, 10, 64)
+	n, _ := strconv.ParseInt(sequence, 10, 64)
+	return s, n
+}
@@ -0,0 +1,162 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/AlexisZankowitch/concept-insight/mcp/slack/fakeslack"
+	"github.com/slack-go/slack"
+	"github.com/strowk/foxy-contexts/pkg/mcp"
+)
+
+func Test_ParsePermalink(t *testing.T) {
+	channel, ts, threadTs, err := parsePermalink("https://concept.slack.com/archives/C01CONCEPT/p1718000600000110?thread_ts=1718000000.000100&cid=C01CONCEPT")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if channel != "C01CONCEPT" || ts != "1718000600.000110" || threadTs != "1718000000.000100" {
+		t.Fatalf("unexpected permalink parts %q %q %q", channel, ts, threadTs)
+	}
+
+	if _, _, _, err := parsePermalink("https://concept.slack.com/team/U7D3Q7N8Y"); err == nil {
+		t.Fatalf("expected an error for a link that is not a message")
+	}
+}
+
+func Test_SlackGetThread(t *testing.T) {
+	s, _ := newTestService(t, loadFixtures(t))
+
+	thread, err := s.GetThread(context.Background(), "C01CONCEPT", "1718000000.000100")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if thread.Parent.Slack_Author_Name != "alexis" || thread.Parent.Reply_Count != 2 {
+		t.Fatalf("unexpected parent %+v", thread.Parent)
+	}
+	if len(thread.Replies) != 2 {
+		t.Fatalf("expected 2 replies, got %+v", thread.Replies)
+	}
+	// replies are ordered and their authors resolved
+	if thread.Replies[0].Slack_Author_Name != "marie.curie" || thread.Replies[1].Slack_Author_Name != "linus" {
+		t.Fatalf("unexpected replies %+v", thread.Replies)
+	}
+}
+
+func Test_SlackGetTechonologyPostThreadInfo(t *testing.T) {
+	s, _ := newTestService(t, loadFixtures(t))
+
+	r, _, err := s.GetTechonologyPost(context.Background(), "golang", "concept-tech", TimeRange{}, 20, "")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(r) != 1 || r[0].Reply_Count != 2 || r[0].Thread_ts != "1718000000.000100" {
+		t.Fatalf("expected the thread info of the post, got %+v", r)
+	}
+}
+
+func Test_SlackAddHistoryInfo(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	results := func() []MessageInfo {
+		infos := []MessageInfo{}
+		for _, permalink := range []string{
+			"https://concept.slack.com/archives/C01CONCEPT/p1718300000000400",
+			"https://concept.slack.com/archives/C02TIL0001/p1718200000000300",
+			"https://concept.slack.com/archives/C01CONCEPT/p1718000000000100",
+			"https://concept.slack.com/archives/C01CONCEPT/p1718100000000200",
+		} {
+			_, ts, _, _ := parsePermalink(permalink)
+			infos = append(infos, MessageInfo{Ts: ts, Permalink: permalink})
+		}
+		return infos
+	}
+
+	// one history call per channel, not per post
+	r := results()
+	s.addHistoryInfo(context.Background(), r)
+	if calls := server.Calls("conversations.history"); calls != 2 {
+		t.Fatalf("expected a history call per channel, got %d", calls)
+	}
+	if r[2].Reply_Count != 2 || r[2].Thread_ts != "1718000000.000100" || r[0].Reply_Count != 0 {
+		t.Fatalf("expected the thread info of the posts, got %+v", r)
+	}
+
+	// the posts of a synced channel are described from the store
+	store := newTestStore(t)
+	if err := s.UseStore(store); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	syncer := NewSyncer(s, store, []Channel{conceptTech}, time.Minute, time.Hour)
+	if err := syncer.SyncAll(context.Background()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	before := server.Calls("conversations.history")
+	r = results()
+	s.addHistoryInfo(context.Background(), r)
+	if calls := server.Calls("conversations.history") - before; calls != 1 {
+		t.Fatalf("expected a history call for today-i-learned only, got %d", calls)
+	}
+	if r[2].Reply_Count != 2 || r[2].Thread_ts != "1718000000.000100" {
+		t.Fatalf("expected the thread info of the stored post, got %+v", r[2])
+	}
+}
+
+func Test_SlackAddHistoryInfoPages(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	// more messages than a page of history between the two results
+	for n := 1; n <= historyPageSize+50; n++ {
+		post := fakeslack.Message{}
+		post.Channel = slack.CtxChannel{ID: "C01CONCEPT", Name: "concept-tech"}
+		post.User = "U02MARIE01"
+		post.Timestamp = fmt.Sprintf("%d.000000", 1718000000+n)
+		post.Text = "daily update"
+		server.AddMessage(post)
+	}
+
+	r := []MessageInfo{
+		{Ts: "1718300000.000400", Permalink: "https://concept.slack.com/archives/C01CONCEPT/p1718300000000400"},
+		{Ts: "1718000000.000100", Permalink: "https://concept.slack.com/archives/C01CONCEPT/p1718000000000100"},
+	}
+	s.addHistoryInfo(context.Background(), r)
+	// the oldest result is on the second page
+	if r[1].Reply_Count != 2 || r[1].Thread_ts != "1718000000.000100" {
+		t.Fatalf("expected the thread info of the oldest post, got %+v", r[1])
+	}
+	if calls := server.Calls("conversations.history"); calls != 2 {
+		t.Fatalf("expected 2 pages of history, got %d", calls)
+	}
+}
+
+func Test_GetThreadTool(t *testing.T) {
+	s, _ := newTestService(t, loadFixtures(t))
+	tool := NewGetThread(s, newTestChannels(t, s))
+
+	// the permalink of a reply returns the whole thread
+	result := tool.CallStructured(context.Background(), map[string]interface{}{
+		"permalink": "https://concept.slack.com/archives/C01CONCEPT/p1718000600000110?thread_ts=1718000000.000100&cid=C01CONCEPT",
+		"limit":     float64(1),
+	})
+	if *result.IsError {
+		t.Fatalf("unexpected error result %+v", result)
+	}
+	thread, ok := result.StructuredContent.(ThreadOutput)
+	if !ok || thread.Parent.Ts != "1718000000.000100" || len(thread.Replies) != 1 {
+		t.Fatalf("unexpected thread %+v", result.StructuredContent)
+	}
+	if len(result.Content) != 2 {
+		t.Fatalf("expected a cursor for the remaining reply, got %+v", result.Content)
+	}
+
+	result = tool.CallStructured(context.Background(), map[string]interface{}{
+		"channel": "random",
+		"ts":      "1718400000.000500",
+	})
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !*result.IsError || !ok {
+		t.Fatalf("expected an error for a channel that is not allowed, got %+v", result)
+	}
+	if text.Text == "" {
+		t.Fatalf("expected an error message")
+	}
+}
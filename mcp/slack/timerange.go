@@ -0,0 +1,143 @@
+package slack
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// sincePattern matches the relative periods of the since argument, e.g. 90d
+var sincePattern = regexp.MustCompile(`^(\d+)([hdwmy])$`)
+
+// TimeRange selects the messages posted from After included to Before excluded,
+// a zero bound leaves that side open
+type TimeRange struct {
+	After  time.Time
+	Before time.Time
+}
+
+// Contains reports whether a message posted at t is in the range
+func (r TimeRange) Contains(t time.Time) bool {
+	if !r.After.IsZero() && t.Before(r.After) {
+		return false
+	}
+	if !r.Before.IsZero() && !t.Before(r.Before) {
+		return false
+	}
+	return true
+}
+
+// addTo restricts a search to the days of the range. Slack compares days, not times, and
+// excludes the days given to after: and before:, so the search is one day wider on each
+// side and its results must be filtered with Contains.
+func (r TimeRange) addTo(query *SearchQuery) *SearchQuery {
+	if !r.After.IsZero() {
+		query.After(r.After.UTC().AddDate(0, 0, -1))
+	}
+	if !r.Before.IsZero() {
+		query.Before(r.Before.UTC().AddDate(0, 0, 1))
+	}
+	return query
+}
+
+// timeRangeArgs extracts the optional after, before and since tool arguments, see ParseTimeRange
+func timeRangeArgs(args map[string]interface{}, now time.Time) (TimeRange, error) {
+	values := map[string]string{}
+	for _, name := range []string{"after", "before", "since"} {
+		raw, ok := args[name]
+		if !ok || raw == nil {
+			continue
+		}
+		value, ok := raw.(string)
+		if !ok {
+			return TimeRange{}, fmt.Errorf("'%s' must be a string", name)
+		}
+		values[name] = value
+	}
+	return ParseTimeRange(values["after"], values["before"], values["since"], now)
+}
+
+// ParseTimeRange parses the optional after and before dates and the since period.
+// since is relative to now and can not be combined with after.
+func ParseTimeRange(after string, before string, since string, now time.Time) (TimeRange, error) {
+	var r TimeRange
+	var err error
+	if r.After, err = parseTime("after", after); err != nil {
+		return r, err
+	}
+	if r.Before, err = parseTime("before", before); err != nil {
+		return r, err
+	}
+
+	if since != "" {
+		if !r.After.IsZero() {
+			return r, fmt.Errorf("'since' and 'after' can not be used together")
+		}
+		r.After, err = parseSince(since, now)
+		if err != nil {
+			return r, err
+		}
+	}
+
+	if !r.After.IsZero() && !r.Before.IsZero() && !r.After.Before(r.Before) {
+		return r, fmt.Errorf("'after' must be before 'before'")
+	}
+	return r, nil
+}
+
+// parseTime parses a date, YYYY-MM-DD at midnight UTC, or an RFC3339 time
+func parseTime(name string, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%s' must be a date as YYYY-MM-DD or RFC3339, got %q", name, value)
+	}
+	return t, nil
+}
+
+// parseSince returns the start of a period ending now, such as 90d, 12w, 6m or 1y
+func parseSince(since string, now time.Time) (time.Time, error) {
+	match := sincePattern.FindStringSubmatch(since)
+	if match == nil {
+		return time.Time{}, fmt.Errorf("'since' must be a number followed by h, d, w, m or y (e.g. 90d), got %q", since)
+	}
+	n, err := strconv.Atoi(match[1])
+	if err != nil || n == 0 {
+		return time.Time{}, fmt.Errorf("'since' must be a positive period, got %q", since)
+	}
+	switch match[2] {
+	case "h":
+		return now.Add(-time.Duration(n) * time.Hour), nil
+	case "d":
+		return now.AddDate(0, 0, -n), nil
+	case "w":
+		return now.AddDate(0, 0, -7*n), nil
+	case "m":
+		return now.AddDate(0, -n, 0), nil
+	default:
+		return now.AddDate(-n, 0, 0), nil
+	}
+}
+
+// withTimeRangeProperties adds the after, before and since arguments to the properties of a tool
+func withTimeRangeProperties(properties map[string]map[string]interface{}) map[string]map[string]interface{} {
+	properties["after"] = map[string]interface{}{
+		"type":        "string",
+		"description": "Only return posts from this date on, as YYYY-MM-DD or RFC3339",
+	}
+	properties["before"] = map[string]interface{}{
+		"type":        "string",
+		"description": "Only return posts before this date, as YYYY-MM-DD or RFC3339",
+	}
+	properties["since"] = map[string]interface{}{
+		"type":        "string",
+		"description": "Only return posts of the last period, e.g. 90d, 12w, 6m or 1y. Replaces 'after'",
+	}
+	return properties
+}
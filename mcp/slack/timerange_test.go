@@ -0,0 +1,113 @@
+package slack
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_TimeRangeArgs(t *testing.T) {
+	now := time.Date(2024, 6, 20, 12, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		args     map[string]interface{}
+		expected TimeRange
+		err      string
+	}{
+		{map[string]interface{}{}, TimeRange{}, ""},
+		{
+			map[string]interface{}{"after": "2024-06-01", "before": "2024-06-15T10:00:00+02:00"},
+			TimeRange{After: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), Before: time.Date(2024, 6, 15, 8, 0, 0, 0, time.UTC)},
+			"",
+		},
+		{map[string]interface{}{"since": "90d"}, TimeRange{After: now.AddDate(0, 0, -90)}, ""},
+		{map[string]interface{}{"since": "2w"}, TimeRange{After: now.AddDate(0, 0, -14)}, ""},
+		{map[string]interface{}{"since": "6m"}, TimeRange{After: now.AddDate(0, -6, 0)}, ""},
+		{map[string]interface{}{"since": "12h"}, TimeRange{After: now.Add(-12 * time.Hour)}, ""},
+		{map[string]interface{}{"after": "last week"}, TimeRange{}, "'after' must be a date"},
+		{map[string]interface{}{"before": float64(2024)}, TimeRange{}, "'before' must be a string"},
+		{map[string]interface{}{"since": "3 months"}, TimeRange{}, "'since' must be a number"},
+		{map[string]interface{}{"since": "0d"}, TimeRange{}, "'since' must be a positive period"},
+		{map[string]interface{}{"since": "90d", "after": "2024-01-01"}, TimeRange{}, "can not be used together"},
+		{map[string]interface{}{"after": "2024-06-15", "before": "2024-06-15"}, TimeRange{}, "'after' must be before 'before'"},
+	} {
+		window, err := timeRangeArgs(test.args, now)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%v: expected an error containing %q, got %v", test.args, test.err, err)
+			}
+			continue
+		}
+		if err != nil || !window.After.Equal(test.expected.After) || !window.Before.Equal(test.expected.Before) {
+			t.Errorf("%v: expected %+v, got %+v, %v", test.args, test.expected, window, err)
+		}
+	}
+}
+
+func Test_TimeRangeContains(t *testing.T) {
+	day := time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)
+	window := TimeRange{After: day, Before: day.AddDate(0, 0, 1)}
+
+	if !window.Contains(day) || !window.Contains(day.Add(23*time.Hour)) {
+		t.Fatalf("expected the day to be in the range")
+	}
+	if window.Contains(day.Add(-time.Second)) || window.Contains(day.AddDate(0, 0, 1)) {
+		t.Fatalf("expected the range to exclude the previous and next days")
+	}
+	if !(TimeRange{}).Contains(day) {
+		t.Fatalf("expected an empty range to contain everything")
+	}
+}
+
+func Test_TimeRangeSearchQuery(t *testing.T) {
+	window := TimeRange{
+		After:  time.Date(2024, 6, 10, 23, 0, 0, 0, time.FixedZone("CEST", 2*3600)),
+		Before: time.Date(2024, 6, 16, 0, 0, 0, 0, time.UTC),
+	}
+	query, err := window.addTo(NewSearchQuery().Has("golang")).Build()
+	if err != nil || query != "has::golang: after:2024-06-09 before:2024-06-17" {
+		t.Fatalf("unexpected query %q, %v", query, err)
+	}
+}
+
+func Test_SlackGetPostByUserTimeRange(t *testing.T) {
+	s, _ := newTestService(t, loadFixtures(t))
+
+	window := TimeRange{After: time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)}
+	r, _, err := s.GetPostByUser(context.Background(), "U7D3Q7N8Y", testChannels, window, 20, "")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(r) != 1 || r[0].Ts != "1718500000.000600" {
+		t.Fatalf("expected the post of 2024-06-16 only, got %+v", r)
+	}
+	if !r[0].Posted.Equal(time.Unix(1718500000, 600000).UTC()) {
+		t.Fatalf("unexpected posted time %v", r[0].Posted)
+	}
+}
+
+func Test_FindTechnologyPostToolTimeRange(t *testing.T) {
+	s, _ := newTestService(t, loadFixtures(t))
+	tool := NewFindTechnologyPost(s, newTestChannels(t, s))
+
+	result := tool.CallStructured(context.Background(), map[string]interface{}{
+		"technology": "golang",
+		"before":     "2024-06-12",
+	})
+	if *result.IsError {
+		t.Fatalf("unexpected error result %+v", result)
+	}
+	output, ok := result.StructuredContent.(PostsOutput)
+	if !ok || len(output.Posts) != 1 || output.Posts[0].Ts != "1718000000.000100" {
+		t.Fatalf("expected the post of 2024-06-10 only, got %+v", result.StructuredContent)
+	}
+
+	result = tool.CallStructured(context.Background(), map[string]interface{}{
+		"technology": "golang",
+		"since":      "90d",
+		"after":      "2024-06-12",
+	})
+	if !*result.IsError {
+		t.Fatalf("expected since and after to be rejected, got %+v", result)
+	}
+}
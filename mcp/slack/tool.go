@@ -2,33 +2,41 @@ package slack
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/AlexisZankowitch/concept-insight/mcp/mcptool"
+	"github.com/AlexisZankowitch/concept-insight/toolid"
 	"github.com/AlexisZankowitch/concept-insight/utils"
-	"github.com/strowk/foxy-contexts/pkg/fxctx"
 	"github.com/strowk/foxy-contexts/pkg/mcp"
 )
 
 
-func NewGetLastestPostsByUserId(slack *SlackService) fxctx.Tool {
-	return fxctx.NewTool(
+// defaultUserPostsLimit is the number of posts returned when the caller gives no limit
+const defaultUserPostsLimit = 200
+
+func NewGetLastestPostsByUserId(slack *SlackService, channels *ChannelSet) mcptool.Tool {
+	return mcptool.NewTool(
 		&mcp.Tool{
-			Name: "Get the latest 200 posts by slack user id",
-			Description: utils.Ptr("Retrieve the lastest 200 posts of a user ifentified by its slack user id"),
+			Name: toolid.Name(toolid.LatestPostsByUser),
+			Description: utils.Ptr("Retrieve the lastest posts of a user ifentified by its slack user id, 200 by default. Use the returned cursor to get older posts, and after, before or since to limit them to a period."),
 			InputSchema: mcp.ToolInputSchema{
 				Type: "object",
-				Properties: map[string]map[string]interface{}{
+				Properties: withPageProperties(withTimeRangeProperties(map[string]map[string]interface{}{
 					"slack_user_id": {
 						"type": "string",
 						"description": "slack user id of the user we want to list the post from",
 					},
-				},
+					"channels": channelsInputProperty(channels),
+				}), defaultUserPostsLimit),
 				Required: []string{"slack_user_id"},
 			},
 		},
-		func(ctx context.Context, args map[string]interface{}) *mcp.CallToolResult {
-			fmt.Println("Received a get latest post by user id command")
+		mcptool.SchemaFor(PostsOutput{}),
+		func(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, interface{}) {
 			slackUserId, ok := args["slack_user_id"].(string)
 			if !ok || slackUserId == "" {
 				return &mcp.CallToolResult{
@@ -39,10 +47,54 @@ func NewGetLastestPostsByUserId(slack *SlackService) fxctx.Tool {
 							Text: "Error: slack user id is required and must be a string",
 						},
 					},
-				}
+				}, nil
+			}
+
+			limit, cursor, err := pageArgs(args, defaultUserPostsLimit)
+			var window TimeRange
+			if err == nil {
+				window, err = timeRangeArgs(args, time.Now())
+			}
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error: %v", err),
+						},
+					},
+				}, nil
 			}
 
-			posts, err := slack.GetPostByUser(slackUserId)
+			requested, err := channelsArg(args)
+			var searched []Channel
+			if err == nil {
+				searched, err = channels.Select(toolid.LatestPostsByUser, requested)
+			}
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error: %v", err),
+						},
+					},
+				}, nil
+			}
+
+			posts, next, err := slack.GetPostByUser(ctx, slackUserId, channelNames(searched), window, limit, cursor)
+			var throttled *ThrottledError
+			if errors.As(err, &throttled) {
+				output := PostsOutput{Posts: []MessageInfo{}, Page: Page{Notice: throttledNotice(throttled)}}
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(false),
+					Content: []interface{}{
+						textContent(output.Notice),
+					},
+				}, output
+			}
 			if err != nil {
 				return &mcp.CallToolResult{
 					IsError: utils.Ptr(true),
@@ -52,38 +104,44 @@ func NewGetLastestPostsByUserId(slack *SlackService) fxctx.Tool {
 							Text: fmt.Sprintf("Error fetching user's post: %v", err),
 						},
 					},
-				}	
+				}, nil	
 			}
 
+			output := PostsOutput{Posts: posts, Page: Page{Next_Cursor: next}}
 			return &mcp.CallToolResult{
 				IsError: utils.Ptr(false),
-				Content: []interface{}{
-					posts,
-				},
-			}
+				Content: pageContent(renderPosts(posts), output.Page),
+			}, output
 		},
 	)
 }			
-func NewGetConceptUserDetails(slack *SlackService) fxctx.Tool {
+// defaultUserDetailsLimit is the number of users returned when the caller gives no limit
+const defaultUserDetailsLimit = 20
+
+func NewGetConceptUserDetails(slack *SlackService) mcptool.Tool {
 
-	return fxctx.NewTool(
+	return mcptool.NewTool(
 		&mcp.Tool{
-			Name: "Get user details",
+			Name: toolid.Name(toolid.UserDetails),
 			Description: utils.Ptr("Get the user details of a Concept employee using its slack id"),
 			InputSchema: mcp.ToolInputSchema{
 				Type: "object",
-				Properties: map[string]map[string]interface{}{
+				Properties: withPageProperties(map[string]map[string]interface{}{
 					"search": {
 						"type": "string",
 						"description": "search parameter, could be slack_id, part of the name of the user you are looking for",
 					},
-				},
+					"refresh": {
+						"type": "boolean",
+						"description": "reload the user directory from Slack instead of using the cached one",
+					},
+				}, defaultUserDetailsLimit),
 				Required: []string{"search"},
 			},
 		},
+		mcptool.SchemaFor(UsersOutput{}),
 
-		func(ctx context.Context, args map[string]interface{}) *mcp.CallToolResult {
-			fmt.Println("Received a Get Concept User details command")
+		func(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, interface{}) {
 			search, ok := args["search"].(string)
 			if !ok || search == "" {
 				return &mcp.CallToolResult{
@@ -94,31 +152,50 @@ func NewGetConceptUserDetails(slack *SlackService) fxctx.Tool {
 							Text: "Error: 'search' parameter is required and must be a string",
 						},
 					},
-				}
+				}, nil
 
 			}
-			users, err := slack.ListUsers()
+			limit, cursor, err := pageArgs(args, defaultUserDetailsLimit)
 			if err != nil {
 				return &mcp.CallToolResult{
 					IsError: utils.Ptr(true),
 					Content: []interface{}{
 						mcp.TextContent{
 							Type: "text",
-							Text: fmt.Sprintf("Error fetching users: %v", err),
+							Text: fmt.Sprintf("Error: %v", err),
 						},
 					},
-				}	
+				}, nil
 			}
 
-			// lowercase search for case-insensitive match
-			searchLower := strings.ToLower(search)
+			// the directory is cached, refresh it when the agent asks for it
+			if refresh, _ := args["refresh"].(bool); refresh {
+				err = slack.Users().Refresh(ctx)
+			}
 			var matches []ConceptUser
-			for _, u := range users {
-				if strings.Contains(strings.ToLower(u.Slack_id), searchLower) ||
-					strings.Contains(strings.ToLower(u.Slack_Name), searchLower) ||
-					strings.Contains(strings.ToLower(u.Real_Name), searchLower) {
-					matches = append(matches, u)
-				}
+			if err == nil {
+				matches, err = slack.Users().Search(ctx, search)
+			}
+			var throttled *ThrottledError
+			if errors.As(err, &throttled) {
+				output := UsersOutput{Users: []ConceptUser{}, Page: Page{Notice: throttledNotice(throttled)}}
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(false),
+					Content: []interface{}{
+						textContent(output.Notice),
+					},
+				}, output
+			}
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error fetching users: %v", err),
+						},
+					},
+				}, nil	
 			}
 
 			if len(matches) == 0 {
@@ -130,44 +207,60 @@ func NewGetConceptUserDetails(slack *SlackService) fxctx.Tool {
 							Text: fmt.Sprintf("No users found matching '%s'", search),
 						},
 					},
-				}
+				}, UsersOutput{Users: []ConceptUser{}}
 			}
 
 
+			page, next, err := paginate(matches, limit, cursor)
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error: %v", err),
+						},
+					},
+				}, nil
+			}
+
 			// return results
+			output := UsersOutput{Users: page, Page: Page{Next_Cursor: next}}
 			return &mcp.CallToolResult{
 				IsError: utils.Ptr(false),
-				Content: []interface{}{
-					matches,
-				},
-			}
+				Content: pageContent(renderUsers(page), output.Page),
+			}, output
 
 
 		},
 	)
 }
 
-func NewFindTechnologyPost(slackService *SlackService) fxctx.Tool {
-	return fxctx.NewTool(
+// defaultTechnologyPostsLimit is the number of posts per channel returned when the caller gives no limit
+const defaultTechnologyPostsLimit = 20
+
+func NewFindTechnologyPost(slackService *SlackService, channelSet *ChannelSet) mcptool.Tool {
+	return mcptool.NewTool(
 		// Tool definition for MCP
 		&mcp.Tool{
-			Name:        "find-technology-posts",
-			Description: utils.Ptr("Find posts from a specific technology. Returns an array containing the post, the slack id of the author, the timestamp of the message."),
+			Name:        toolid.FindTechnologyPosts,
+			Description: utils.Ptr("Find posts from a specific technology, with their author, timestamp, permalink, reply and reaction counts. The limit applies to each searched channel. In the channels synced locally, posts mentioning the technology without its emoji are also returned, ranked with a score and a snippet. Use after, before or since to limit the posts to a period."),
 			InputSchema: mcp.ToolInputSchema{
 				Type: "object",
-				Properties: map[string]map[string]interface{}{
+				Properties: withPageProperties(withTimeRangeProperties(map[string]map[string]interface{}{
 					"technology": {
 						"type":        "string",
-						"description": "The technology to search for (e.g., python, react, golang)",
+						"description": "The technology or category to search for (e.g., python, react, golang, frontend). Aliases such as k8s are resolved, see list-technologies",
 					},
-				},
+					"channels": channelsInputProperty(channelSet),
+				}), defaultTechnologyPostsLimit),
 				Required: []string{"technology"},
 			},
 		},
+		mcptool.SchemaFor(PostsOutput{}),
 
 		// Tool execution callback
-		func(ctx context.Context, args map[string]interface{}) *mcp.CallToolResult {
-			fmt.Println("Received a Find technology post command")
+		func(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, interface{}) {
 			// Extract technology from arguments
 			tech, ok := args["technology"].(string)
 			if !ok || tech == "" {
@@ -179,43 +272,777 @@ func NewFindTechnologyPost(slackService *SlackService) fxctx.Tool {
 							Text: "Error: 'technology' parameter is required and must be a string",
 						},
 					},
-				}
+				}, nil
 			}
 
-			// Search in both channels
-			channels := []string{"concept-tech", "today-I-learned"}
-			var allMessages []MessageInfo
-			var searchErrors []string
+			limit, cursor, err := pageArgs(args, defaultTechnologyPostsLimit)
+			var window TimeRange
+			if err == nil {
+				window, err = timeRangeArgs(args, time.Now())
+			}
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error: %v", err),
+						},
+					},
+				}, nil
+			}
 
+			requested, err := channelsArg(args)
+			var searched []Channel
+			if err == nil {
+				searched, err = channelSet.Select(toolid.FindTechnologyPosts, requested)
+			}
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error: %v", err),
+						},
+					},
+				}, nil
+			}
+
+			// Search in every channel
+			channels := channelNames(searched)
+			channelCursors := map[string]string{}
 			for _, channel := range channels {
-				messages, err := slackService.GetTechonologyPost(tech, channel)
+				channelCursors[channel] = ""
+			}
+			if cursor != "" {
+				// channels missing from the cursor have no results left
+				channelCursors, err = decodeChannelCursors(cursor)
 				if err != nil {
-					searchErrors = append(searchErrors, fmt.Sprintf("Error searching in %s: %v", channel, err))
+					return &mcp.CallToolResult{
+						IsError: utils.Ptr(true),
+						Content: []interface{}{
+							mcp.TextContent{
+								Type: "text",
+								Text: fmt.Sprintf("Error: %v", err),
+							},
+						},
+					}, nil
+				}
+			}
+
+			// search the channels concurrently, a failing channel does not hide the others
+			searchedChannels := []string{}
+			for _, channel := range channels {
+				if _, ok := channelCursors[channel]; ok {
+					searchedChannels = append(searchedChannels, channel)
+				}
+			}
+			results, statuses := searchChannels(ctx, searchedChannels, func(ctx context.Context, channel string) (channelResult, error) {
+				messages, next, err := slackService.GetTechonologyPost(ctx, tech, channel, window, limit, channelCursors[channel])
+				return channelResult{posts: messages, next: next}, err
+			})
+
+			nextCursors := map[string]string{}
+			failed := []string{}
+			for i, status := range statuses {
+				channel := status.Channel
+				if status.Status == ChannelOK {
+					nextCursors[channel] = results[i].next
 					continue
 				}
-				allMessages = append(allMessages, messages...)
+				if status.Status == ChannelError {
+					failed = append(failed, fmt.Sprintf("%s: %s", channel, status.Error))
+				}
+				// keep the channel in the cursor so the next call retries it, the store and the
+				// searches page differently so a channel failing on its first page starts over
+				channelCursor := channelCursors[channel]
+				if channelCursor == "" {
+					channelCursor = channelRestart
+				}
+				nextCursors[channel] = channelCursor
 			}
+			allMessages := mergeChannelPosts(results)
 
-			// If we have errors but no messages, return error
-			if len(allMessages) == 0 && len(searchErrors) > 0 {
+			// every channel failed
+			if len(failed) == len(statuses) && len(failed) > 0 {
 				return &mcp.CallToolResult{
 					IsError: utils.Ptr(true),
 					Content: []interface{}{
 						mcp.TextContent{
 							Type: "text",
-							Text: fmt.Sprintf("Failed to retrieve messages: %s", strings.Join(searchErrors, "; ")),
+							Text: fmt.Sprintf("Failed to retrieve messages: %s", strings.Join(failed, "; ")),
 						},
 					},
+				}, nil
+			}
+
+			output := PostsOutput{Posts: allMessages, Channels: statuses, Page: Page{Next_Cursor: encodeChannelCursors(nextCursors), Notice: incompleteNotice(statuses)}}
+			return &mcp.CallToolResult{
+				Content: pageContent(renderPosts(allMessages), output.Page),
+				IsError: utils.Ptr(false),
+			}, output
+		},
+	)
+}
+
+// defaultThreadRepliesLimit is the number of replies returned when the caller gives no limit
+const defaultThreadRepliesLimit = 100
+
+func NewGetThread(slack *SlackService, channels *ChannelSet) mcptool.Tool {
+	return mcptool.NewTool(
+		&mcp.Tool{
+			Name:        toolid.GetThread,
+			Description: utils.Ptr("Get a post and the replies of its thread, oldest first. Identify the post with its permalink, or with its channel and timestamp. Use it on posts with a reply count to read the discussion."),
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: withPageProperties(map[string]map[string]interface{}{
+					"permalink": {
+						"type":        "string",
+						"description": "Permalink of the post, as returned by the other tools",
+					},
+					"channel": {
+						"type":        "string",
+						"description": "Channel name or id of the post, when no permalink is given",
+					},
+					"ts": {
+						"type":        "string",
+						"description": "Timestamp of the post (ts or thread_ts), when no permalink is given",
+					},
+				}, defaultThreadRepliesLimit),
+			},
+		},
+		mcptool.SchemaFor(ThreadOutput{}),
+		func(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, interface{}) {
+			permalink, _ := args["permalink"].(string)
+			channelArg, _ := args["channel"].(string)
+			ts, _ := args["ts"].(string)
+
+			var err error
+			if permalink != "" {
+				var threadTs string
+				channelArg, ts, threadTs, err = parsePermalink(permalink)
+				// the permalink of a reply points to its thread
+				if threadTs != "" {
+					ts = threadTs
 				}
+			} else if channelArg == "" || ts == "" {
+				err = fmt.Errorf("either 'permalink' or both 'channel' and 'ts' are required")
+			}
+			var channel Channel
+			if err == nil {
+				channel, err = channels.Lookup(channelArg)
+			}
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error: %v", err),
+						},
+					},
+				}, nil
+			}
+
+			limit, cursor, err := pageArgs(args, defaultThreadRepliesLimit)
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error: %v", err),
+						},
+					},
+				}, nil
 			}
 
+			thread, err := slack.GetThread(ctx, channel.ID, ts)
+			var throttled *ThrottledError
+			if errors.As(err, &throttled) {
+				output := ThreadOutput{Thread: Thread{Replies: []MessageInfo{}}, Page: Page{Notice: throttledNotice(throttled)}}
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(false),
+					Content: []interface{}{
+						textContent(output.Notice),
+					},
+				}, output
+			}
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error fetching thread: %v", err),
+						},
+					},
+				}, nil
+			}
+
+			var next string
+			thread.Replies, next, err = paginate(thread.Replies, limit, cursor)
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error: %v", err),
+						},
+					},
+				}, nil
+			}
 
+			output := ThreadOutput{Thread: thread, Page: Page{Next_Cursor: next}}
 			return &mcp.CallToolResult{
+				IsError: utils.Ptr(false),
+				Content: pageContent(renderThread(thread), output.Page),
+			}, output
+		},
+	)
+}
+
+// defaultExpertsLimit is the number of experts returned when the caller gives no limit
+const defaultExpertsLimit = 10
+
+func NewFindExperts(slack *SlackService, channels *ChannelSet) mcptool.Tool {
+	return mcptool.NewTool(
+		&mcp.Tool{
+			Name:        toolid.FindExperts,
+			Description: utils.Ptr("Find the people who know a technology best, ranked by the posts they tagged with it, the reactions and thread replies they received and how recent they are. Each expert comes with their user details, their top posts and why they ranked."),
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: withPageProperties(map[string]map[string]interface{}{
+					"technology": {
+						"type":        "string",
+						"description": "The technology or category to find experts of (e.g., python, react, golang, frontend). Aliases such as k8s are resolved, see list-technologies",
+					},
+					"channels": channelsInputProperty(channels),
+				}, defaultExpertsLimit),
+				Required: []string{"technology"},
+			},
+		},
+		mcptool.SchemaFor(ExpertsOutput{}),
+		func(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, interface{}) {
+			tech, ok := args["technology"].(string)
+			if !ok || tech == "" {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: "Error: 'technology' parameter is required and must be a string",
+						},
+					},
+				}, nil
+			}
+
+			limit, cursor, err := pageArgs(args, defaultExpertsLimit)
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error: %v", err),
+						},
+					},
+				}, nil
+			}
+
+			requested, err := channelsArg(args)
+			var searched []Channel
+			if err == nil {
+				searched, err = channels.Select(toolid.FindExperts, requested)
+			}
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error: %v", err),
+						},
+					},
+				}, nil
+			}
+
+			experts, err := slack.FindExperts(ctx, tech, channelNames(searched))
+			var throttled *ThrottledError
+			if errors.As(err, &throttled) {
+				output := ExpertsOutput{Experts: []Expert{}, Page: Page{Notice: throttledNotice(throttled)}}
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(false),
+					Content: []interface{}{
+						textContent(output.Notice),
+					},
+				}, output
+			}
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error finding experts: %v", err),
+						},
+					},
+				}, nil
+			}
+
+			if len(experts) == 0 {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(false),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("No experts found for '%s'", tech),
+						},
+					},
+				}, ExpertsOutput{Experts: []Expert{}}
+			}
+
+			page, next, err := paginate(experts, limit, cursor)
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error: %v", err),
+						},
+					},
+				}, nil
+			}
+
+			output := ExpertsOutput{Experts: page, Page: Page{Next_Cursor: next}}
+			return &mcp.CallToolResult{
+				IsError: utils.Ptr(false),
+				Content: pageContent(renderExperts(page), output.Page),
+			}, output
+		},
+	)
+}
+
+// throttledNotice tells the agent Slack is rate limiting us and when to retry,
+// so a throttled call is not reported as a failure. The missing items are named
+// when the other results could be fetched.
+func throttledNotice(err *ThrottledError, missing ...string) string {
+	if len(missing) > 0 {
+		return fmt.Sprintf("Slack is rate limiting requests, the results of %s could not be fetched. Retry in %s.", strings.Join(missing, ", "), err.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("Slack is rate limiting requests, no results could be fetched. Retry in %s.", err.RetryAfter.Round(time.Second))
+}
+
+func NewListTechnologies(slack *SlackService) mcptool.Tool {
+	return mcptool.NewTool(
+		&mcp.Tool{
+			Name:        toolid.ListTechnologies,
+			Description: utils.Ptr("List the technology catalog used by the search tools: the name of each technology, its aliases, the Slack emojis tagging its posts and its parent categories. Searching a technology also searches the technologies under it, e.g. frontend includes react."),
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]map[string]interface{}{
+					"category": {
+						"type":        "string",
+						"description": "Only list this technology or category and the technologies under it (e.g., frontend, devops)",
+					},
+				},
+			},
+		},
+		mcptool.SchemaFor(TechnologiesOutput{}),
+		func(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, interface{}) {
+			technologies := slack.Taxonomy().Technologies()
+			if category, _ := args["category"].(string); category != "" {
+				tech, ok := slack.Taxonomy().Lookup(category)
+				if !ok {
+					return &mcp.CallToolResult{
+						IsError: utils.Ptr(true),
+						Content: []interface{}{
+							mcp.TextContent{
+								Type: "text",
+								Text: fmt.Sprintf("Error: unknown technology '%s'", category),
+							},
+						},
+					}, nil
+				}
+				technologies = slack.Taxonomy().Descendants(tech)
+			}
+
+			output := TechnologiesOutput{Technologies: technologies}
+			return &mcp.CallToolResult{
+				IsError: utils.Ptr(false),
 				Content: []interface{}{
-					allMessages,
-				}, 
+					textContent(renderTechnologies(technologies)),
+				},
+			}, output
+		},
+	)
+}
+
+func NewTechnologyTrends(slack *SlackService, channels *ChannelSet) mcptool.Tool {
+	return mcptool.NewTool(
+		&mcp.Tool{
+			Name:        toolid.TechnologyTrends,
+			Description: utils.Ptr("Count the posts tagged with one or more technologies per week or month, with the number of distinct authors per period and whether the activity is rising, falling or stable. Use it to answer adoption questions such as whether a technology is still used. Covers the last 26 weeks or 12 months unless after or since is given."),
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: withTimeRangeProperties(map[string]map[string]interface{}{
+					"technologies": {
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "The technologies or categories to compare (e.g., elixir, golang, frontend). Aliases such as k8s are resolved, see list-technologies",
+					},
+					"interval": {
+						"type":        "string",
+						"enum":        []string{TrendWeek, TrendMonth},
+						"description": "Size of the periods the posts are counted in (default month)",
+					},
+					"channels": channelsInputProperty(channels),
+				}),
+				Required: []string{"technologies"},
+			},
+		},
+		mcptool.SchemaFor(TrendsOutput{}),
+		func(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, interface{}) {
+			technologies, err := technologiesArg(args)
+			interval := TrendMonth
+			if err == nil && args["interval"] != nil {
+				interval, _ = args["interval"].(string)
+				if interval != TrendWeek && interval != TrendMonth {
+					err = fmt.Errorf("'interval' must be %s or %s", TrendWeek, TrendMonth)
+				}
+			}
+			now := time.Now()
+			var window TimeRange
+			if err == nil {
+				window, err = timeRangeArgs(args, now)
+			}
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error: %v", err),
+						},
+					},
+				}, nil
+			}
+
+			requested, err := channelsArg(args)
+			var searched []Channel
+			if err == nil {
+				searched, err = channels.Select(toolid.TechnologyTrends, requested)
+			}
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error: %v", err),
+						},
+					},
+				}, nil
+			}
+
+			trends := []TechnologyTrend{}
+			// the trends are kept when Slack throttles some technologies, the notice names the others
+			var throttled *ThrottledError
+			missing := []string{}
+			for _, tech := range technologies {
+				trend, err := slack.TechnologyTrend(ctx, tech, channelNames(searched), interval, window, now)
+				var techThrottled *ThrottledError
+				if errors.As(err, &techThrottled) {
+					if throttled == nil || techThrottled.RetryAfter > throttled.RetryAfter {
+						throttled = techThrottled
+					}
+					missing = append(missing, tech)
+					continue
+				}
+				if err != nil {
+					return &mcp.CallToolResult{
+						IsError: utils.Ptr(true),
+						Content: []interface{}{
+							mcp.TextContent{
+								Type: "text",
+								Text: fmt.Sprintf("Error computing the trend of '%s': %v", tech, err),
+							},
+						},
+					}, nil
+				}
+				trends = append(trends, trend)
+			}
+
+			if throttled != nil && len(trends) == 0 {
+				output := TrendsOutput{Trends: trends, Page: Page{Notice: throttledNotice(throttled)}}
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(false),
+					Content: []interface{}{
+						textContent(output.Notice),
+					},
+				}, output
+			}
+			output := TrendsOutput{Trends: trends}
+			if throttled != nil {
+				output.Notice = throttledNotice(throttled, missing...)
+			}
+			return &mcp.CallToolResult{
 				IsError: utils.Ptr(false),
+				Content: pageContent(renderTrends(trends), output.Page),
+			}, output
+		},
+	)
+}
+
+// technologiesArg extracts the required technologies tool argument
+func technologiesArg(args map[string]interface{}) ([]string, error) {
+	values, ok := args["technologies"].([]interface{})
+	if !ok || len(values) == 0 {
+		return nil, fmt.Errorf("'technologies' parameter is required and must be an array of technologies")
+	}
+	technologies := []string{}
+	for _, value := range values {
+		tech, ok := value.(string)
+		if !ok || tech == "" {
+			return nil, fmt.Errorf("'technologies' must be an array of technologies")
+		}
+		technologies = append(technologies, tech)
+	}
+	return technologies, nil
+}
+
+func NewSkillsMatrix(slack *SlackService, channels *ChannelSet) mcptool.Tool {
+	return mcptool.NewTool(
+		&mcp.Tool{
+			Name:        toolid.SkillsMatrix,
+			Description: utils.Ptr("Build a staffing view of who knows what: a matrix of people by technologies where each cell holds the number of posts the person tagged with the technology, their last post date and a link to their best example post. Exported as a Markdown table, CSV or JSON. Covers the whole catalog unless technologies are given. On channels that are not synced each emoji and keyword is a Slack search, a matrix needing more searches than the rate limit allows is refused: give fewer technologies."),
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: withTimeRangeProperties(map[string]map[string]interface{}{
+					"technologies": {
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "The technologies or categories of the columns (e.g., golang, react, devops), every technology of list-technologies by default",
+					},
+					"format": {
+						"type":        "string",
+						"enum":        SkillsFormats,
+						"description": "Format of the text export (default markdown), the structured content is always the matrix",
+					},
+					"channels": channelsInputProperty(channels),
+				}),
+			},
+		},
+		mcptool.SchemaFor(SkillsOutput{}),
+		func(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, interface{}) {
+			var technologies []string
+			var err error
+			if args["technologies"] != nil {
+				technologies, err = technologiesArg(args)
+			} else {
+				for _, tech := range slack.Taxonomy().Technologies() {
+					technologies = append(technologies, tech.Name)
+				}
+			}
+			format := SkillsFormatMarkdown
+			if err == nil && args["format"] != nil {
+				format, _ = args["format"].(string)
+				if !slices.Contains(SkillsFormats, format) {
+					err = fmt.Errorf("'format' must be one of %s", strings.Join(SkillsFormats, ", "))
+				}
 			}
+			var window TimeRange
+			if err == nil {
+				window, err = timeRangeArgs(args, time.Now())
+			}
+			if err == nil && len(technologies) == 0 {
+				err = fmt.Errorf("the technology catalog is empty, give 'technologies'")
+			}
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error: %v", err),
+						},
+					},
+				}, nil
+			}
+
+			requested, err := channelsArg(args)
+			var searched []Channel
+			if err == nil {
+				searched, err = channels.Select(toolid.SkillsMatrix, requested)
+			}
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error: %v", err),
+						},
+					},
+				}, nil
+			}
+
+			matrix, err := slack.SkillsMatrix(ctx, technologies, channelNames(searched), window)
+			var throttled *ThrottledError
+			if errors.As(err, &throttled) {
+				output := SkillsOutput{SkillsMatrix: SkillsMatrix{Technologies: []string{}, Rows: []SkillRow{}}, Page: Page{Notice: throttledNotice(throttled)}}
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(false),
+					Content: []interface{}{
+						textContent(output.Notice),
+					},
+				}, output
+			}
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error building the skills matrix: %v", err),
+						},
+					},
+				}, nil
+			}
+
+			var export strings.Builder
+			if err := matrix.Write(&export, format); err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error exporting the skills matrix: %v", err),
+						},
+					},
+				}, nil
+			}
+
+			output := SkillsOutput{SkillsMatrix: matrix}
+			return &mcp.CallToolResult{
+				IsError: utils.Ptr(false),
+				Content: []interface{}{
+					textContent(export.String()),
+				},
+			}, output
+		},
+	)
+}
+
+// defaultProfileSkillsLimit is the number of skills returned when the caller gives no limit
+const defaultProfileSkillsLimit = 10
+
+func NewGetUserExpertiseProfile(slack *SlackService, channels *ChannelSet) mcptool.Tool {
+	return mcptool.NewTool(
+		&mcp.Tool{
+			Name:        toolid.ExpertiseProfile,
+			Description: utils.Ptr("Infer the technologies a person knows from the keywords in the text of their posts, without relying on emoji tags. Technologies of the catalog mentioned by the person are ranked with TF-IDF against all the posts of the channels, with a confidence score and example posts."),
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: withPageProperties(withTimeRangeProperties(map[string]map[string]interface{}{
+					"user": {
+						"type":        "string",
+						"description": "Slack id, handle or name of the person",
+					},
+					"channels": channelsInputProperty(channels),
+				}), defaultProfileSkillsLimit),
+				Required: []string{"user"},
+			},
+		},
+		mcptool.SchemaFor(ProfileOutput{}),
+		func(ctx context.Context, args map[string]interface{}) (*mcp.CallToolResult, interface{}) {
+			search, ok := args["user"].(string)
+			if !ok || search == "" {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: "Error: 'user' parameter is required and must be a string",
+						},
+					},
+				}, nil
+			}
+
+			limit, cursor, err := pageArgs(args, defaultProfileSkillsLimit)
+			var window TimeRange
+			if err == nil {
+				window, err = timeRangeArgs(args, time.Now())
+			}
+			var requested []string
+			if err == nil {
+				requested, err = channelsArg(args)
+			}
+			var searched []Channel
+			if err == nil {
+				searched, err = channels.Select(toolid.ExpertiseProfile, requested)
+			}
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error: %v", err),
+						},
+					},
+				}, nil
+			}
+
+			user, err := slack.resolveUser(ctx, search)
+			var profile ExpertiseProfile
+			if err == nil {
+				profile, err = slack.ExpertiseProfile(ctx, user, searched, window)
+			}
+			var throttled *ThrottledError
+			if errors.As(err, &throttled) {
+				output := ProfileOutput{ExpertiseProfile: ExpertiseProfile{User: user, Skills: []InferredSkill{}}, Page: Page{Notice: throttledNotice(throttled)}}
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(false),
+					Content: []interface{}{
+						textContent(output.Notice),
+					},
+				}, output
+			}
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error building the expertise profile: %v", err),
+						},
+					},
+				}, nil
+			}
+
+			var next string
+			profile.Skills, next, err = paginate(profile.Skills, limit, cursor)
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: utils.Ptr(true),
+					Content: []interface{}{
+						mcp.TextContent{
+							Type: "text",
+							Text: fmt.Sprintf("Error: %v", err),
+						},
+					},
+				}, nil
+			}
+
+			output := ProfileOutput{ExpertiseProfile: profile, Page: Page{Next_Cursor: next}}
+			return &mcp.CallToolResult{
+				IsError: utils.Ptr(false),
+				Content: pageContent(renderProfile(profile), output.Page),
+			}, output
 		},
 	)
 }
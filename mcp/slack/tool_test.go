@@ -0,0 +1,176 @@
+package slack
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexisZankowitch/concept-insight/mcp/mcptool"
+	"github.com/AlexisZankowitch/concept-insight/toolid"
+	"github.com/strowk/foxy-contexts/pkg/mcp"
+)
+
+func Test_FindTechnologyPostTool(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	tool := NewFindTechnologyPost(s, newTestChannels(t, s))
+
+	result := tool.CallStructured(context.Background(), map[string]interface{}{"technology": "golang"})
+	if *result.IsError {
+		t.Fatalf("unexpected error result %+v", result)
+	}
+
+	output, ok := result.StructuredContent.(PostsOutput)
+	if !ok {
+		t.Fatalf("expected posts, got %T", result.StructuredContent)
+	}
+	// the post in #random is ignored
+	if len(output.Posts) != 3 {
+		t.Fatalf("expected 3 posts, got %d: %+v", len(output.Posts), output.Posts)
+	}
+	if len(result.Content) != 1 || output.Next_Cursor != "" {
+		t.Fatalf("expected no cursor, got %+v", result.Content[1:])
+	}
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok || !strings.HasPrefix(text.Text, "3 posts:") || !strings.Contains(text.Text, output.Posts[0].Permalink) {
+		t.Fatalf("expected the posts rendered as text, got %+v", result.Content[0])
+	}
+	if calls := server.Calls("search.messages"); calls != 4 {
+		t.Fatalf("expected an emoji and a keyword search per channel, got %d", calls)
+	}
+}
+
+func Test_FindTechnologyPostToolRetriesSyncedChannel(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	store := newTestStore(t)
+	if err := s.UseStore(store); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	tool := NewFindTechnologyPost(s, newTestChannels(t, s))
+	args := map[string]interface{}{"technology": "golang", "channels": []interface{}{"concept-tech"}}
+
+	// the channel is not synced yet and Slack throttles its search
+	server.RateLimit("search.messages", maxRateLimitRetries+1)
+	result := tool.CallStructured(context.Background(), args)
+	output, ok := result.StructuredContent.(PostsOutput)
+	if *result.IsError || !ok || len(output.Posts) != 0 || output.Next_Cursor == "" {
+		t.Fatalf("expected the channel kept in the cursor, got %+v", result)
+	}
+
+	// the retry reads the synced channel from the store, from the start
+	syncer := NewSyncer(s, store, []Channel{conceptTech}, time.Minute, time.Hour)
+	if err := syncer.SyncAll(context.Background()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	args["cursor"] = output.Next_Cursor
+	result = tool.CallStructured(context.Background(), args)
+	output, ok = result.StructuredContent.(PostsOutput)
+	if *result.IsError || !ok || len(output.Posts) != 1 || output.Next_Cursor != "" {
+		t.Fatalf("expected the post of concept-tech, got %+v", result)
+	}
+}
+
+func Test_FindTechnologyPostToolMissingTechnology(t *testing.T) {
+	s, _ := newTestService(t, loadFixtures(t))
+	tool := NewFindTechnologyPost(s, newTestChannels(t, s))
+
+	result := tool.CallStructured(context.Background(), map[string]interface{}{})
+	if !*result.IsError || result.StructuredContent != nil {
+		t.Fatalf("expected an error result, got %+v", result)
+	}
+}
+
+func Test_GetConceptUserDetailsTool(t *testing.T) {
+	s, _ := newTestService(t, loadFixtures(t))
+	tool := NewGetConceptUserDetails(s)
+
+	result := tool.CallStructured(context.Background(), map[string]interface{}{"search": "curie"})
+	output, ok := result.StructuredContent.(UsersOutput)
+	if !ok {
+		t.Fatalf("expected users, got %T", result.StructuredContent)
+	}
+	if len(output.Users) != 1 || output.Users[0].Slack_id != "U02MARIE01" {
+		t.Fatalf("unexpected users %+v", output.Users)
+	}
+
+	result = tool.CallStructured(context.Background(), map[string]interface{}{"search": "nobody"})
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok || text.Text != "No users found matching 'nobody'" {
+		t.Fatalf("unexpected result %+v", result.Content)
+	}
+}
+
+func Test_GetLastestPostsByUserIdTool(t *testing.T) {
+	s, _ := newTestService(t, loadFixtures(t))
+	tool := NewGetLastestPostsByUserId(s, newTestChannels(t, s))
+
+	result := tool.CallStructured(context.Background(), map[string]interface{}{
+		"slack_user_id": "U7D3Q7N8Y",
+		"limit":         float64(1),
+	})
+	if *result.IsError {
+		t.Fatalf("unexpected error result %+v", result)
+	}
+	output, ok := result.StructuredContent.(PostsOutput)
+	if !ok || len(output.Posts) != 1 {
+		t.Fatalf("expected 1 post, got %+v", result.StructuredContent)
+	}
+	if len(result.Content) != 2 || output.Next_Cursor == "" {
+		t.Fatalf("expected a cursor for the remaining post, got %+v", result.Content)
+	}
+}
+
+func Test_ListTechnologiesTool(t *testing.T) {
+	s, _ := newTestService(t, loadFixtures(t))
+	s.UseTaxonomy(newTestTaxonomy(t))
+	tool := NewListTechnologies(s)
+
+	result := tool.CallStructured(context.Background(), map[string]interface{}{})
+	output, ok := result.StructuredContent.(TechnologiesOutput)
+	if !ok || len(output.Technologies) != 5 || output.Technologies[0].Name != "backend" {
+		t.Fatalf("expected the catalog sorted by name, got %+v", result.StructuredContent)
+	}
+
+	result = tool.CallStructured(context.Background(), map[string]interface{}{"category": "reactjs"})
+	output, _ = result.StructuredContent.(TechnologiesOutput)
+	if len(output.Technologies) != 2 || output.Technologies[0].Name != "react" || output.Technologies[1].Name != "nextjs" {
+		t.Fatalf("expected react and the technologies under it, got %+v", output.Technologies)
+	}
+	text, _ := result.Content[0].(mcp.TextContent)
+	if !strings.Contains(text.Text, "- **nextjs**, tagged :nextjs:, under react") {
+		t.Fatalf("unexpected text %q", text.Text)
+	}
+
+	result = tool.CallStructured(context.Background(), map[string]interface{}{"category": "cobol"})
+	if !*result.IsError {
+		t.Fatalf("expected an error for an unknown category, got %+v", result)
+	}
+}
+
+func Test_ToolIds(t *testing.T) {
+	s, _ := newTestService(t, loadFixtures(t))
+	channels := newTestChannels(t, s)
+	ids := []string{}
+	for _, tool := range []mcptool.Tool{
+		NewFindTechnologyPost(s, channels),
+		NewGetConceptUserDetails(s),
+		NewGetLastestPostsByUserId(s, channels),
+		NewGetThread(s, channels),
+		NewFindExperts(s, channels),
+		NewListTechnologies(s),
+		NewTechnologyTrends(s, channels),
+		NewSkillsMatrix(s, channels),
+		NewGetUserExpertiseProfile(s, channels),
+	} {
+		ids = append(ids, toolid.ForName(tool.GetMcpTool().Name))
+	}
+	// the overrides of the configuration find the tools by id
+	if !slices.Equal(slices.Sorted(slices.Values(ids)), toolid.All()) {
+		t.Fatalf("expected a tool per id, got %v", ids)
+	}
+	// the first tools keep the name the clients know
+	if name := NewGetConceptUserDetails(s).GetMcpTool().Name; name != "Get user details" {
+		t.Fatalf("expected the tool name unchanged, got %q", name)
+	}
+}
@@ -0,0 +1,205 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// TrendWeek and TrendMonth are the bucket sizes of a technology trend
+	TrendWeek  = "week"
+	TrendMonth = "month"
+
+	// trendPageSize is the number of posts fetched per page while collecting a trend
+	trendPageSize = maxLimit
+	// trendMaxPosts caps the posts collected per technology and channel
+	trendMaxPosts = 10000
+	// trendDirectionThreshold is the relative change between the older and the recent half
+	// of the buckets above which a trend is rising or falling
+	trendDirectionThreshold = 0.25
+)
+
+// trendDefaultPeriods is the number of buckets covered when no start date is given
+var trendDefaultPeriods = map[string]int{
+	TrendWeek:  26,
+	TrendMonth: 12,
+}
+
+// sparkBars draws the buckets of a trend, from the emptiest to the busiest
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// TrendBucket is the activity of a technology during a week or a month
+type TrendBucket struct {
+	Start        time.Time `json:"start" description:"Start of the week (Monday) or month, UTC"`
+	Post_Count   int       `json:"post_count"`
+	Author_Count int       `json:"author_count" description:"Number of distinct authors who posted in the bucket"`
+}
+
+// TechnologyTrend is the activity of a technology over time, oldest bucket first
+type TechnologyTrend struct {
+	Technology   string        `json:"technology"`
+	Interval     string        `json:"interval" description:"Size of the buckets, week or month"`
+	Buckets      []TrendBucket `json:"buckets"`
+	Post_Count   int           `json:"post_count"`
+	Author_Count int           `json:"author_count" description:"Number of distinct authors over the whole period"`
+	// Direction compares the recent half of the buckets with the older half
+	Direction string `json:"direction" description:"rising, falling or stable, comparing the recent half of the buckets with the older half"`
+	Sparkline string `json:"sparkline"`
+	Truncated bool   `json:"truncated,omitempty" description:"Set when there were too many posts to count them all"`
+}
+
+// TechnologyTrend counts the posts tagged with the technology in the channels, per week or month
+// of the time range, reading every page of the technology search. Without a start date,
+// the trend covers the last 26 weeks or 12 months.
+func (s *SlackService) TechnologyTrend(ctx context.Context, tech string, channels []string, interval string, window TimeRange, now time.Time) (TechnologyTrend, error) {
+	if _, ok := trendDefaultPeriods[interval]; !ok {
+		return TechnologyTrend{}, fmt.Errorf("invalid interval %q, expected %s or %s", interval, TrendWeek, TrendMonth)
+	}
+	if window.After.IsZero() {
+		window.After = trendDefaultStart(interval, now)
+	}
+
+	posts := []MessageInfo{}
+	truncated := false
+	for _, channel := range channels {
+		// only the dates and authors are counted, the posts do not need their thread info
+		messages, channelTruncated, err := collectAllPages(func(cursor string) ([]MessageInfo, string, error) {
+			messages, next, _, err := s.technologyPosts(ctx, tech, channel, window, trendPageSize, cursor)
+			return messages, next, err
+		}, trendMaxPosts)
+		if err != nil {
+			return TechnologyTrend{}, fmt.Errorf("failed to search %s: %w", channel, err)
+		}
+		// a post tagged with several emojis of the technology can come back on a later page
+		posts = append(posts, uniquePosts(messages)...)
+		truncated = truncated || channelTruncated
+	}
+
+	trend := buildTrend(s.taxonomy.Expand(tech).Name, posts, interval, window, now)
+	trend.Truncated = truncated
+	return trend, nil
+}
+
+// uniquePosts removes the posts of a channel returned twice, keeping the first one
+func uniquePosts(posts []MessageInfo) []MessageInfo {
+	seen := map[string]bool{}
+	unique := []MessageInfo{}
+	for _, post := range posts {
+		if !seen[post.Ts] {
+			seen[post.Ts] = true
+			unique = append(unique, post)
+		}
+	}
+	return unique
+}
+
+// buildTrend buckets the posts of the time range, an open end stops at now
+func buildTrend(tech string, posts []MessageInfo, interval string, window TimeRange, now time.Time) TechnologyTrend {
+	end := window.Before
+	if end.IsZero() || end.After(now) {
+		end = now
+	}
+
+	trend := TechnologyTrend{Technology: tech, Interval: interval, Buckets: []TrendBucket{}}
+	index := map[time.Time]int{}
+	for start := trendBucketStart(window.After, interval); start.Before(end); start = nextTrendBucket(start, interval) {
+		index[start] = len(trend.Buckets)
+		trend.Buckets = append(trend.Buckets, TrendBucket{Start: start})
+	}
+
+	authors := map[string]bool{}
+	bucketAuthors := make([]map[string]bool, len(trend.Buckets))
+	for _, post := range posts {
+		i, ok := index[trendBucketStart(post.Posted, interval)]
+		if !ok || !window.Contains(post.Posted) {
+			continue
+		}
+		trend.Buckets[i].Post_Count++
+		trend.Post_Count++
+		if post.Slack_id == "" {
+			continue
+		}
+		authors[post.Slack_id] = true
+		if bucketAuthors[i] == nil {
+			bucketAuthors[i] = map[string]bool{}
+		}
+		bucketAuthors[i][post.Slack_id] = true
+	}
+	for i := range trend.Buckets {
+		trend.Buckets[i].Author_Count = len(bucketAuthors[i])
+	}
+	trend.Author_Count = len(authors)
+	trend.Direction = trendDirection(trend.Buckets)
+	trend.Sparkline = sparkline(trend.Buckets)
+	return trend
+}
+
+// trendDirection compares the average activity of the recent half of the buckets with the older half.
+// The middle bucket of an odd count belongs to neither half.
+func trendDirection(buckets []TrendBucket) string {
+	half := len(buckets) / 2
+	if half == 0 {
+		return "stable"
+	}
+	older, recent := 0, 0
+	for i := 0; i < half; i++ {
+		older += buckets[i].Post_Count
+		recent += buckets[len(buckets)-1-i].Post_Count
+	}
+	switch {
+	case float64(recent) > float64(older)*(1+trendDirectionThreshold):
+		return "rising"
+	case float64(recent) < float64(older)*(1-trendDirectionThreshold):
+		return "falling"
+	default:
+		return "stable"
+	}
+}
+
+// sparkline draws the post counts of the buckets relative to the busiest one
+func sparkline(buckets []TrendBucket) string {
+	busiest := 0
+	for _, bucket := range buckets {
+		busiest = max(busiest, bucket.Post_Count)
+	}
+	bars := make([]rune, len(buckets))
+	for i, bucket := range buckets {
+		level := 0
+		if busiest > 0 {
+			level = bucket.Post_Count * (len(sparkBars) - 1) / busiest
+		}
+		bars[i] = sparkBars[level]
+	}
+	return string(bars)
+}
+
+// trendBucketStart returns the start of the week, on Monday, or of the month of t, in UTC
+func trendBucketStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	if interval == TrendMonth {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+func nextTrendBucket(start time.Time, interval string) time.Time {
+	if interval == TrendMonth {
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 7)
+}
+
+// trendDefaultStart is the start of the default period, made of whole buckets ending with the current one
+func trendDefaultStart(interval string, now time.Time) time.Time {
+	start := trendBucketStart(now, interval)
+	for i := 1; i < trendDefaultPeriods[interval]; i++ {
+		if interval == TrendMonth {
+			start = start.AddDate(0, -1, 0)
+		} else {
+			start = start.AddDate(0, 0, -7)
+		}
+	}
+	return start
+}
@@ -0,0 +1,170 @@
+package slack
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/strowk/foxy-contexts/pkg/mcp"
+)
+
+func Test_TrendBucketStart(t *testing.T) {
+	sunday := time.Date(2024, 6, 16, 23, 30, 0, 0, time.UTC)
+	if start := trendBucketStart(sunday, TrendWeek); !start.Equal(time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the week to start on Monday, got %v", start)
+	}
+	// a Monday morning in Paris is still Sunday in UTC
+	monday := time.Date(2024, 6, 17, 1, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+	if start := trendBucketStart(monday, TrendWeek); !start.Equal(time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the UTC week, got %v", start)
+	}
+	if start := trendBucketStart(sunday, TrendMonth); !start.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the first day of the month, got %v", start)
+	}
+
+	now := time.Date(2024, 6, 16, 12, 0, 0, 0, time.UTC)
+	if start := trendDefaultStart(TrendMonth, now); !start.Equal(time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected 12 months ending with June 2024, got %v", start)
+	}
+}
+
+func Test_BuildTrend(t *testing.T) {
+	day := func(month time.Month, d int) time.Time { return time.Date(2024, month, d, 12, 0, 0, 0, time.UTC) }
+	posts := []MessageInfo{
+		{Slack_id: "U1", Posted: day(1, 10)},
+		{Slack_id: "U1", Posted: day(3, 5)},
+		{Slack_id: "U1", Posted: day(3, 6)},
+		{Slack_id: "U2", Posted: day(3, 7)},
+		{Slack_id: "U2", Posted: day(4, 1)},
+		{Slack_id: "U3", Posted: day(4, 2)},
+		// outside of the range
+		{Slack_id: "U4", Posted: day(5, 2)},
+	}
+	window := TimeRange{After: day(1, 1), Before: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}
+
+	trend := buildTrend("golang", posts, TrendMonth, window, day(6, 1))
+	expected := []TrendBucket{
+		{Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Post_Count: 1, Author_Count: 1},
+		{Start: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{Start: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Post_Count: 3, Author_Count: 2},
+		{Start: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), Post_Count: 2, Author_Count: 2},
+	}
+	if len(trend.Buckets) != len(expected) {
+		t.Fatalf("expected %d buckets, got %+v", len(expected), trend.Buckets)
+	}
+	for i, bucket := range trend.Buckets {
+		if !bucket.Start.Equal(expected[i].Start) || bucket.Post_Count != expected[i].Post_Count || bucket.Author_Count != expected[i].Author_Count {
+			t.Errorf("bucket %d: expected %+v, got %+v", i, expected[i], bucket)
+		}
+	}
+	if trend.Post_Count != 6 || trend.Author_Count != 3 {
+		t.Fatalf("expected 6 posts by 3 authors, got %+v", trend)
+	}
+	if trend.Direction != "rising" || trend.Sparkline != "▃▁█▅" {
+		t.Fatalf("expected a rising trend, got %s %s", trend.Direction, trend.Sparkline)
+	}
+}
+
+func Test_TrendDirection(t *testing.T) {
+	for _, test := range []struct {
+		counts   []int
+		expected string
+	}{
+		{[]int{}, "stable"},
+		{[]int{5}, "stable"},
+		{[]int{4, 4, 5, 4}, "stable"},
+		{[]int{1, 2, 9, 4, 4}, "rising"},
+		{[]int{8, 6, 0, 2, 1}, "falling"},
+		{[]int{0, 0, 1}, "rising"},
+	} {
+		buckets := []TrendBucket{}
+		for _, count := range test.counts {
+			buckets = append(buckets, TrendBucket{Post_Count: count})
+		}
+		if direction := trendDirection(buckets); direction != test.expected {
+			t.Errorf("%v: expected %s, got %s", test.counts, test.expected, direction)
+		}
+	}
+}
+
+func Test_TechnologyTrendsTool(t *testing.T) {
+	s, _ := newTestService(t, loadFixtures(t))
+	tool := NewTechnologyTrends(s, newTestChannels(t, s))
+
+	result := tool.CallStructured(context.Background(), map[string]interface{}{
+		"technologies": []interface{}{"golang", "rust"},
+		"interval":     "week",
+		"after":        "2024-06-03",
+		"before":       "2024-07-01",
+	})
+	if *result.IsError {
+		t.Fatalf("unexpected error result %+v", result)
+	}
+	output, ok := result.StructuredContent.(TrendsOutput)
+	if !ok || len(output.Trends) != 2 {
+		t.Fatalf("expected 2 trends, got %+v", result.StructuredContent)
+	}
+
+	golang := output.Trends[0]
+	if golang.Technology != "golang" || len(golang.Buckets) != 4 {
+		t.Fatalf("expected 4 weeks of golang, got %+v", golang)
+	}
+	// the post in #random is ignored
+	if golang.Buckets[1].Post_Count != 3 || golang.Buckets[1].Author_Count != 2 || golang.Post_Count != 3 {
+		t.Fatalf("expected 3 posts by 2 authors the week of June 10, got %+v", golang.Buckets)
+	}
+	if golang.Direction != "falling" || golang.Sparkline != "▁█▁▁" {
+		t.Fatalf("unexpected trend %s %s", golang.Direction, golang.Sparkline)
+	}
+	if output.Trends[1].Post_Count != 1 {
+		t.Fatalf("expected 1 rust post, got %+v", output.Trends[1])
+	}
+
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok || !strings.Contains(text.Text, "**golang** ▁█▁▁ falling, 3 posts by 2 authors") || !strings.Contains(text.Text, "- week of 2024-06-10: 3 posts, 2 authors") {
+		t.Fatalf("expected the trends rendered as text, got %+v", result.Content[0])
+	}
+}
+
+func Test_TechnologyTrendsToolInvalidArgs(t *testing.T) {
+	s, _ := newTestService(t, loadFixtures(t))
+	tool := NewTechnologyTrends(s, newTestChannels(t, s))
+
+	for _, args := range []map[string]interface{}{
+		{},
+		{"technologies": "golang"},
+		{"technologies": []interface{}{"golang"}, "interval": "day"},
+		{"technologies": []interface{}{"golang"}, "since": "soon"},
+	} {
+		if result := tool.CallStructured(context.Background(), args); !*result.IsError {
+			t.Errorf("%v: expected an error result, got %+v", args, result)
+		}
+	}
+}
+
+func Test_TechnologyTrendsToolThrottled(t *testing.T) {
+	s, server := newTestService(t, loadFixtures(t))
+	tool := NewTechnologyTrends(s, newTestChannels(t, s))
+	// the searches of golang give up, the ones of rust answer
+	server.RateLimit("search.messages", maxRateLimitRetries+1)
+
+	result := tool.CallStructured(context.Background(), map[string]interface{}{
+		"technologies": []interface{}{"golang", "rust"},
+		"after":        "2024-06-01",
+		"before":       "2024-07-01",
+	})
+	if *result.IsError {
+		t.Fatalf("throttling must not be reported as an error, got %+v", result)
+	}
+	output, ok := result.StructuredContent.(TrendsOutput)
+	if !ok || len(output.Trends) != 1 || output.Trends[0].Technology != "rust" {
+		t.Fatalf("expected the trend of rust, got %+v", result.StructuredContent)
+	}
+	if !strings.Contains(output.Notice, "rate limiting") || !strings.Contains(output.Notice, "the results of golang could not be fetched") {
+		t.Fatalf("expected a notice naming golang, got %q", output.Notice)
+	}
+	if len(result.Content) != 2 || result.Content[1].(mcp.TextContent).Text != output.Notice {
+		t.Fatalf("expected the trends then the notice, got %+v", result.Content)
+	}
+}
@@ -0,0 +1,66 @@
+// Package toolid names the tools. The id of a tool is the key of its channel, timeout and
+// scope overrides in the configuration, e.g. TOOL_TIMEOUT_SKILLS_MATRIX for skills-matrix,
+// and its MCP name except for the first tools, which keep the name the clients know.
+package toolid
+
+import "slices"
+
+// Ids of the tools
+const (
+	FindTechnologyPosts = "find-technology-posts"
+	LatestPostsByUser   = "latest-posts-by-user"
+	UserDetails         = "get-user-details"
+	GetThread           = "get-thread"
+	FindExperts         = "find-experts"
+	ListTechnologies    = "list-technologies"
+	TechnologyTrends    = "technology-trends"
+	SkillsMatrix        = "skills-matrix"
+	ExpertiseProfile    = "get-user-expertise-profile"
+)
+
+// all lists the ids by name
+var all = []string{
+	FindExperts,
+	FindTechnologyPosts,
+	GetThread,
+	UserDetails,
+	ExpertiseProfile,
+	LatestPostsByUser,
+	ListTechnologies,
+	SkillsMatrix,
+	TechnologyTrends,
+}
+
+// legacyNames are the MCP names of the tools named before they had an id
+var legacyNames = map[string]string{
+	LatestPostsByUser: "Get the latest 200 posts by slack user id",
+	UserDetails:       "Get user details",
+}
+
+// Name returns the MCP name of the tool with the id
+func Name(id string) string {
+	if name, ok := legacyNames[id]; ok {
+		return name
+	}
+	return id
+}
+
+// ForName returns the id of the tool with the MCP name
+func ForName(name string) string {
+	for id, legacyName := range legacyNames {
+		if legacyName == name {
+			return id
+		}
+	}
+	return name
+}
+
+// All returns the id of every tool, by name
+func All() []string {
+	return slices.Clone(all)
+}
+
+// Known tells if a tool has the id
+func Known(id string) bool {
+	return slices.Contains(all, id)
+}